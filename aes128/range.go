@@ -0,0 +1,61 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aes128
+
+import (
+	"crypto/aes"
+	"fmt"
+)
+
+// BlockRange returns the byte range [start, end) of the encrypted data,
+// which is required to decrypt the plain data in [offset, offset+length).
+//
+// The range is aligned at 16 bytes, and contains the preceding 16-byte block
+// used as the IV if offset is not in the first block, that's, offset>=16.
+//
+// offset is relative to the beginning of the encrypted data,
+// where the cipher block chaining starts.
+func BlockRange(offset, length uint64) (start, end uint64) {
+	start = offset &^ (aes.BlockSize - 1)                              // Floor
+	end = (offset + length + aes.BlockSize - 1) &^ (aes.BlockSize - 1) // Ceil
+	if start >= aes.BlockSize {
+		start -= aes.BlockSize
+	}
+	return
+}
+
+// DecryptRange decrypts the encrypted data in the range returned by
+// BlockRange(offset, length), and returns the plain data
+// in [offset, offset+length).
+//
+// iv is only used when offset is in the first block, that's, offset<16.
+// Or, the preceding block in encrypted is used as the IV.
+func DecryptRange(encrypted, key, iv []byte, offset, length uint64) (data []byte, err error) {
+	start, end := BlockRange(offset, length)
+	if uint64(len(encrypted)) != end-start {
+		return nil, fmt.Errorf("invalid encrypted data: expect %d bytes, but got %d", end-start, len(encrypted))
+	}
+
+	if start+aes.BlockSize <= offset {
+		iv, encrypted = encrypted[:aes.BlockSize], encrypted[aes.BlockSize:]
+		start += aes.BlockSize
+	}
+
+	if data, err = Decrypt(encrypted, key, iv, false); err == nil {
+		offset -= start
+		data = data[offset : offset+length]
+	}
+	return
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aes128
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlockRange(t *testing.T) {
+	for _, c := range []struct {
+		Offset, Length uint64
+		Start, End     uint64
+	}{
+		{Offset: 0, Length: 1, Start: 0, End: 16},
+		{Offset: 15, Length: 2, Start: 0, End: 32},
+		{Offset: 16, Length: 16, Start: 0, End: 32},
+		{Offset: 33, Length: 10, Start: 16, End: 48},
+		{Offset: 100, Length: 28, Start: 80, End: 128},
+	} {
+		start, end := BlockRange(c.Offset, c.Length)
+		if start != c.Start || end != c.End {
+			t.Errorf("offset=%d, length=%d: expect [%d, %d), but got [%d, %d)",
+				c.Offset, c.Length, c.Start, c.End, start, end)
+		}
+	}
+}
+
+func TestDecryptRange(t *testing.T) {
+	var (
+		key = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		iv  = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	)
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	encrypted, err := Encrypt(data, key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range [][2]uint64{{0, 1}, {0, 1000}, {15, 2}, {16, 16}, {17, 100}, {376, 188}, {999, 1}} {
+		start, end := BlockRange(r[0], r[1])
+		decrypted, err := DecryptRange(encrypted[start:end], key, iv, r[0], r[1])
+		if err != nil {
+			t.Errorf("offset=%d, length=%d: %v", r[0], r[1], err)
+		} else if expect := data[r[0] : r[0]+r[1]]; !bytes.Equal(decrypted, expect) {
+			t.Errorf("offset=%d, length=%d: the decrypted data is not equal to the original", r[0], r[1])
+		}
+	}
+
+	if _, err := DecryptRange(encrypted[:16], key, iv, 32, 16); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
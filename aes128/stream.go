@@ -0,0 +1,194 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aes128
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+)
+
+const streamBufferSize = 32 * 1024
+
+var errWriterClosed = errors.New("encrypt writer is closed")
+
+func newBlockMode(key, iv []byte, encrypt bool) (cipher.BlockMode, error) {
+	switch {
+	case len(key) != 16:
+		return nil, errInvalidEncryptedKey
+
+	case len(iv) != 16:
+		return nil, errInvalidEncryptedIV
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypt {
+		return cipher.NewCBCEncrypter(block, iv), nil
+	}
+	return cipher.NewCBCDecrypter(block, iv), nil
+}
+
+/// ----------------------------------------------------------------------- ///
+
+type decryptReader struct {
+	r    io.Reader
+	mode cipher.BlockMode
+
+	encrypted []byte // The buffer of the encrypted data.
+	buffered  int    // The number of the bytes buffered in encrypted.
+	decrypted []byte // The buffer of the decrypted data.
+	out       []byte // The decrypted data to be read.
+	err       error
+}
+
+// NewDecryptReader returns a reader that decrypts the data read from r
+// by AES-128 CBC block by block, and removes the PKCS7 padding
+// only when reaching EOF of r.
+//
+// It is equal to Decrypt with removePadding=true, but does not require
+// the whole encrypted data in memory.
+func NewDecryptReader(r io.Reader, key, iv []byte) (io.Reader, error) {
+	mode, err := newBlockMode(key, iv, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:         r,
+		mode:      mode,
+		encrypted: make([]byte, streamBufferSize+aes.BlockSize),
+		decrypted: make([]byte, streamBufferSize+aes.BlockSize),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (n int, err error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.fill()
+	}
+
+	n = copy(p, d.out)
+	d.out = d.out[n:]
+	return
+}
+
+func (d *decryptReader) fill() {
+	n, err := d.r.Read(d.encrypted[d.buffered:])
+	d.buffered += n
+
+	switch err {
+	case nil:
+		// Hold back the last block, which may contain the padding.
+		size := d.buffered&^(aes.BlockSize-1) - aes.BlockSize
+		if size > 0 {
+			d.decrypt(size)
+		}
+
+	case io.EOF:
+		if d.buffered%aes.BlockSize != 0 {
+			d.err = errors.New("invalid encrypted data: not a multiple of block size")
+			return
+		}
+
+		d.decrypt(d.buffered)
+		if d.out, err = removePKCS7Padding(d.out); err != nil {
+			d.out, d.err = nil, err
+		} else {
+			d.err = io.EOF
+		}
+
+	default:
+		d.err = err
+	}
+}
+
+func (d *decryptReader) decrypt(size int) {
+	d.mode.CryptBlocks(d.decrypted[:size], d.encrypted[:size])
+	d.out = d.decrypted[:size]
+	d.buffered = copy(d.encrypted, d.encrypted[size:d.buffered])
+}
+
+/// ----------------------------------------------------------------------- ///
+
+type encryptWriter struct {
+	w    io.Writer
+	mode cipher.BlockMode
+
+	buf    []byte
+	nbuf   int // The number of the bytes buffered in buf, which is less than a block.
+	closed bool
+}
+
+// NewEncryptWriter returns a writer that encrypts the data written into it
+// by AES-128 CBC block by block, and writes the encrypted data into w.
+//
+// Close must be called to add the PKCS7 padding and flush the last block,
+// but it does not close w.
+func NewEncryptWriter(w io.Writer, key, iv []byte) (io.WriteCloser, error) {
+	mode, err := newBlockMode(key, iv, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, mode: mode, buf: make([]byte, streamBufferSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, errWriterClosed
+	}
+
+	for len(p) > 0 {
+		m := copy(e.buf[e.nbuf:], p)
+		e.nbuf += m
+		p = p[m:]
+
+		if size := e.nbuf &^ (aes.BlockSize - 1); size > 0 {
+			if err = e.flush(size); err != nil {
+				return
+			}
+		}
+		n += m
+	}
+
+	return
+}
+
+func (e *encryptWriter) flush(size int) (err error) {
+	e.mode.CryptBlocks(e.buf[:size], e.buf[:size])
+	if _, err = e.w.Write(e.buf[:size]); err == nil {
+		e.nbuf = copy(e.buf, e.buf[size:e.nbuf])
+	}
+	return
+}
+
+// Close adds the PKCS7 padding and flushes the last block.
+func (e *encryptWriter) Close() (err error) {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	padding := addPKCS7Padding(e.buf[:e.nbuf], aes.BlockSize)
+	e.nbuf = copy(e.buf, padding)
+	return e.flush(e.nbuf)
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aes128
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStream(t *testing.T) {
+	var (
+		key = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		iv  = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	)
+
+	for _, size := range []int{0, 1, 15, 16, 17, 1000, streamBufferSize, streamBufferSize*3 + 7} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+
+		expect, err := Encrypt(data, key, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		w, err := NewEncryptWriter(&buf, key, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Write in odd-sized chunks.
+		for p := data; len(p) > 0; {
+			n := min(len(p), 1001)
+			if _, err = w.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), expect) {
+			t.Errorf("size=%d: the encrypted data is not equal to Encrypt", size)
+		}
+
+		for _, r := range []io.Reader{bytes.NewReader(expect), iotest.OneByteReader(bytes.NewReader(expect))} {
+			r, err := NewDecryptReader(r, key, iv)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("size=%d: %v", size, err)
+			} else if !bytes.Equal(decrypted, data) {
+				t.Errorf("size=%d: the decrypted data is not equal to the original", size)
+			}
+		}
+	}
+
+	r, _ := NewDecryptReader(bytes.NewReader(make([]byte, 17)), key, iv)
+	if _, err := io.ReadAll(r); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cenc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/xgfone/go-hls/fmp4"
+)
+
+var errInvalidBox = errors.New("invalid box")
+
+// maxSampleCount is the maximum number of the samples in a box.
+const maxSampleCount = 1 << 20
+
+// KeyID is the 16-byte key identifier.
+type KeyID [16]byte
+
+// String returns the hexadecimal string of the key id.
+func (id KeyID) String() string { return hex.EncodeToString(id[:]) }
+
+// TrackEncryption is the track encryption box "tenc".
+type TrackEncryption struct {
+	CryptByteBlock  uint8 // Only for the pattern encryption, such as "cbcs".
+	SkipByteBlock   uint8 // Only for the pattern encryption, such as "cbcs".
+	IsProtected     uint8
+	PerSampleIVSize uint8 // 0, 8 or 16
+	KID             KeyID
+	ConstantIV      []byte // Only if IsProtected is 1 and PerSampleIVSize is 0.
+}
+
+// ParseTenc parses the track encryption box "tenc".
+func ParseTenc(b fmp4.Box) (tenc TrackEncryption, err error) {
+	version, _, data, err := b.FullBox()
+	if err != nil {
+		return
+	} else if len(data) < 20 {
+		return tenc, fmt.Errorf("%w: 'tenc' is too short", errInvalidBox)
+	}
+
+	if version > 0 {
+		tenc.CryptByteBlock = data[1] >> 4
+		tenc.SkipByteBlock = data[1] & 0x0F
+	}
+	tenc.IsProtected = data[2]
+	tenc.PerSampleIVSize = data[3]
+	copy(tenc.KID[:], data[4:20])
+
+	if tenc.IsProtected == 1 && tenc.PerSampleIVSize == 0 {
+		if len(data) < 21 || len(data) < 21+int(data[20]) {
+			return tenc, fmt.Errorf("%w: missing the constant IV in 'tenc'", errInvalidBox)
+		}
+		tenc.ConstantIV = data[21 : 21+data[20]]
+	}
+
+	return
+}
+
+// ProtectionSystem is the protection system specific header box "pssh".
+type ProtectionSystem struct {
+	SystemID KeyID
+	KIDs     []KeyID
+	Data     []byte
+}
+
+// ParsePssh parses the protection system specific header box "pssh".
+func ParsePssh(b fmp4.Box) (pssh ProtectionSystem, err error) {
+	version, _, data, err := b.FullBox()
+	if err != nil {
+		return
+	} else if len(data) < 16 {
+		return pssh, fmt.Errorf("%w: 'pssh' is too short", errInvalidBox)
+	}
+
+	copy(pssh.SystemID[:], data[:16])
+	data = data[16:]
+
+	if version > 0 {
+		if len(data) < 4 {
+			return pssh, fmt.Errorf("%w: 'pssh' is too short", errInvalidBox)
+		}
+
+		count := int(binary.BigEndian.Uint32(data[:4]))
+		if data = data[4:]; count > len(data)/16 {
+			return pssh, fmt.Errorf("%w: invalid KID count in 'pssh'", errInvalidBox)
+		}
+
+		pssh.KIDs = make([]KeyID, count)
+		for i := range pssh.KIDs {
+			copy(pssh.KIDs[i][:], data[:16])
+			data = data[16:]
+		}
+	}
+
+	if len(data) < 4 || len(data)-4 < int(binary.BigEndian.Uint32(data[:4])) {
+		return pssh, fmt.Errorf("%w: invalid data size in 'pssh'", errInvalidBox)
+	}
+	pssh.Data = data[4 : 4+binary.BigEndian.Uint32(data[:4])]
+	return
+}
+
+// Subsample is the clear and protected bytes of a subsample.
+type Subsample struct {
+	ClearBytes     uint16
+	ProtectedBytes uint32
+}
+
+// SampleEncryption is the encryption information of a sample,
+// which is in the sample encryption box "senc", or is the sample
+// auxiliary information referred by "saiz" and "saio".
+type SampleEncryption struct {
+	IV         []byte
+	Subsamples []Subsample // If empty, the whole sample is protected.
+}
+
+// sencUseSubsampleEncryption is the flag of "senc".
+const sencUseSubsampleEncryption = 0x000002
+
+// ParseSenc parses the sample encryption box "senc" with the per-sample
+// IV size, which comes from "tenc".
+func ParseSenc(b fmp4.Box, ivSize uint8) (samples []SampleEncryption, err error) {
+	_, flags, data, err := b.FullBox()
+	if err != nil {
+		return
+	} else if len(data) < 4 {
+		return nil, fmt.Errorf("%w: 'senc' is too short", errInvalidBox)
+	}
+
+	subsample := flags&sencUseSubsampleEncryption != 0
+	minsize := int(ivSize) // The minimum size of each sample.
+	if subsample {
+		minsize += 2
+	}
+
+	count := int(binary.BigEndian.Uint32(data[:4]))
+	if data = data[4:]; count > maxSampleCount || (minsize > 0 && count > len(data)/minsize) {
+		return nil, fmt.Errorf("%w: invalid sample count in 'senc'", errInvalidBox)
+	}
+
+	samples = make([]SampleEncryption, count)
+	for i := range samples {
+		if data, err = parseSampleEncryption(&samples[i], data, ivSize, subsample); err != nil {
+			return nil, fmt.Errorf("'senc' sample %d: %w", i, err)
+		}
+	}
+	return
+}
+
+func parseSampleEncryption(s *SampleEncryption, data []byte, ivSize uint8, subsample bool) ([]byte, error) {
+	if len(data) < int(ivSize) {
+		return nil, errInvalidBox
+	}
+	s.IV, data = data[:ivSize], data[ivSize:]
+
+	if subsample {
+		if len(data) < 2 {
+			return nil, errInvalidBox
+		}
+
+		count := int(binary.BigEndian.Uint16(data[:2]))
+		if data = data[2:]; len(data) < count*6 {
+			return nil, errInvalidBox
+		}
+
+		s.Subsamples = make([]Subsample, count)
+		for i := range s.Subsamples {
+			s.Subsamples[i] = Subsample{
+				ClearBytes:     binary.BigEndian.Uint16(data[:2]),
+				ProtectedBytes: binary.BigEndian.Uint32(data[2:6]),
+			}
+			data = data[6:]
+		}
+	}
+
+	return data, nil
+}
+
+// SampleAuxInfoSizes is the sample auxiliary information sizes box "saiz".
+type SampleAuxInfoSizes struct {
+	DefaultSize uint8
+	SampleCount uint32
+	Sizes       []uint8 // Only if DefaultSize is 0.
+}
+
+// Size returns the size of the auxiliary information of the i-th sample.
+func (s SampleAuxInfoSizes) Size(i int) int {
+	if s.DefaultSize > 0 {
+		return int(s.DefaultSize)
+	} else if i < len(s.Sizes) {
+		return int(s.Sizes[i])
+	}
+	return 0
+}
+
+// ParseSaiz parses the sample auxiliary information sizes box "saiz".
+func ParseSaiz(b fmp4.Box) (saiz SampleAuxInfoSizes, err error) {
+	_, flags, data, err := b.FullBox()
+	if err != nil {
+		return
+	}
+
+	if flags&1 != 0 { // aux_info_type and aux_info_type_parameter
+		if len(data) < 8 {
+			return saiz, fmt.Errorf("%w: 'saiz' is too short", errInvalidBox)
+		}
+		data = data[8:]
+	}
+
+	if len(data) < 5 {
+		return saiz, fmt.Errorf("%w: 'saiz' is too short", errInvalidBox)
+	}
+
+	saiz.DefaultSize = data[0]
+	saiz.SampleCount = binary.BigEndian.Uint32(data[1:5])
+	if saiz.DefaultSize == 0 {
+		if uint64(len(data)-5) < uint64(saiz.SampleCount) {
+			return saiz, fmt.Errorf("%w: 'saiz' is too short", errInvalidBox)
+		}
+		saiz.Sizes = data[5 : 5+saiz.SampleCount]
+	}
+
+	return
+}
+
+// ParseSaio parses the sample auxiliary information offsets box "saio",
+// and returns the offsets.
+func ParseSaio(b fmp4.Box) (offsets []uint64, err error) {
+	version, flags, data, err := b.FullBox()
+	if err != nil {
+		return
+	}
+
+	if flags&1 != 0 { // aux_info_type and aux_info_type_parameter
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%w: 'saio' is too short", errInvalidBox)
+		}
+		data = data[8:]
+	}
+
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: 'saio' is too short", errInvalidBox)
+	}
+
+	size := 4
+	if version > 0 {
+		size = 8
+	}
+
+	count := int(binary.BigEndian.Uint32(data[:4]))
+	if data = data[4:]; len(data) < count*size {
+		return nil, fmt.Errorf("%w: 'saio' is too short", errInvalidBox)
+	}
+
+	offsets = make([]uint64, count)
+	for i := range offsets {
+		if size == 8 {
+			offsets[i] = binary.BigEndian.Uint64(data[i*8:])
+		} else {
+			offsets[i] = uint64(binary.BigEndian.Uint32(data[i*4:]))
+		}
+	}
+	return
+}
@@ -0,0 +1,437 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cenc provides some functions to decrypt the fragmented MP4
+// encrypted by the Common Encryption defined in ISO/IEC 23001-7,
+// such as the fMP4 media segments of HLS with METHOD=SAMPLE-AES
+// or METHOD=SAMPLE-AES-CTR.
+//
+// It supports the schemes "cenc" (AES-CTR) and "cbcs" (AES-CBC
+// with the pattern encryption) with the clear key.
+package cenc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/xgfone/go-hls/fmp4"
+)
+
+// Some protection scheme types.
+const (
+	SchemeCENC = "cenc"
+	SchemeCBCS = "cbcs"
+)
+
+var errInvalidKey = errors.New("invalid content key")
+
+// Track is the protection information of an encrypted track.
+type Track struct {
+	ID             uint32
+	Scheme         string // Such as "cenc" or "cbcs".
+	OriginalFormat string // Such as "avc1" or "mp4a".
+	Encryption     TrackEncryption
+
+	entry fmp4.Box // The sample entry, such as "encv" or "enca".
+	sinf  fmp4.Box
+}
+
+// Init is the protection information of the init section.
+type Init struct {
+	Tracks map[uint32]Track // Only contain the encrypted tracks.
+	Trexs  map[uint32]fmp4.TrackExtends
+	PSSHs  []ProtectionSystem
+
+	data  []byte
+	boxes []fmp4.Box
+}
+
+// ParseInit parses the protection information from the init section,
+// that's, the media initialization section referred by EXT-X-MAP.
+func ParseInit(init []byte) (info Init, err error) {
+	info.data = init
+	if info.boxes, err = fmp4.ReadBoxes(init); err != nil {
+		return
+	}
+
+	if info.Trexs, err = fmp4.ParseTrexs(info.boxes); err != nil {
+		return
+	}
+
+	for _, box := range fmp4.FindBoxes(info.boxes, "moov", "pssh") {
+		pssh, err := ParsePssh(box)
+		if err != nil {
+			return info, err
+		}
+		info.PSSHs = append(info.PSSHs, pssh)
+	}
+
+	info.Tracks = make(map[uint32]Track, 2)
+	for _, trak := range fmp4.FindBoxes(info.boxes, "moov", "trak") {
+		track, ok, err := parseTrack(trak)
+		if err != nil {
+			return info, err
+		} else if ok {
+			info.Tracks[track.ID] = track
+		}
+	}
+
+	return
+}
+
+func parseTrack(trak fmp4.Box) (track Track, ok bool, err error) {
+	boxes, err := trak.Children()
+	if err != nil {
+		return
+	}
+
+	tkhd, ok := fmp4.FindBox(boxes, "tkhd")
+	if !ok {
+		return track, false, fmt.Errorf("%w: missing 'tkhd'", errInvalidBox)
+	}
+
+	version, _, data, err := tkhd.FullBox()
+	if err != nil {
+		return
+	} else if version == 1 && len(data) >= 20 {
+		track.ID = binary.BigEndian.Uint32(data[16:20])
+	} else if version == 0 && len(data) >= 12 {
+		track.ID = binary.BigEndian.Uint32(data[8:12])
+	} else {
+		return track, false, fmt.Errorf("%w: invalid 'tkhd'", errInvalidBox)
+	}
+
+	stsd, ok := fmp4.FindBox(boxes, "mdia", "minf", "stbl", "stsd")
+	if !ok {
+		return
+	}
+
+	entries, err := stsd.ChildrenAt(8) // version, flags and entry_count
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		var skip int
+		switch entry.Type {
+		case "encv":
+			skip = 78 // VisualSampleEntry
+		case "enca":
+			skip = 28 // AudioSampleEntry
+			if payload := entry.Payload(); len(payload) >= 10 {
+				switch binary.BigEndian.Uint16(payload[8:10]) { // QuickTime sound version
+				case 1:
+					skip += 16
+				case 2:
+					skip += 36
+				}
+			}
+		default:
+			continue
+		}
+
+		children, err := entry.ChildrenAt(skip)
+		if err != nil {
+			return track, false, err
+		}
+
+		sinf, ok := fmp4.FindBox(children, "sinf")
+		if !ok {
+			continue
+		}
+
+		track.entry, track.sinf = entry, sinf
+		return track, true, track.parseSinf()
+	}
+
+	return track, false, nil
+}
+
+func (t *Track) parseSinf() error {
+	boxes, err := t.sinf.Children()
+	if err != nil {
+		return err
+	}
+
+	if frma, ok := fmp4.FindBox(boxes, "frma"); ok && len(frma.Payload()) >= 4 {
+		t.OriginalFormat = string(frma.Payload()[:4])
+	}
+
+	if schm, ok := fmp4.FindBox(boxes, "schm"); ok {
+		if _, _, data, err := schm.FullBox(); err != nil {
+			return err
+		} else if len(data) >= 4 {
+			t.Scheme = string(data[:4])
+		}
+	}
+
+	tenc, ok := fmp4.FindBox(boxes, "schi", "tenc")
+	if !ok {
+		return fmt.Errorf("%w: missing 'tenc' in track %d", errInvalidBox, t.ID)
+	}
+
+	t.Encryption, err = ParseTenc(tenc)
+	return err
+}
+
+// DecryptSegment is a convenient function to parse the init section
+// and decrypt the media segment with the key.
+func DecryptSegment(init, segment, key []byte) ([]byte, error) {
+	info, err := ParseInit(init)
+	if err != nil {
+		return nil, err
+	}
+	return info.Decrypt(segment, key)
+}
+
+// Decrypt decrypts the samples of the encrypted tracks in the media segment
+// with the key, and returns the decrypted copy, the boxes of which are kept.
+//
+// The sample encryption information comes from the box "senc",
+// or "saiz" and "saio" if "senc" is absent.
+func (i Init) Decrypt(segment, key []byte) ([]byte, error) {
+	if len(key) != 16 {
+		return nil, errInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	segment = bytes.Clone(segment)
+	boxes, err := fmp4.ReadBoxes(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, moof := range boxes {
+		if moof.Type != "moof" {
+			continue
+		}
+
+		trafs, err := fmp4.ParseMovieFragment(moof, i.Trexs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, traf := range trafs {
+			track, ok := i.Tracks[traf.Header.TrackID]
+			if !ok || track.Encryption.IsProtected == 0 {
+				continue
+			}
+
+			if err = track.decrypt(block, segment, traf); err != nil {
+				return nil, fmt.Errorf("track %d: %w", track.ID, err)
+			}
+		}
+	}
+
+	return segment, nil
+}
+
+func (t Track) decrypt(block cipher.Block, segment []byte, traf fmp4.TrackFragment) error {
+	samples, err := t.sampleEncryptions(segment, traf)
+	if err != nil {
+		return err
+	} else if len(samples) < len(traf.Samples) {
+		return fmt.Errorf("%w: missing the encryption information of %d samples",
+			errInvalidBox, len(traf.Samples)-len(samples))
+	}
+
+	for i, s := range traf.Samples {
+		end := s.Offset + int(s.Size)
+		if s.Offset < 0 || end > len(segment) {
+			return fmt.Errorf("%w: sample %d is out of range", errInvalidBox, i)
+		}
+
+		iv := samples[i].IV
+		if len(iv) == 0 {
+			iv = t.Encryption.ConstantIV
+		}
+
+		data, subsamples := segment[s.Offset:end], samples[i].Subsamples
+		switch t.Scheme {
+		case SchemeCENC:
+			err = decryptCTR(block, iv, data, subsamples)
+		case SchemeCBCS:
+			err = decryptCBCS(block, iv, data, subsamples,
+				t.Encryption.CryptByteBlock, t.Encryption.SkipByteBlock)
+		default:
+			err = fmt.Errorf("unsupported protection scheme '%s'", t.Scheme)
+		}
+
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (t Track) sampleEncryptions(segment []byte, traf fmp4.TrackFragment) ([]SampleEncryption, error) {
+	boxes, err := traf.Box.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	if senc, ok := fmp4.FindBox(boxes, "senc"); ok {
+		return ParseSenc(senc, t.Encryption.PerSampleIVSize)
+	}
+
+	saizbox, ok1 := fmp4.FindBox(boxes, "saiz")
+	saiobox, ok2 := fmp4.FindBox(boxes, "saio")
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("%w: missing 'senc', or 'saiz' and 'saio'", errInvalidBox)
+	}
+
+	saiz, err := ParseSaiz(saizbox)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := ParseSaio(saiobox)
+	if err != nil {
+		return nil, err
+	} else if len(offsets) == 0 {
+		return nil, fmt.Errorf("%w: no offset in 'saio'", errInvalidBox)
+	}
+
+	// If only one offset, the auxiliary information of all the samples
+	// is contiguous. Or, there is one offset for each track run.
+	resets := map[int]uint64{0: offsets[0]}
+	if len(offsets) > 1 {
+		if len(offsets) != len(traf.Runs) {
+			return nil, fmt.Errorf("%w: mismatched offsets in 'saio'", errInvalidBox)
+		}
+
+		var index int
+		for k, run := range traf.Runs {
+			resets[index] = offsets[k]
+			index += len(run.Samples)
+		}
+	}
+
+	var offset uint64
+	samples := make([]SampleEncryption, saiz.SampleCount)
+	for i := range samples {
+		if o, ok := resets[i]; ok {
+			offset = uint64(traf.BaseDataOffset) + o
+		}
+
+		size := uint64(saiz.Size(i))
+		if offset+size > uint64(len(segment)) {
+			return nil, fmt.Errorf("%w: the auxiliary information is out of range", errInvalidBox)
+		}
+
+		info := segment[offset : offset+size]
+		subsample := size > uint64(t.Encryption.PerSampleIVSize)
+		if _, err = parseSampleEncryption(&samples[i], info, t.Encryption.PerSampleIVSize, subsample); err != nil {
+			return nil, fmt.Errorf("%w: invalid auxiliary information of sample %d", errInvalidBox, i)
+		}
+
+		// Copy the IV, because the segment data will be decrypted in place.
+		samples[i].IV = bytes.Clone(samples[i].IV)
+		offset += size
+	}
+
+	return samples, nil
+}
+
+// protectedRanges calls f with the protected ranges of the sample in turn.
+func protectedRanges(data []byte, subsamples []Subsample, f func([]byte)) error {
+	if len(subsamples) == 0 {
+		f(data)
+		return nil
+	}
+
+	for _, s := range subsamples {
+		start := int(s.ClearBytes)
+		end := start + int(s.ProtectedBytes)
+		if end > len(data) {
+			return fmt.Errorf("%w: the subsample is out of range", errInvalidBox)
+		}
+
+		f(data[start:end])
+		data = data[end:]
+	}
+	return nil
+}
+
+// decryptCTR decrypts the sample in place by the scheme "cenc",
+// the protected ranges of which are treated as the contiguous data.
+func decryptCTR(block cipher.Block, iv, data []byte, subsamples []Subsample) error {
+	if len(iv) != 8 && len(iv) != 16 {
+		return fmt.Errorf("invalid iv size %d", len(iv))
+	}
+
+	var counter [aes.BlockSize]byte
+	copy(counter[:], iv) // The 8-byte IV is padded with 0.
+	stream := cipher.NewCTR(block, counter[:])
+	return protectedRanges(data, subsamples, func(b []byte) {
+		stream.XORKeyStream(b, b)
+	})
+}
+
+// decryptCBCS decrypts the sample in place by the scheme "cbcs",
+// the cipher block chaining of which is reset for each subsample.
+//
+// If both crypt and skip are 0, all the blocks are encrypted.
+// The remaining partial block is always not encrypted.
+func decryptCBCS(block cipher.Block, iv, data []byte, subsamples []Subsample, crypt, skip uint8) error {
+	if len(iv) != aes.BlockSize {
+		return fmt.Errorf("invalid iv size %d", len(iv))
+	}
+
+	return protectedRanges(data, subsamples, func(b []byte) {
+		mode := cipher.NewCBCDecrypter(block, iv)
+		if crypt == 0 && skip == 0 {
+			n := len(b) &^ (aes.BlockSize - 1)
+			mode.CryptBlocks(b[:n], b[:n])
+			return
+		}
+
+		for len(b) >= aes.BlockSize {
+			n := min(int(crypt)*aes.BlockSize, len(b)&^(aes.BlockSize-1))
+			mode.CryptBlocks(b[:n], b[:n])
+			b = b[n:]
+			b = b[min(int(skip)*aes.BlockSize, len(b)):]
+		}
+	})
+}
+
+// ClearInit returns a copy of the init section, which makes the encrypted
+// tracks to be clear by restoring the original format of the sample entries
+// and replacing the boxes "sinf" and "pssh" with the box "free".
+//
+// So the decrypted media segments can be played with it directly.
+func (i Init) ClearInit() []byte {
+	init := bytes.Clone(i.data)
+	for _, track := range i.Tracks {
+		if len(track.OriginalFormat) == 4 {
+			copy(init[track.entry.Offset+4:], track.OriginalFormat)
+		}
+		copy(init[track.sinf.Offset+4:], "free")
+	}
+
+	for _, pssh := range fmp4.FindBoxes(i.boxes, "moov", "pssh") {
+		copy(init[pssh.Offset+4:], "free")
+	}
+
+	return init
+}
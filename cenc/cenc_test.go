@@ -0,0 +1,220 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cenc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"testing"
+
+	"github.com/xgfone/go-hls/fmp4"
+)
+
+var (
+	testKey = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	testKID = KeyID{15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0}
+	testIV  = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+)
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// newInit returns an init section with two encrypted tracks:
+// track 1 is the video encrypted by "cbcs" with the pattern 1:9,
+// and track 2 is the audio encrypted by "cenc" with the 8-byte IVs.
+func newInit() []byte {
+	box, full := fmp4.AppendBox, fmp4.AppendFullBox
+
+	trak := func(id uint32, entry string, entryFields []byte, format, scheme string, tenc []byte) []byte {
+		sinf := box(nil, "sinf",
+			box(nil, "frma", []byte(format)),
+			full(nil, "schm", 0, 0, []byte(scheme), u32(0x10000)),
+			box(nil, "schi", tenc),
+		)
+		stsd := full(nil, "stsd", 0, 0, u32(1), box(nil, entry, entryFields, sinf))
+		return box(nil, "trak",
+			full(nil, "tkhd", 0, 3, make([]byte, 8), u32(id), make([]byte, 68)),
+			box(nil, "mdia", box(nil, "minf", box(nil, "stbl", stsd))),
+		)
+	}
+
+	video := trak(1, "encv", make([]byte, 78), "avc1", SchemeCBCS,
+		full(nil, "tenc", 1, 0, []byte{0, 1<<4 | 9, 1, 0}, testKID[:], []byte{16}, testIV))
+	audio := trak(2, "enca", make([]byte, 28), "mp4a", SchemeCENC,
+		full(nil, "tenc", 0, 0, []byte{0, 0, 1, 8}, testKID[:]))
+
+	return append(box(nil, "ftyp", []byte("iso6"), u32(0)), box(nil, "moov",
+		video, audio,
+		box(nil, "mvex",
+			full(nil, "trex", 0, 0, u32(1), u32(1), u32(0), u32(0), u32(0)),
+			full(nil, "trex", 0, 0, u32(2), u32(1), u32(1024), u32(100), u32(0)),
+		),
+		full(nil, "pssh", 1, 0, make([]byte, 16), u32(1), testKID[:], u32(3), []byte("abc")),
+	)...)
+}
+
+func newSample(size int, seed byte) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)*seed + seed
+	}
+	return data
+}
+
+func encryptCBCS(data []byte, subsamples []Subsample) {
+	block, _ := aes.NewCipher(testKey)
+	_ = protectedRanges(data, subsamples, func(b []byte) {
+		mode := cipher.NewCBCEncrypter(block, testIV)
+		for len(b) >= 16 {
+			n := min(16, len(b)&^15)
+			mode.CryptBlocks(b[:n], b[:n])
+			b = b[n:]
+			b = b[min(9*16, len(b)):]
+		}
+	})
+}
+
+// newSegment returns the clear and encrypted media segments.
+//
+// If saio is true, the sample encryption information of the audio track
+// is referred by "saiz" and "saio" instead of "senc".
+func newSegment(saio bool) (clear, encrypted []byte) {
+	box, full := fmp4.AppendBox, fmp4.AppendFullBox
+	block, _ := aes.NewCipher(testKey)
+
+	videoSubsamples := [][]Subsample{
+		{{ClearBytes: 5, ProtectedBytes: 300}, {ClearBytes: 10, ProtectedBytes: 185}},
+		{{ClearBytes: 20, ProtectedBytes: 180}},
+	}
+	videos := [][]byte{newSample(500, 3), newSample(200, 5)}
+	audios := [][]byte{newSample(100, 7), newSample(100, 9), newSample(100, 11)}
+	audioIVs := [][]byte{{1, 1, 1, 1, 1, 1, 1, 1}, {2, 2, 2, 2, 2, 2, 2, 2}, {3, 3, 3, 3, 3, 3, 3, 3}}
+
+	var mdat, encmdat []byte
+	for i, sample := range videos {
+		mdat = append(mdat, sample...)
+		sample = bytes.Clone(sample)
+		encryptCBCS(sample, videoSubsamples[i])
+		encmdat = append(encmdat, sample...)
+	}
+	for i, sample := range audios {
+		mdat = append(mdat, sample...)
+		sample = bytes.Clone(sample)
+		_ = decryptCTR(block, audioIVs[i], sample, nil) // CTR is symmetric.
+		encmdat = append(encmdat, sample...)
+	}
+
+	moof := func(offset uint32, saioOffset uint32) []byte {
+		vsenc := u32(2)
+		for _, subsamples := range videoSubsamples {
+			vsenc = append(vsenc, u16(uint16(len(subsamples)))...)
+			for _, s := range subsamples {
+				vsenc = append(append(vsenc, u16(s.ClearBytes)...), u32(s.ProtectedBytes)...)
+			}
+		}
+
+		var aux []byte
+		if saio {
+			aux = append(full(nil, "saiz", 0, 0, []byte{8}, u32(3)),
+				full(nil, "saio", 0, 0, u32(1), u32(saioOffset))...)
+			aux = box(aux, "free", bytes.Join(audioIVs, nil))
+		} else {
+			aux = full(nil, "senc", 0, 0, u32(3), bytes.Join(audioIVs, nil))
+		}
+
+		return box(nil, "moof",
+			full(nil, "mfhd", 0, 0, u32(1)),
+			box(nil, "traf",
+				full(nil, "tfhd", 0, fmp4.TfhdDefaultBaseIsMoof, u32(1)),
+				full(nil, "trun", 0, fmp4.TrunDataOffsetPresent|fmp4.TrunSampleSizePresent,
+					u32(2), u32(offset), u32(500), u32(200)),
+				full(nil, "senc", 0, sencUseSubsampleEncryption, vsenc),
+			),
+			box(nil, "traf",
+				full(nil, "tfhd", 0, fmp4.TfhdDefaultBaseIsMoof, u32(2)),
+				full(nil, "trun", 0, fmp4.TrunDataOffsetPresent, u32(3), u32(offset+700)),
+				aux,
+			),
+		)
+	}
+
+	size := uint32(len(moof(0, 0)))
+	header := moof(size+8, 0)
+	if saio {
+		offset := bytes.Index(header, []byte("free")) + 4
+		header = moof(size+8, uint32(offset))
+	}
+
+	clear = box(bytes.Clone(header), "mdat", mdat)
+	encrypted = box(header, "mdat", encmdat)
+	return
+}
+
+func TestDecrypt(t *testing.T) {
+	init, err := ParseInit(newInit())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(init.Tracks) != 2 {
+		t.Fatalf("expect %d encrypted tracks, but got %d", 2, len(init.Tracks))
+	}
+	if track := init.Tracks[1]; track.Scheme != SchemeCBCS || track.OriginalFormat != "avc1" ||
+		track.Encryption.CryptByteBlock != 1 || track.Encryption.SkipByteBlock != 9 ||
+		!bytes.Equal(track.Encryption.ConstantIV, testIV) {
+		t.Errorf("unexpected video track: %+v", track)
+	}
+	if track := init.Tracks[2]; track.Scheme != SchemeCENC || track.OriginalFormat != "mp4a" ||
+		track.Encryption.PerSampleIVSize != 8 || track.Encryption.KID != testKID {
+		t.Errorf("unexpected audio track: %+v", track)
+	}
+	if len(init.PSSHs) != 1 || len(init.PSSHs[0].KIDs) != 1 || string(init.PSSHs[0].Data) != "abc" {
+		t.Errorf("unexpected pssh: %+v", init.PSSHs)
+	}
+
+	for _, saio := range []bool{false, true} {
+		clear, encrypted := newSegment(saio)
+		if bytes.Equal(clear, encrypted) {
+			t.Fatal("the segment is not encrypted")
+		}
+
+		decrypted, err := init.Decrypt(encrypted, testKey)
+		if err != nil {
+			t.Errorf("saio=%v: %v", saio, err)
+		} else if !bytes.Equal(decrypted, clear) {
+			t.Errorf("saio=%v: the decrypted segment is not equal to the original", saio)
+		}
+	}
+
+	if _, err := init.Decrypt(nil, testKey[:8]); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+
+	cleared, err := ParseInit(init.ClearInit())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(cleared.Tracks) != 0 || len(cleared.PSSHs) != 0 {
+		t.Errorf("expect no encrypted tracks and pssh, but got %d and %d", len(cleared.Tracks), len(cleared.PSSHs))
+	}
+
+	boxes, _ := fmp4.ReadBoxes(init.ClearInit())
+	if stsd, ok := fmp4.FindBox(boxes, "moov", "trak", "mdia", "minf", "stbl", "stsd"); !ok {
+		t.Errorf("missing stsd")
+	} else if entries, _ := stsd.ChildrenAt(8); len(entries) != 1 || entries[0].Type != "avc1" {
+		t.Errorf("expect the sample entry 'avc1', but got %+v", entries)
+	}
+}
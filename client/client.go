@@ -0,0 +1,165 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/xgfone/go-toolkit/httpx"
+)
+
+// DefaultClient is the default client used by the package-level functions.
+var DefaultClient = new(Client)
+
+// Doer is an interface to send a http request and return a http response,
+// such as *http.Client and httpx.DoFunc.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Middleware is used to wrap a Doer to do something before or after
+// sending the http request, such as signing, logging, metrics, etc.
+type Middleware func(Doer) Doer
+
+// Client is a HTTP client to download the playlists, segments and keys.
+type Client struct {
+	// Doer is used to send the http request.
+	//
+	// Default: httpx.GetClient()
+	Doer Doer
+
+	// Header is the default headers added into each http request,
+	// which may be overridden by the options.
+	Header http.Header
+
+	// UserAgent is the default value of the header "User-Agent".
+	UserAgent string
+
+	// Jar is used to insert the cookies into each http request,
+	// and updated with the cookies of each http response.
+	Jar http.CookieJar
+
+	// Timeout is the timeout of each request, including reading the body.
+	Timeout time.Duration
+
+	// Middlewares is the middleware chain to wrap Doer, the first of which
+	// is the outermost.
+	Middlewares []Middleware
+}
+
+// NewClient returns a new client with the doer.
+func NewClient(doer Doer) *Client {
+	return &Client{Doer: doer}
+}
+
+func getClient(c *Client) *Client {
+	if c == nil {
+		return DefaultClient
+	}
+	return c
+}
+
+// Use appends the middlewares into the middleware chain, and returns itself.
+func (c *Client) Use(mws ...Middleware) *Client {
+	c.Middlewares = append(c.Middlewares, mws...)
+	return c
+}
+
+func (c *Client) doer() (doer Doer) {
+	if doer = c.Doer; doer == nil {
+		doer = httpx.GetClient()
+	}
+
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		doer = c.Middlewares[i](doer)
+	}
+	return
+}
+
+func (c *Client) request(ctx context.Context, method, url string, body io.Reader,
+	do func(*http.Response) error, options ...Option) (err error) {
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return
+	}
+
+	for key, values := range c.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.Jar != nil {
+		for _, cookie := range c.Jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	for i := range options {
+		req = options[i](req)
+	}
+
+	resp, err := c.doer().Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if c.Jar != nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			c.Jar.SetCookies(req.URL, cookies)
+		}
+	}
+
+	return do(resp)
+}
+
+// Logging returns a middleware to log the http request by slog.
+func Logging(level slog.Level) Middleware {
+	return func(next Doer) Doer {
+		return httpx.DoFunc(func(req *http.Request) (resp *http.Response, err error) {
+			start := time.Now()
+			resp, err = next.Do(req)
+
+			ctx := req.Context()
+			if logger := slog.Default(); logger.Enabled(ctx, level) {
+				attrs := []slog.Attr{
+					slog.String("method", req.Method),
+					slog.String("url", req.URL.String()),
+					slog.Duration("cost", time.Since(start)),
+				}
+				if err != nil {
+					attrs = append(attrs, slog.String("err", err.Error()))
+				} else {
+					attrs = append(attrs, slog.Int("statuscode", resp.StatusCode))
+				}
+				logger.LogAttrs(ctx, level, "http request", attrs...)
+			}
+
+			return
+		})
+	}
+}
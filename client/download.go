@@ -21,48 +21,90 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"unsafe"
 
-	"github.com/xgfone/go-toolkit/httpx"
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
 )
 
-func request(ctx context.Context, method, url string, body io.Reader,
-	do func(*http.Response) error, options ...Option) (err error) {
-
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
-	if err != nil {
-		return
-	}
-
-	for i := range options {
-		req = options[i](req)
-	}
+// Get is a convenient function to download something by HTTP
+// with DefaultClient.
+func Get(ctx context.Context, url string, do func(*http.Response) error, options ...Option) error {
+	return DefaultClient.Get(ctx, url, do, options...)
+}
 
-	resp, err := httpx.GetClient().Do(req)
-	if err != nil {
-		return
-	}
+// StatusError is the error returned by Get when the status code
+// of the response is not 2xx.
+type StatusError struct {
+	StatusCode int
+	Err        error // The response body as the error, or the error to read it.
+}
 
-	return do(resp)
+// Error implements the interface error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("statuscode=%d, err=%s", e.StatusCode, e.Err)
 }
 
-// Get is a convenient function to download something by HTTP.
-func Get(ctx context.Context, url string, do func(*http.Response) error, options ...Option) error {
-	return request(ctx, http.MethodGet, url, nil, func(r *http.Response) (err error) {
+// Unwrap returns the inner error.
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// Get is a convenient method to download something by HTTP.
+//
+// If the status code of the response is not 2xx, return a *StatusError
+// without calling do.
+func (c *Client) Get(ctx context.Context, url string, do func(*http.Response) error, options ...Option) error {
+	return c.request(ctx, http.MethodGet, url, nil, func(r *http.Response) (err error) {
 		if r.StatusCode < 200 || r.StatusCode >= 300 {
 			data, err := io.ReadAll(r.Body)
 			if err != nil {
-				return fmt.Errorf("statuscode=%d, err=%w", r.StatusCode, err)
+				return &StatusError{StatusCode: r.StatusCode, Err: err}
 			}
 
 			msg := unsafe.String(unsafe.SliceData(data), len(data))
-			return fmt.Errorf("statuscode=%d, err=%s", r.StatusCode, msg)
+			return &StatusError{StatusCode: r.StatusCode, Err: errors.New(msg)}
 		}
 		return do(r)
 	}, options...)
 }
 
+// GetDecryptedRange downloads the byte range of the resource encrypted
+// by AES-128 with DefaultClient, and returns the decrypted data.
+func GetDecryptedRange(ctx context.Context, url string, br playlist.XByteRange,
+	key, iv []byte, options ...Option) ([]byte, error) {
+	return DefaultClient.GetDecryptedRange(ctx, url, br, key, iv, options...)
+}
+
+// GetDecryptedRange downloads the byte range of the resource encrypted
+// by AES-128, and returns the decrypted data exactly in the byte range,
+// such as the I-frame in an encrypted media segment.
+//
+// It only requests the range aligned at 16 bytes, which contains
+// the preceding 16-byte block as the IV. So iv is only used
+// when the byte range starts in the first block of the resource.
+func (c *Client) GetDecryptedRange(ctx context.Context, url string, br playlist.XByteRange,
+	key, iv []byte, options ...Option) (data []byte, err error) {
+	if br.Length == 0 {
+		return nil, errors.New("missing the length of byte range")
+	}
+
+	start, end := aes128.BlockRange(br.Offset, br.Length)
+	options = append(slices.Clip(options), ByteRange(start, end-start))
+	err = c.Get(ctx, url, func(r *http.Response) (err error) {
+		if r.StatusCode != http.StatusPartialContent && start > 0 {
+			return fmt.Errorf("unexpected statuscode %d for the byte range", r.StatusCode)
+		}
+
+		encrypted, err := io.ReadAll(io.LimitReader(r.Body, int64(end-start)))
+		if err == nil {
+			data, err = aes128.DecryptRange(encrypted, key, iv, br.Offset, br.Length)
+		}
+		return
+	}, options...)
+	return
+}
+
 // ResolveURL tries to reslove the relative url based on baseurl
 // if uri is relative, and returns it.
 func ResolveURL(baseurl, uri string) (string, error) {
@@ -15,15 +15,20 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
 	"github.com/xgfone/go-toolkit/httpx"
 )
 
@@ -58,7 +63,7 @@ func TestDownload(t *testing.T) {
 			Body:       io.NopCloser(strings.NewReader(ranger)),
 		}, nil
 	}
-	httpx.SetClient(httpx.DoFunc(do))
+	client := NewClient(httpx.DoFunc(do))
 
 	const (
 		baseurl = "http://localhost/dir"
@@ -80,10 +85,93 @@ func TestDownload(t *testing.T) {
 	}
 
 	const expectbody = "0-99"
-	err = Get(context.Background(), url, bodydo, ByteRange(0, 100))
+	err = client.Get(context.Background(), url, bodydo, ByteRange(0, 100))
 	if err != nil {
 		t.Fatal(err)
 	} else if body != expectbody {
 		t.Errorf("expect response body '%s', but got '%s'", expectbody, body)
 	}
 }
+
+func TestClient(t *testing.T) {
+	do := func(r *http.Request) (*http.Response, error) {
+		body := strings.Join([]string{
+			r.Header.Get("User-Agent"),
+			r.Header.Get("X-Default"),
+			r.Header.Get("X-Middleware"),
+		}, ",")
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+
+	client := &Client{
+		Doer:      httpx.DoFunc(do),
+		Header:    http.Header{"X-Default": []string{"default"}},
+		UserAgent: "go-hls",
+	}
+
+	var order []string
+	client.Use(func(next Doer) Doer {
+		return httpx.DoFunc(func(r *http.Request) (*http.Response, error) {
+			order = append(order, "mw1")
+			r.Header.Set("X-Middleware", "signed")
+			return next.Do(r)
+		})
+	}, func(next Doer) Doer {
+		return httpx.DoFunc(func(r *http.Request) (*http.Response, error) {
+			order = append(order, "mw2")
+			return next.Do(r)
+		})
+	})
+
+	var body string
+	err := client.Get(context.Background(), "http://localhost", func(r *http.Response) error {
+		data, err := io.ReadAll(r.Body)
+		body = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := "go-hls,default,signed"; body != expect {
+		t.Errorf("expect response body '%s', but got '%s'", expect, body)
+	}
+	if expect := "mw1,mw2"; strings.Join(order, ",") != expect {
+		t.Errorf("expect middleware order '%s', but got '%s'", expect, strings.Join(order, ","))
+	}
+}
+
+func TestGetDecryptedRange(t *testing.T) {
+	iv := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	data := make([]byte, 188*10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	encrypted, err := aes128.Encrypt(data, testKey, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "main.ts", time.Time{}, bytes.NewReader(encrypted))
+	}))
+	defer server.Close()
+
+	for _, br := range []playlist.XByteRange{{Length: 188}, {Offset: 376, Length: 564}, {Offset: 1692, Length: 188}} {
+		decrypted, err := GetDecryptedRange(context.Background(), server.URL+"/main.ts", br, testKey, iv)
+		if err != nil {
+			t.Errorf("%+v: %v", br, err)
+		} else if !bytes.Equal(decrypted, data[br.Offset:br.Offset+br.Length]) {
+			t.Errorf("%+v: the decrypted data is not equal to the original", br)
+		}
+	}
+
+	if _, err := GetDecryptedRange(context.Background(), server.URL+"/main.ts", playlist.XByteRange{}, testKey, iv); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
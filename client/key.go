@@ -0,0 +1,316 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/cenc"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/sampleaes"
+	"github.com/xgfone/go-hls/ts"
+)
+
+// KeyFormatIdentity is the default key format, which means that
+// the key file is a single packed array of 16 octets in binary format.
+const KeyFormatIdentity = "identity"
+
+var errKeyNotFound = errors.New("key not found")
+
+// KeyProvider is used to acquire the key to decrypt the media segments.
+type KeyProvider interface {
+	GetKey(ctx context.Context, key playlist.XKey) ([]byte, error)
+}
+
+// KeyProviderFunc is a function to acquire the key.
+type KeyProviderFunc func(ctx context.Context, key playlist.XKey) ([]byte, error)
+
+// GetKey implements the interface KeyProvider.
+func (f KeyProviderFunc) GetKey(ctx context.Context, key playlist.XKey) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// StaticKeyProvider is a key provider based on the map,
+// the key of which is the URI of XKey.
+type StaticKeyProvider map[string][]byte
+
+// GetKey implements the interface KeyProvider.
+func (p StaticKeyProvider) GetKey(ctx context.Context, key playlist.XKey) ([]byte, error) {
+	if data, ok := p[key.URI]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: uri=%s", errKeyNotFound, key.URI)
+}
+
+// HTTPKeyProvider is a key provider to acquire the key by HTTP,
+// which caches the keys by the URI of XKey.
+type HTTPKeyProvider struct {
+	Client  *Client  // Default: DefaultClient
+	Options []Option // Such as the options to set the authorization.
+
+	lock  sync.RWMutex
+	cache map[string][]byte
+}
+
+// NewHTTPKeyProvider returns a new http key provider.
+func NewHTTPKeyProvider(client *Client, options ...Option) *HTTPKeyProvider {
+	return &HTTPKeyProvider{Client: client, Options: options}
+}
+
+// GetKey implements the interface KeyProvider.
+//
+// The URI of key must be an absolute url.
+func (p *HTTPKeyProvider) GetKey(ctx context.Context, key playlist.XKey) (data []byte, err error) {
+	p.lock.RLock()
+	data, ok := p.cache[key.URI]
+	p.lock.RUnlock()
+	if ok {
+		return
+	}
+
+	err = getClient(p.Client).Get(ctx, key.URI, func(r *http.Response) (err error) {
+		data, err = io.ReadAll(io.LimitReader(r.Body, 1024))
+		return
+	}, p.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get key '%s': %w", key.URI, err)
+	}
+
+	p.lock.Lock()
+	if p.cache == nil {
+		p.cache = make(map[string][]byte, 4)
+	}
+	p.cache[key.URI] = data
+	p.lock.Unlock()
+	return
+}
+
+// Purge removes the cached key by the uri. If uri is empty, remove all.
+func (p *HTTPKeyProvider) Purge(uri string) {
+	p.lock.Lock()
+	if uri == "" {
+		clear(p.cache)
+	} else {
+		delete(p.cache, uri)
+	}
+	p.lock.Unlock()
+}
+
+// SchemeKeyProvider is a key provider to dispatch the key acquisition
+// to the registered provider by the scheme of the key URI,
+// such as "skd" for FairPlay Streaming.
+type SchemeKeyProvider struct {
+	// Default is used when no provider is registered for the scheme.
+	//
+	// Default: a HTTPKeyProvider with DefaultClient
+	Default KeyProvider
+
+	providers map[string]KeyProvider
+}
+
+// NewSchemeKeyProvider returns a new scheme key provider with
+// the default provider.
+func NewSchemeKeyProvider(_default KeyProvider) *SchemeKeyProvider {
+	if _default == nil {
+		_default = new(HTTPKeyProvider)
+	}
+	return &SchemeKeyProvider{Default: _default}
+}
+
+// Register registers the key provider for the scheme,
+// which is case-insensitive, and returns itself.
+//
+// It should be called before acquiring any key.
+func (p *SchemeKeyProvider) Register(scheme string, provider KeyProvider) *SchemeKeyProvider {
+	if p.providers == nil {
+		p.providers = make(map[string]KeyProvider, 4)
+	}
+	p.providers[strings.ToLower(scheme)] = provider
+	return p
+}
+
+// GetKey implements the interface KeyProvider.
+func (p *SchemeKeyProvider) GetKey(ctx context.Context, key playlist.XKey) ([]byte, error) {
+	if u, err := url.Parse(key.URI); err == nil {
+		if provider, ok := p.providers[strings.ToLower(u.Scheme)]; ok {
+			return provider.GetKey(ctx, key)
+		}
+	}
+
+	if p.Default == nil {
+		return nil, fmt.Errorf("%w: uri=%s", errKeyNotFound, key.URI)
+	}
+	return p.Default.GetKey(ctx, key)
+}
+
+// SelectKey selects the key of the media segment to decrypt it,
+// which prefers the key with the "identity" key format.
+//
+// Return false if the media segment is not encrypted.
+func SelectKey(seg playlist.MediaSegment) (key playlist.XKey, ok bool) {
+	for _, k := range seg.Keys {
+		if k.Method == "" || k.Method == playlist.XKeyMethodNone {
+			continue
+		}
+
+		if k.Format == "" || k.Format == KeyFormatIdentity {
+			return k, true
+		} else if !ok {
+			key, ok = k, true
+		}
+	}
+	return
+}
+
+// DecryptSegment acquires the key of the media segment from provider
+// and decrypts the data of the media segment with it.
+//
+// For SAMPLE-AES, the media segment must be the MPEG-2 transport stream.
+// For the fMP4 media segment, use DecryptFMP4Segment instead.
+//
+// If baseurl is not empty, the relative key URI is resolved based on it.
+// If the media segment is not encrypted, return data directly.
+// If it has no key with the "identity" key format, return an error.
+func DecryptSegment(ctx context.Context, provider KeyProvider, baseurl string,
+	seg playlist.MediaSegment, data []byte) ([]byte, error) {
+	return decryptSegment(ctx, provider, baseurl, seg, nil, data)
+}
+
+// DecryptFMP4Segment is the same as DecryptSegment, but also supports
+// the fMP4 media segment encrypted by the common encryption with
+// METHOD=SAMPLE-AES ("cbcs") or METHOD=SAMPLE-AES-CTR ("cenc"),
+// which requires the init section referred by EXT-X-MAP to get
+// the protection information of the tracks.
+//
+// The decrypted media segment keeps the boxes, and the init section
+// can be made clear by cenc.Init.ClearInit.
+func DecryptFMP4Segment(ctx context.Context, provider KeyProvider, baseurl string,
+	seg playlist.MediaSegment, init, data []byte) ([]byte, error) {
+	return decryptSegment(ctx, provider, baseurl, seg, init, data)
+}
+
+func decryptSegment(ctx context.Context, provider KeyProvider, baseurl string,
+	seg playlist.MediaSegment, init, data []byte) ([]byte, error) {
+	seg, keydata, err := acquireKey(ctx, provider, baseurl, seg)
+	if err != nil || keydata == nil {
+		return data, err
+	}
+
+	switch method := seg.Keys[0].Method; method {
+	case playlist.XKeyMethodAES128:
+		return seg.AES128Decrypt(data, keydata, true)
+
+	case playlist.XKeyMethodSampleAES, playlist.XKeyMethodSampleAESCTR:
+		if method == playlist.XKeyMethodSampleAES && len(data) > 0 && data[0] == ts.SyncByte {
+			iv, err := seg.IV()
+			if err != nil {
+				return nil, err
+			}
+			return sampleaes.DecryptTS(data, keydata, iv)
+		}
+
+		if init == nil {
+			return nil, fmt.Errorf("missing the init section to decrypt the fMP4 segment by %s", method)
+		}
+		return cenc.DecryptSegment(init, data, keydata)
+
+	default:
+		return nil, fmt.Errorf("unsupported key method '%s'", method)
+	}
+}
+
+// DecryptSegmentReader is the same as DecryptSegment, but returns a reader
+// to decrypt the data read from r in stream, such as the response body.
+func DecryptSegmentReader(ctx context.Context, provider KeyProvider, baseurl string,
+	seg playlist.MediaSegment, r io.Reader) (io.Reader, error) {
+	seg, keydata, err := acquireKey(ctx, provider, baseurl, seg)
+	if err != nil || keydata == nil {
+		return r, err
+	}
+
+	switch method := seg.Keys[0].Method; method {
+	case playlist.XKeyMethodAES128:
+		iv, err := seg.IV()
+		if err != nil {
+			return nil, err
+		}
+		return aes128.NewDecryptReader(r, keydata, iv)
+
+	case playlist.XKeyMethodSampleAES:
+		// SAMPLE-AES requires the whole PES packets to decrypt.
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		iv, err := seg.IV()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = sampleaes.DecryptTS(data, keydata, iv)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key method '%s'", method)
+	}
+}
+
+// acquireKey acquires the key of the media segment, and returns the media
+// segment that only contains the selected key.
+//
+// Return a nil key if the media segment is not encrypted.
+func acquireKey(ctx context.Context, provider KeyProvider, baseurl string,
+	seg playlist.MediaSegment) (playlist.MediaSegment, []byte, error) {
+	key, ok := SelectKey(seg)
+	if !ok {
+		return seg, nil, nil
+	} else if key.Format != "" && key.Format != KeyFormatIdentity {
+		// Only the key file of the "identity" key format contains the key,
+		// and the others, such as FairPlay Streaming, require the DRM system.
+		return seg, nil, fmt.Errorf("unsupported key format '%s'", key.Format)
+	}
+
+	if baseurl != "" {
+		uri, err := ResolveURL(baseurl, key.URI)
+		if err != nil {
+			return seg, nil, err
+		}
+		key.URI = uri
+	}
+
+	keydata, err := provider.GetKey(ctx, key)
+	if err != nil {
+		return seg, nil, err
+	} else if keydata == nil {
+		keydata = []byte{}
+	}
+
+	// Use the IV of the selected key.
+	seg.Keys = []playlist.XKey{key}
+	return seg, keydata, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+var testKey = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func TestDecryptSegment(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(401)
+			return
+		}
+		_, _ = w.Write(testKey)
+	}))
+	defer server.Close()
+
+	seg := playlist.MediaSegment{
+		URI:           "0.ts",
+		Duration:      10,
+		MediaSequence: 123,
+		Keys: []playlist.XKey{
+			{Method: playlist.XKeyMethodSampleAES, URI: "skd://key", Format: "com.apple.streamingkeydelivery"},
+			{Method: playlist.XKeyMethodAES128, URI: "key.bin"},
+		},
+	}
+
+	iv, _ := seg.IV()
+	data := []byte("0123456789")
+	encrypted, err := aes128.Encrypt(data, testKey, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := func(r *http.Request) *http.Request {
+		r.Header.Set("Authorization", "Bearer token")
+		return r
+	}
+
+	var skd atomic.Int64
+	provider := NewSchemeKeyProvider(NewHTTPKeyProvider(nil, auth)).
+		Register("skd", KeyProviderFunc(func(context.Context, playlist.XKey) ([]byte, error) {
+			skd.Add(1)
+			return nil, nil
+		}))
+
+	for range 2 {
+		decrypted, err := DecryptSegment(context.Background(), provider, server.URL+"/live/index.m3u8", seg, encrypted)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(decrypted, data) {
+			t.Errorf("expect '%s', but got '%s'", data, decrypted)
+		}
+	}
+
+	r, err := DecryptSegmentReader(context.Background(), provider, server.URL+"/live/index.m3u8", seg, bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatal(err)
+	} else if decrypted, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(decrypted, data) {
+		t.Errorf("expect '%s', but got '%s'", data, decrypted)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expect %d key requests, but got %d", 1, n)
+	}
+	if n := skd.Load(); n != 0 {
+		t.Errorf("expect %d skd key requests, but got %d", 0, n)
+	}
+
+	// Only the key of the other key format is left.
+	seg.Keys = seg.Keys[:1]
+	if _, err := DecryptSegment(context.Background(), provider, "", seg, encrypted); err == nil ||
+		!strings.Contains(err.Error(), "unsupported key format") {
+		t.Errorf("expect the unsupported key format error, but got '%v'", err)
+	}
+	if _, err := DecryptSegmentReader(context.Background(), provider, "", seg, bytes.NewReader(encrypted)); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+	if n := skd.Load(); n != 0 {
+		t.Errorf("expect %d skd key requests, but got %d", 0, n)
+	}
+
+	static := StaticKeyProvider{"skd://key": testKey}
+	if _, err := static.GetKey(context.Background(), playlist.XKey{URI: "skd://key"}); err != nil {
+		t.Error(err)
+	}
+	if _, err := static.GetKey(context.Background(), playlist.XKey{URI: "skd://missing"}); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
+
+func TestDecryptSegmentSampleAES(t *testing.T) {
+	var buf bytes.Buffer
+	w := ts.NewWriter(&buf)
+	_ = w.WritePAT(ts.PAT{Programs: []ts.Program{{Number: 1, PID: 0x1000}}})
+	_ = w.WritePMT(0x1000, ts.PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: []ts.Stream{
+		{Type: ts.StreamTypeH264SampleAES, PID: 0x100},
+	}})
+
+	// The short NAL unit is not encrypted.
+	nal := []byte{0, 0, 0, 1, 0x65, 1, 2, 3, 4, 5, 6, 7, 8}
+	_ = w.WritePES(0x100, ts.AppendPES(nil, 0xE0, 0, -1, nal), 0, true)
+
+	seg := playlist.MediaSegment{
+		URI:  "0.ts",
+		Keys: []playlist.XKey{{Method: playlist.XKeyMethodSampleAES, URI: "key.bin"}},
+	}
+	provider := StaticKeyProvider{"key.bin": testKey}
+
+	data, err := DecryptSegment(context.Background(), provider, "", seg, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets, err := ts.Packets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section, err := ts.Section(packets[1].Payload())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pmt, err := ts.ParsePMT(section); err != nil {
+		t.Fatal(err)
+	} else if s, _ := pmt.Stream(0x100); s.Type != ts.StreamTypeH264 {
+		t.Errorf("expect stream type 0x%02x, but got 0x%02x", ts.StreamTypeH264, s.Type)
+	}
+
+	if payload := packets[2].Payload(); !bytes.HasSuffix(payload, nal) {
+		t.Errorf("unexpected PES payload: %x", payload)
+	}
+}
+
+func TestDecryptFMP4SegmentWithoutInit(t *testing.T) {
+	seg := playlist.MediaSegment{
+		URI:  "0.m4s",
+		Keys: []playlist.XKey{{Method: playlist.XKeyMethodSampleAESCTR, URI: "key.bin"}},
+	}
+	provider := StaticKeyProvider{"key.bin": testKey}
+
+	if _, err := DecryptSegment(context.Background(), provider, "", seg, []byte("data")); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+
+	if _, err := DecryptFMP4Segment(context.Background(), provider, "", seg, []byte{}, []byte{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
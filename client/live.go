@@ -0,0 +1,340 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Define the errors returned by LivePoller.
+var (
+	ErrLiveReset   = errors.New("live media playlist is reset")
+	ErrLiveStalled = errors.New("live media playlist is stalled")
+)
+
+// LivePoller is used to poll a live media playlist by the reload rules
+// and emit the newly added media segments.
+//
+// See [[RFC 8216, 6.3.4]].
+//
+// [RFC 8216, 6.3.4]: https://datatracker.ietf.org/doc/html/rfc8216#section-6.3.4
+type LivePoller struct {
+	// Required
+	URL string
+
+	// Optional
+	Client  *Client // Default: DefaultClient
+	Options []Option
+
+	// Backups is the urls of the redundant streams of URL, such as
+	// playlist.Variant.URIs()[1:], which are switched to in order
+	// when failing to load the current one or it is stalled.
+	//
+	// The media segments are continued to be emitted by the media
+	// sequence number after switching, so the redundant streams
+	// must have the same media sequence numbers.
+	Backups []string
+
+	// MaxRetries is the maximum number of the continuous failures to load
+	// the media playlist, such as the 5xx response or the timeout, which
+	// are retried with the exponential backoff after failing over all
+	// the backups. If exceeded, Run returns the error.
+	//
+	// Default: 3. Negative means not to retry.
+	MaxRetries int
+
+	// RetryInterval is the initial interval to retry to load the media
+	// playlist, which is doubled for each retry up to the target duration.
+	//
+	// Default: 500ms
+	RetryInterval time.Duration
+
+	// StallTimeout is the maximum duration that the media playlist
+	// is allowed to be unchanged.
+	//
+	// Default: 3 * TargetDuration
+	StallTimeout time.Duration
+
+	// OnStall is called when the media playlist has not changed
+	// for StallTimeout. If it returns nil, continue to poll.
+	//
+	// Default: return ErrLiveStalled
+	OnStall func(last playlist.MediaPlayList) error
+
+	// OnReset is called when the media sequence of the media playlist
+	// goes back, for example, the encoder is restarted. If it returns nil,
+	// continue to poll by starting from the new media playlist.
+	//
+	// Default: return ErrLiveReset
+	OnReset func(last, current playlist.MediaPlayList) error
+
+	last      playlist.MediaPlayList
+	lastseq   uint64 // The media sequence of the last emitted media segment.
+	dseq      uint64 // The discontinuity sequence of the last emitted media segment.
+	emitted   bool
+	changedAt time.Time
+
+	index    int  // The index of the current url in URL and Backups.
+	failures int  // The number of the continuous failovers.
+	retries  int  // The number of the continuous retries.
+	switched bool // Whether the url is switched to a backup.
+}
+
+// CurrentURL returns the url of the current loaded media playlist,
+// which is used to resolve the relative uris of the emitted media segments.
+func (p *LivePoller) CurrentURL() string {
+	if p.index == 0 {
+		return p.URL
+	}
+	return p.Backups[p.index-1]
+}
+
+// Run starts to poll the live media playlist, and calls handle
+// with each newly added media segment in order of the media sequence.
+//
+// The DiscontinuitySequence of the emitted media segment is tracked
+// by the poller across the reloads. If some media segments have slid out
+// of the window before being emitted, the next emitted media segment
+// is marked as the discontinuity.
+//
+// It returns nil when the media playlist contains #EXT-X-ENDLIST,
+// or the context error when ctx is done.
+func (p *LivePoller) Run(ctx context.Context, handle func(playlist.MediaSegment) error) error {
+	if p.URL == "" {
+		return errors.New("LivePoller: missing url")
+	}
+
+	for {
+		start := time.Now()
+		pl, err := p.load(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			} else if p.failover() {
+				continue
+			} else if err = p.retry(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		p.retries = 0
+
+		changed, err := p.update(pl, handle)
+		if err != nil {
+			return err
+		} else if pl.EndList {
+			return nil
+		} else if changed {
+			p.failures = 0
+		}
+
+		// RFC 8216, 6.3.4:
+		// If changed, wait for at least the target duration before reloading,
+		// measured from the last time the client began loading the playlist.
+		// If not, wait for one-half the target duration before retrying.
+		interval := targetDuration(pl)
+		if !changed {
+			interval /= 2
+		}
+
+		if err = sleep(ctx, interval-time.Since(start)); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *LivePoller) load(ctx context.Context) (pl playlist.MediaPlayList, err error) {
+	err = getClient(p.Client).Get(ctx, p.CurrentURL(), func(r *http.Response) error {
+		return pl.Parse(r.Body)
+	}, p.Options...)
+	return
+}
+
+func (p *LivePoller) update(pl playlist.MediaPlayList, handle func(playlist.MediaSegment) error) (changed bool, err error) {
+	now := time.Now()
+	if !p.emitted {
+		return true, p.emit(pl, pl.Segments, now, handle)
+	}
+
+	if len(pl.Segments) == 0 {
+		return false, p.checkStall(now, pl)
+	}
+
+	switch first, last := pl.Segments[0], pl.Segments[len(pl.Segments)-1]; {
+	case p.switched:
+		// The redundant stream may lag behind or be ahead of the last one,
+		// so continue to emit the media segments after the last emitted one.
+		if last.MediaSequence <= p.lastseq {
+			p.last = pl
+			return false, p.checkStall(now, pl)
+		}
+		p.switched = false
+
+	case last.MediaSequence < p.lastseq, first.MediaSequence < p.last.MediaSequence:
+		if err = p.reset(pl); err != nil {
+			return
+		}
+		return true, p.emit(pl, pl.Segments, now, handle)
+
+	case last.MediaSequence == p.lastseq:
+		if pl.EndList {
+			p.last = pl
+			return true, nil
+		}
+		return false, p.checkStall(now, pl)
+	}
+
+	index := pl.GetSegmentIndexByMediaSequence(p.lastseq + 1)
+	if index < 0 {
+		// The segments after the last emitted one have slid out of the window,
+		// so mark the gap as the discontinuity.
+		index = 0
+		pl.Segments = slices.Clone(pl.Segments)
+		pl.Segments[0].Discontinuity = true
+	}
+
+	return true, p.emit(pl, pl.Segments[index:], now, handle)
+}
+
+func (p *LivePoller) emit(pl playlist.MediaPlayList, segs []playlist.MediaSegment,
+	now time.Time, handle func(playlist.MediaSegment) error) (err error) {
+	p.last = pl
+	p.changedAt = now
+	for _, seg := range segs {
+		switch {
+		case !p.emitted:
+			p.dseq = seg.DiscontinuitySequence
+		case seg.Discontinuity:
+			p.dseq++
+		}
+
+		seg.DiscontinuitySequence = p.dseq
+		if err = handle(seg); err != nil {
+			return
+		}
+
+		p.lastseq = seg.MediaSequence
+		p.emitted = true
+	}
+	return
+}
+
+func (p *LivePoller) reset(pl playlist.MediaPlayList) (err error) {
+	if p.OnReset == nil {
+		return ErrLiveReset
+	}
+
+	if err = p.OnReset(p.last, pl); err == nil {
+		p.emitted = false
+	}
+	return
+}
+
+func (p *LivePoller) checkStall(now time.Time, pl playlist.MediaPlayList) (err error) {
+	timeout := p.StallTimeout
+	if timeout <= 0 {
+		timeout = targetDuration(pl) * 3
+	}
+
+	if now.Sub(p.changedAt) < timeout {
+		return
+	}
+
+	if p.failover() {
+		p.changedAt = now
+		return
+	}
+
+	if p.OnStall == nil {
+		return ErrLiveStalled
+	}
+
+	if err = p.OnStall(p.last); err == nil {
+		p.changedAt = now
+	}
+	return
+}
+
+// retry waits for the backoff interval to reload the media playlist,
+// and returns err if the retries are exhausted.
+func (p *LivePoller) retry(ctx context.Context, err error) error {
+	return retry(ctx, err, &p.retries, p.MaxRetries, p.RetryInterval, targetDuration(p.last))
+}
+
+// retry sleeps for the exponential backoff of the continuous retries,
+// which is doubled from interval up to limit, or returns err
+// if the retries have exceeded maxRetries.
+func retry(ctx context.Context, err error, retries *int, maxRetries int, interval, limit time.Duration) error {
+	switch {
+	case maxRetries == 0:
+		maxRetries = 3
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+
+	if *retries >= maxRetries {
+		return err
+	}
+
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	interval = min(interval<<*retries, max(interval, limit))
+	*retries++
+	return sleep(ctx, interval)
+}
+
+// failover switches to the next url of the redundant streams,
+// and returns false if all of them have been tried continuously.
+func (p *LivePoller) failover() bool {
+	if p.failures >= len(p.Backups) {
+		return false
+	}
+
+	p.failures++
+	p.switched = p.emitted
+	p.index = (p.index + 1) % (len(p.Backups) + 1)
+	return true
+}
+
+func targetDuration(pl playlist.MediaPlayList) time.Duration {
+	if pl.TargetDuration == 0 {
+		return time.Second
+	}
+	return time.Duration(pl.TargetDuration) * time.Second
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
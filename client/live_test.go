@@ -0,0 +1,238 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+func newEvolvingServer(playlists ...string) *httptest.Server {
+	var index atomic.Int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(int(index.Add(1))-1, len(playlists)-1)
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		_, _ = w.Write([]byte(playlists[i]))
+	}))
+}
+
+func TestLivePoller(t *testing.T) {
+	server := newEvolvingServer(
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXTINF:1,
+0.ts
+#EXTINF:1,
+1.ts
+#EXTINF:1,
+2.ts
+`,
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXT-X-MEDIA-SEQUENCE:1
+#EXTINF:1,
+1.ts
+#EXTINF:1,
+2.ts
+#EXTINF:1,
+3.ts
+`,
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXT-X-MEDIA-SEQUENCE:2
+#EXTINF:1,
+2.ts
+#EXTINF:1,
+3.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:1,
+4.ts
+`,
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXT-X-MEDIA-SEQUENCE:3
+#EXTINF:1,
+3.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:1,
+4.ts
+#EXTINF:1,
+5.ts
+#EXT-X-ENDLIST
+`,
+	)
+	defer server.Close()
+
+	var segs []playlist.MediaSegment
+	poller := &LivePoller{URL: server.URL + "/live.m3u8"}
+	err := poller.Run(context.Background(), func(seg playlist.MediaSegment) error {
+		segs = append(segs, seg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(segs) != 6 {
+		t.Fatalf("expect %d media segments, but got %d", 6, len(segs))
+	}
+
+	for i, seg := range segs {
+		var dseq uint64
+		if i >= 4 {
+			dseq = 1
+		}
+
+		if seg.MediaSequence != uint64(i) {
+			t.Errorf("%d: expect media sequence %d, but got %d", i, i, seg.MediaSequence)
+		}
+		if seg.DiscontinuitySequence != dseq {
+			t.Errorf("%d: expect discontinuity sequence %d, but got %d", i, dseq, seg.DiscontinuitySequence)
+		}
+	}
+}
+
+func TestLivePollerReset(t *testing.T) {
+	server := newEvolvingServer(
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXT-X-MEDIA-SEQUENCE:10
+#EXTINF:1,
+10.ts
+`,
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXTINF:1,
+0.ts
+`,
+	)
+	defer server.Close()
+
+	poller := &LivePoller{URL: server.URL + "/live.m3u8"}
+	err := poller.Run(context.Background(), func(playlist.MediaSegment) error { return nil })
+	if !errors.Is(err, ErrLiveReset) {
+		t.Errorf("expect error '%v', but got '%v'", ErrLiveReset, err)
+	}
+}
+
+func TestLivePollerFailover(t *testing.T) {
+	var requests atomic.Int64
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\n0.ts\n#EXTINF:1,\n1.ts\n#EXTINF:1,\n2.ts\n"))
+	}))
+	defer primary.Close()
+
+	// The backup stream lags behind the primary at first.
+	backup := newEvolvingServer(
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXT-X-MEDIA-SEQUENCE:1
+#EXTINF:1,
+1.ts
+#EXTINF:1,
+2.ts
+`,
+		`#EXTM3U
+#EXT-X-TARGETDURATION:1
+#EXT-X-MEDIA-SEQUENCE:1
+#EXTINF:1,
+1.ts
+#EXTINF:1,
+2.ts
+#EXTINF:1,
+3.ts
+#EXTINF:1,
+4.ts
+#EXT-X-ENDLIST
+`,
+	)
+	defer backup.Close()
+
+	var seqs []uint64
+	poller := &LivePoller{URL: primary.URL + "/live.m3u8", Backups: []string{backup.URL + "/live.m3u8"}}
+	err := poller.Run(context.Background(), func(seg playlist.MediaSegment) error {
+		seqs = append(seqs, seg.MediaSequence)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(seqs, []uint64{0, 1, 2, 3, 4}) {
+		t.Errorf("unexpected media sequences %v", seqs)
+	}
+	if url := poller.CurrentURL(); url != backup.URL+"/live.m3u8" {
+		t.Errorf("expect current url '%s', but got '%s'", backup.URL+"/live.m3u8", url)
+	}
+
+	// All the redundant streams fail.
+	poller = &LivePoller{URL: primary.URL + "/live.m3u8", Backups: []string{primary.URL + "/backup.m3u8"}, MaxRetries: -1}
+	if err := poller.Run(context.Background(), func(playlist.MediaSegment) error { return nil }); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
+
+func TestLivePollerRetry(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			_, _ = w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\n0.ts\n#EXTINF:1,\n1.ts\n"))
+		case 2, 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			// The media segments 2 and 3 have slid out of the window.
+			_, _ = w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:4\n#EXTINF:1,\n4.ts\n#EXTINF:1,\n5.ts\n#EXT-X-ENDLIST\n"))
+		}
+	}))
+	defer server.Close()
+
+	var segs []playlist.MediaSegment
+	poller := &LivePoller{URL: server.URL + "/live.m3u8", RetryInterval: 10 * time.Millisecond}
+	err := poller.Run(context.Background(), func(seg playlist.MediaSegment) error {
+		segs = append(segs, seg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(segs) != 4 {
+		t.Fatalf("expect %d media segments, but got %d", 4, len(segs))
+	} else if seg := segs[2]; seg.MediaSequence != 4 || !seg.Discontinuity || seg.DiscontinuitySequence != 1 {
+		t.Errorf("expect the gap is marked as the discontinuity, but got %+v", seg)
+	} else if seg := segs[3]; seg.Discontinuity || seg.DiscontinuitySequence != 1 {
+		t.Errorf("unexpected media segment after the gap: %+v", seg)
+	}
+
+	// Exceed the maximum retries.
+	requests.Store(1)
+	poller = &LivePoller{URL: server.URL + "/live.m3u8", MaxRetries: 1, RetryInterval: 10 * time.Millisecond}
+	if err := poller.Run(context.Background(), func(playlist.MediaSegment) error { return nil }); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
@@ -0,0 +1,472 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// LowLatencyPart represents a partial segment emitted by LowLatencyPoller.
+type LowLatencyPart struct {
+	playlist.XPart
+
+	URL           string // The absolute url of the partial segment.
+	MediaSequence uint64 // The media sequence of the parent media segment.
+	Index         int64  // The index of the partial segment in the parent media segment.
+
+	Data []byte
+}
+
+// LowLatencyPoller is used to poll a Low-Latency HLS media playlist
+// by the blocking playlist reload, and emit the newly added partial segments.
+//
+// See [[RFC 8216bis, 6.2.5]].
+//
+// [RFC 8216bis, 6.2.5]: https://datatracker.ietf.org/doc/html/draft-pantos-hls-rfc8216bis#section-6.2.5
+type LowLatencyPoller struct {
+	// Required
+	URL string
+
+	// Optional
+	Client  *Client // Default: DefaultClient
+	Options []Option
+
+	// RequestTimeout is the timeout of the blocking playlist reload.
+	//
+	// Default: 3 * TargetDuration
+	RequestTimeout time.Duration
+
+	// If true, open the requests of the partial segments early
+	// by following #EXT-X-PRELOAD-HINT.
+	Preload bool
+
+	// If true, do not request the delta update of the media playlist.
+	DisableDeltaUpdate bool
+
+	// MaxRetries is the maximum number of the continuous failures to load
+	// the media playlist, such as the 5xx response or the timeout of
+	// the blocking playlist reload, which are retried with the exponential
+	// backoff. If exceeded, Run returns the error.
+	//
+	// Default: 3. Negative means not to retry.
+	MaxRetries int
+
+	// RetryInterval is the initial interval to retry to load the media
+	// playlist, which is doubled for each retry up to the target duration.
+	//
+	// Default: 500ms
+	RetryInterval time.Duration
+
+	lock     sync.Mutex
+	switchto string
+
+	url       string
+	last      playlist.MediaPlayList
+	lastpos   _PartPos
+	emitted   bool
+	retries   int // The number of the continuous retries.
+	prefetchs map[_PrefetchKey]*_Prefetch
+}
+
+type _PartPos struct {
+	msn  uint64
+	part int64
+}
+
+func (p _PartPos) after(o _PartPos) bool {
+	return p.msn > o.msn || (p.msn == o.msn && p.part > o.part)
+}
+
+type _PrefetchKey struct {
+	url    string
+	offset uint64
+}
+
+type _Prefetch struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	data   []byte
+	err    error
+}
+
+// Switch switches to the rendition identified by uri, which is relative
+// to the current media playlist, such as the URI of #EXT-X-RENDITION-REPORT.
+//
+// The next blocking playlist reload will be issued to the new rendition
+// by the rendition report without an extra reload.
+func (p *LowLatencyPoller) Switch(uri string) {
+	p.lock.Lock()
+	p.switchto = uri
+	p.lock.Unlock()
+}
+
+func (p *LowLatencyPoller) takeSwitch() (uri string) {
+	p.lock.Lock()
+	uri, p.switchto = p.switchto, ""
+	p.lock.Unlock()
+	return
+}
+
+// Run starts to poll the media playlist, and calls handle with each newly
+// added partial segment, the data of which has been downloaded.
+//
+// It starts with the partial segment that is at least PART-HOLD-BACK
+// from the end of the media playlist.
+//
+// It returns nil when the media playlist contains #EXT-X-ENDLIST,
+// or the context error when ctx is done.
+func (p *LowLatencyPoller) Run(ctx context.Context, handle func(LowLatencyPart) error) error {
+	if p.URL == "" {
+		return errors.New("LowLatencyPoller: missing url")
+	}
+
+	defer p.cancelPrefetchs(nil)
+
+	p.url = p.URL
+	var options []Option
+	for {
+		start := time.Now()
+		pl, err := p.load(ctx, options)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			} else if err = p.retry(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		p.retries = 0
+
+		changed, err := p.emit(ctx, pl, handle)
+		if err != nil {
+			return err
+		} else if pl.EndList {
+			return nil
+		}
+
+		if p.Preload {
+			p.preload(ctx, pl)
+		}
+
+		if uri := p.takeSwitch(); uri != "" {
+			if options, err = p.switchRendition(pl, uri); err != nil {
+				return err
+			}
+			continue
+		}
+
+		interval := targetDuration(pl)
+		if pl.PartInf.PartTarget > 0 {
+			interval = time.Duration(pl.PartInf.PartTarget * float64(time.Second))
+		}
+
+		if pl.ServerControl.CanBlockReload {
+			// The server holds the blocking playlist reload until the next
+			// partial segment is available, but keep a floor of one-half
+			// the part target in case that it responds immediately.
+			options = p.blockingOptions(pl, p.nextPos(pl))
+			interval /= 2
+		} else {
+			options = p.deltaOptions(pl, nil)
+			if !changed {
+				interval /= 2
+			}
+		}
+
+		if err = sleep(ctx, interval-time.Since(start)); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *LowLatencyPoller) retry(ctx context.Context, err error) error {
+	return retry(ctx, err, &p.retries, p.MaxRetries, p.RetryInterval, targetDuration(p.last))
+}
+
+func (p *LowLatencyPoller) load(ctx context.Context, options []Option) (pl playlist.MediaPlayList, err error) {
+	if p.emitted {
+		timeout := p.RequestTimeout
+		if timeout <= 0 {
+			timeout = targetDuration(p.last) * 3
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	parse := func(r *http.Response) error { return pl.Parse(r.Body) }
+	err = getClient(p.Client).Get(ctx, p.url, parse, append(slices.Clip(p.Options), options...)...)
+	if err != nil || pl.Skip.SkippedSegments == 0 {
+		return
+	}
+
+	if delta, _err := mergeDeltaUpdate(p.last, pl); _err == nil {
+		return delta, nil
+	}
+
+	// Fall back to reload the full media playlist.
+	pl = playlist.MediaPlayList{}
+	err = getClient(p.Client).Get(ctx, p.url, parse, p.Options...)
+	return
+}
+
+func (p *LowLatencyPoller) blockingOptions(pl playlist.MediaPlayList, next _PartPos) []Option {
+	return p.deltaOptions(pl, []Option{BlockingReload(next.msn, next.part)})
+}
+
+func (p *LowLatencyPoller) deltaOptions(pl playlist.MediaPlayList, options []Option) []Option {
+	if !p.DisableDeltaUpdate && pl.ServerControl.CanSkipUntil > 0 {
+		options = append(options, DeltaUpdate(pl.ServerControl.CanSkipDateRanges))
+	}
+	return options
+}
+
+func (p *LowLatencyPoller) switchRendition(pl playlist.MediaPlayList, uri string) (options []Option, err error) {
+	url, err := ResolveURL(p.url, uri)
+	if err != nil {
+		return
+	}
+
+	var next _PartPos
+	var found bool
+	for _, report := range pl.RenditionReports {
+		if _url, _ := ResolveURL(p.url, report.URI); _url == url {
+			next, found = _PartPos{msn: report.LastMSN, part: report.LastPart + 1}, true
+			if !report.HasLastPart {
+				next = _PartPos{msn: report.LastMSN + 1, part: -1}
+			}
+			break
+		}
+	}
+
+	// The delta update is not applied to another rendition.
+	p.url, p.last = url, playlist.MediaPlayList{TargetDuration: pl.TargetDuration}
+	p.cancelPrefetchs(nil)
+
+	if found && pl.ServerControl.CanBlockReload {
+		options = []Option{BlockingReload(next.msn, next.part)}
+	}
+	return
+}
+
+func (p *LowLatencyPoller) nextPos(pl playlist.MediaPlayList) _PartPos {
+	if len(pl.Segments) == 0 {
+		return _PartPos{msn: pl.MediaSequence, part: 0}
+	}
+
+	msn := pl.Segments[len(pl.Segments)-1].MediaSequence + 1
+	return _PartPos{msn: msn, part: int64(len(pl.Parts))}
+}
+
+func (p *LowLatencyPoller) emit(ctx context.Context, pl playlist.MediaPlayList,
+	handle func(LowLatencyPart) error) (changed bool, err error) {
+	p.last = pl
+
+	parts := collectParts(pl)
+	if len(parts) == 0 {
+		return
+	}
+
+	start := 0
+	if !p.emitted {
+		start = holdBackIndex(pl, parts)
+	}
+
+	for _, part := range parts[start:] {
+		pos := _PartPos{msn: part.MediaSequence, part: part.Index}
+		if p.emitted && !pos.after(p.lastpos) {
+			continue
+		}
+
+		if part.URL, err = ResolveURL(p.url, part.URI); err != nil {
+			return
+		}
+
+		if part.Data, err = p.fetch(ctx, part); err != nil {
+			return
+		}
+
+		if err = handle(part); err != nil {
+			return
+		}
+
+		p.lastpos = pos
+		p.emitted = true
+		changed = true
+	}
+
+	return
+}
+
+func (p *LowLatencyPoller) fetch(ctx context.Context, part LowLatencyPart) (data []byte, err error) {
+	key := _PrefetchKey{url: part.URL, offset: part.ByteRange.Offset}
+	if prefetch, ok := p.prefetchs[key]; ok {
+		delete(p.prefetchs, key)
+		select {
+		case <-ctx.Done():
+			prefetch.cancel()
+			return nil, ctx.Err()
+
+		case <-prefetch.done:
+			prefetch.cancel()
+			if prefetch.err == nil {
+				data = prefetch.data
+				if length := part.ByteRange.Length; length > 0 && uint64(len(data)) > length {
+					data = data[:length]
+				}
+				return
+			}
+		}
+	}
+
+	err = getClient(p.Client).Get(ctx, part.URL, func(r *http.Response) (err error) {
+		data, err = io.ReadAll(r.Body)
+		return
+	}, append(slices.Clip(p.Options), ByteRange(part.ByteRange.Offset, part.ByteRange.Length))...)
+	if err != nil {
+		err = fmt.Errorf("fail to download the part '%s': %w", part.URL, err)
+	}
+	return
+}
+
+func (p *LowLatencyPoller) preload(ctx context.Context, pl playlist.MediaPlayList) {
+	keys := make(map[_PrefetchKey]struct{}, len(pl.PreloadHints))
+	for _, hint := range pl.PreloadHints {
+		if hint.Type != playlist.XPreloadHintTypePart {
+			continue
+		}
+
+		url, err := ResolveURL(p.url, hint.URI)
+		if err != nil {
+			continue
+		}
+
+		key := _PrefetchKey{url: url, offset: hint.ByteRangeStart}
+		if keys[key] = struct{}{}; p.prefetchs[key] != nil {
+			continue
+		}
+
+		if p.prefetchs == nil {
+			p.prefetchs = make(map[_PrefetchKey]*_Prefetch, 2)
+		}
+
+		pctx, cancel := context.WithCancel(ctx)
+		prefetch := &_Prefetch{cancel: cancel, done: make(chan struct{})}
+		p.prefetchs[key] = prefetch
+
+		options := append(slices.Clip(p.Options), ByteRange(hint.ByteRangeStart, hint.ByteRangeLength))
+		go func() {
+			defer close(prefetch.done)
+			prefetch.err = getClient(p.Client).Get(pctx, url, func(r *http.Response) (err error) {
+				prefetch.data, err = io.ReadAll(r.Body)
+				return
+			}, options...)
+		}()
+	}
+
+	p.cancelPrefetchs(keys)
+}
+
+// cancelPrefetchs cancels the prefetches which are not in keys.
+func (p *LowLatencyPoller) cancelPrefetchs(keys map[_PrefetchKey]struct{}) {
+	for key, prefetch := range p.prefetchs {
+		if _, ok := keys[key]; !ok {
+			prefetch.cancel()
+			delete(p.prefetchs, key)
+		}
+	}
+}
+
+func collectParts(pl playlist.MediaPlayList) (parts []LowLatencyPart) {
+	var lasturi string
+	var lastend uint64
+	add := func(msn uint64, _parts []playlist.XPart) {
+		for i, part := range _parts {
+			// The partial segment continues the previous one in the same resource.
+			if part.ByteRange.Length > 0 && part.ByteRange.Offset == 0 && part.URI == lasturi {
+				part.ByteRange.Offset = lastend
+			}
+			lasturi, lastend = part.URI, part.ByteRange.Offset+part.ByteRange.Length
+
+			parts = append(parts, LowLatencyPart{XPart: part, MediaSequence: msn, Index: int64(i)})
+		}
+	}
+
+	for _, seg := range pl.Segments {
+		add(seg.MediaSequence, seg.Parts)
+	}
+
+	if len(pl.Parts) > 0 {
+		var msn uint64
+		if len(pl.Segments) > 0 {
+			msn = pl.Segments[len(pl.Segments)-1].MediaSequence + 1
+		}
+		add(msn, pl.Parts)
+	}
+
+	return
+}
+
+// holdBackIndex returns the index of the partial segment to start,
+// which is at least PART-HOLD-BACK from the end of the media playlist.
+func holdBackIndex(pl playlist.MediaPlayList, parts []LowLatencyPart) int {
+	holdback := pl.ServerControl.PartHoldBack
+	if holdback <= 0 {
+		holdback = pl.PartInf.PartTarget * 3
+	}
+
+	index := len(parts) - 1
+	for total := parts[index].Duration; index > 0 && total < holdback; {
+		index--
+		total += parts[index].Duration
+	}
+
+	// Prefer to start with an independent partial segment.
+	for i := index; i >= 0; i-- {
+		if parts[i].Independent {
+			return i
+		}
+	}
+
+	return index
+}
+
+// mergeDeltaUpdate replaces the skipped media segments in the delta update
+// with those in the last media playlist.
+func mergeDeltaUpdate(last, delta playlist.MediaPlayList) (playlist.MediaPlayList, error) {
+	skipped := int(delta.Skip.SkippedSegments)
+	index := last.GetSegmentIndexByMediaSequence(delta.MediaSequence)
+	if index < 0 || index+skipped > len(last.Segments) {
+		return delta, errors.New("fail to apply the delta update: missing the skipped media segments")
+	}
+
+	segments := make([]playlist.MediaSegment, 0, skipped+len(delta.Segments))
+	segments = append(segments, last.Segments[index:index+skipped]...)
+	segments = append(segments, delta.Segments...)
+
+	delta.Segments = segments
+	delta.Skip = playlist.XSkip{}
+	return delta, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// lowLatencyServer publishes a new partial segment for each blocking request.
+type lowLatencyServer struct {
+	lock      sync.Mutex
+	published int
+	requests  map[string]int
+	failures  int         // The number of the next playlist requests to fail.
+	reloads   []time.Time // The times of the blocking playlist reloads.
+}
+
+const (
+	llPartsPerSegment = 2
+	llTotalParts      = 8
+)
+
+func (s *lowLatencyServer) playlist() string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:1\n")
+	b.WriteString("#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1\n")
+	b.WriteString("#EXT-X-PART-INF:PART-TARGET=0.5\n")
+	for i := 0; i < s.published; i++ {
+		msn, part := i/llPartsPerSegment, i%llPartsPerSegment
+		fmt.Fprintf(&b, "#EXT-X-PART:DURATION=0.5,URI=\"part%d.%d.mp4\"", msn, part)
+		if part == 0 {
+			b.WriteString(",INDEPENDENT=YES")
+		}
+		b.WriteString("\n")
+
+		if part == llPartsPerSegment-1 {
+			fmt.Fprintf(&b, "#EXTINF:1,\nsegment%d.mp4\n", msn)
+		}
+	}
+
+	if s.published < llTotalParts {
+		msn, part := s.published/llPartsPerSegment, s.published%llPartsPerSegment
+		fmt.Fprintf(&b, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part%d.%d.mp4\"\n", msn, part)
+	} else {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	return b.String()
+}
+
+func (s *lowLatencyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if strings.HasSuffix(r.URL.Path, ".mp4") {
+		s.requests[r.URL.Path]++
+		_, _ = w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/")))
+		return
+	}
+
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	if msn := query.Get(playlist.QueryHLSMsn); msn != "" {
+		s.reloads = append(s.reloads, time.Now())
+		m, _ := strconv.Atoi(msn)
+		p, _ := strconv.Atoi(query.Get(playlist.QueryHLSPart))
+		s.published = min(max(s.published, m*llPartsPerSegment+p+1), llTotalParts)
+	}
+
+	_, _ = w.Write([]byte(s.playlist()))
+}
+
+func TestLowLatencyPoller(t *testing.T) {
+	handler := &lowLatencyServer{published: 3, requests: make(map[string]int)}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var parts []string
+	poller := &LowLatencyPoller{URL: server.URL + "/live.m3u8", Preload: true}
+	err := poller.Run(context.Background(), func(part LowLatencyPart) error {
+		if expect := part.URI; string(part.Data) != expect {
+			t.Errorf("expect part data '%s', but got '%s'", expect, part.Data)
+		}
+		parts = append(parts, fmt.Sprintf("%d.%d", part.MediaSequence, part.Index))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expects := []string{"0.0", "0.1", "1.0", "1.1", "2.0", "2.1", "3.0", "3.1"}
+	if strings.Join(parts, ",") != strings.Join(expects, ",") {
+		t.Errorf("expect parts %v, but got %v", expects, parts)
+	}
+
+	for path, count := range handler.requests {
+		if count != 1 {
+			t.Errorf("expect the part '%s' to be requested once, but got %d", path, count)
+		}
+	}
+
+	// The server responds immediately, so the blocking playlist reloads
+	// are spaced by one-half the part target at least.
+	for i := 1; i < len(handler.reloads); i++ {
+		if d := handler.reloads[i].Sub(handler.reloads[i-1]); d < 240*time.Millisecond {
+			t.Errorf("expect the reloads to be spaced by %s, but got %s", 250*time.Millisecond, d)
+		}
+	}
+}
+
+func TestLowLatencyPollerRetry(t *testing.T) {
+	handler := &lowLatencyServer{published: 3, requests: make(map[string]int), failures: 2}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var count int
+	poller := &LowLatencyPoller{URL: server.URL + "/live.m3u8", RetryInterval: time.Millisecond}
+	err := poller.Run(context.Background(), func(LowLatencyPart) error { count++; return nil })
+	if err != nil {
+		t.Fatal(err)
+	} else if count != llTotalParts {
+		t.Errorf("expect %d parts, but got %d", llTotalParts, count)
+	}
+
+	handler = &lowLatencyServer{published: 3, requests: make(map[string]int), failures: 2}
+	server2 := httptest.NewServer(handler)
+	defer server2.Close()
+
+	poller = &LowLatencyPoller{URL: server2.URL + "/live.m3u8", MaxRetries: 1, RetryInterval: time.Millisecond}
+	if err := poller.Run(context.Background(), func(LowLatencyPart) error { return nil }); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
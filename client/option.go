@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+
+	"github.com/xgfone/go-hls/playlist"
 )
 
 // Option is a function that modifies the HTTP request.
@@ -41,3 +43,48 @@ func ByteRange(offset, length uint64) Option {
 		return r
 	}
 }
+
+// Query returns an Option that adds the query parameter into the request url.
+func Query(key, value string) Option {
+	return func(r *http.Request) *http.Request {
+		query := r.URL.Query()
+		query.Add(key, value)
+		r.URL.RawQuery = query.Encode()
+		return r
+	}
+}
+
+// BlockingReload returns an Option that sets the query parameters
+// "_HLS_msn" and "_HLS_part" to request the media playlist
+// which contains the media segment msn and its partial segment part.
+//
+// If part is negative, "_HLS_part" is not set.
+func BlockingReload(msn uint64, part int64) Option {
+	return func(r *http.Request) *http.Request {
+		query := r.URL.Query()
+		query.Set(playlist.QueryHLSMsn, strconv.FormatUint(msn, 10))
+		if part >= 0 {
+			query.Set(playlist.QueryHLSPart, strconv.FormatInt(part, 10))
+		}
+		r.URL.RawQuery = query.Encode()
+		return r
+	}
+}
+
+// DeltaUpdate returns an Option that sets the query parameter "_HLS_skip"
+// to request a delta update of the media playlist.
+//
+// If skipDateRanges is true, set it to "v2". Or, set it to "YES".
+func DeltaUpdate(skipDateRanges bool) Option {
+	value := "YES"
+	if skipDateRanges {
+		value = "v2"
+	}
+
+	return func(r *http.Request) *http.Request {
+		query := r.URL.Query()
+		query.Set(playlist.QueryHLSSkip, value)
+		r.URL.RawQuery = query.Encode()
+		return r
+	}
+}
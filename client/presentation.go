@@ -0,0 +1,207 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// maxPresentationConcurrency is the maximum number of the media playlists
+// loaded concurrently by LoadPresentation.
+const maxPresentationConcurrency = 16
+
+// PresentationMedia represents a media playlist loaded for a presentation.
+type PresentationMedia struct {
+	URL      string
+	PlayList playlist.MediaPlayList
+	Err      error // The error occurred when loading the media playlist.
+}
+
+// PresentationVariant represents a variant stream in a presentation.
+type PresentationVariant struct {
+	Stream playlist.XStreamInf
+	Media  *PresentationMedia
+
+	// Renditions are the renditions in the AUDIO, VIDEO and SUBTITLES groups
+	// referred by the variant stream.
+	Renditions []PresentationRendition
+}
+
+// PresentationIFrame represents an I-frame stream in a presentation.
+type PresentationIFrame struct {
+	Stream playlist.XIFrameStreamInf
+	Media  *PresentationMedia
+}
+
+// PresentationRendition represents a rendition in a presentation.
+type PresentationRendition struct {
+	Rendition playlist.XMedia
+	Media     *PresentationMedia // nil if the rendition has no URI.
+}
+
+// Presentation represents an entire presentation, which consists of
+// a master playlist and all the media playlists referred by it.
+type Presentation struct {
+	URL    string
+	Master playlist.MasterPlayList
+
+	Variants []PresentationVariant
+	IFrames  []PresentationIFrame
+
+	// Medias contains all the media playlists, the key of which is the url.
+	Medias map[string]*PresentationMedia
+}
+
+// Err returns the joined errors occurred when loading the media playlists.
+//
+// Return nil if all the media playlists are loaded successfully.
+func (p Presentation) Err() error {
+	urls := make([]string, 0, len(p.Medias))
+	for url, media := range p.Medias {
+		if media.Err != nil {
+			urls = append(urls, url)
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil
+	}
+
+	sort.Strings(urls)
+	errs := make([]error, len(urls))
+	for i, url := range urls {
+		errs[i] = p.Medias[url].Err
+	}
+	return errors.Join(errs...)
+}
+
+// LoadPresentation loads the master playlist from url, then concurrently loads
+// all the media playlists of the variant streams, the I-frame streams
+// and the renditions referred by it.
+//
+// The error of loading a media playlist is collected in PresentationMedia.Err
+// instead of failing the whole load. So it only returns an error
+// when failing to load the master playlist.
+func LoadPresentation(ctx context.Context, url string, options ...Option) (Presentation, error) {
+	return DefaultClient.LoadPresentation(ctx, url, options...)
+}
+
+// LoadPresentation is the same as the package-level function LoadPresentation,
+// but uses the client c to load the playlists.
+func (c *Client) LoadPresentation(ctx context.Context, url string, options ...Option) (p Presentation, err error) {
+	err = c.Get(ctx, url, func(r *http.Response) error {
+		return p.Master.Parse(r.Body)
+	}, options...)
+	if err != nil {
+		return
+	}
+
+	p.URL = url
+	p.Medias = make(map[string]*PresentationMedia, len(p.Master.Streams)*2)
+
+	// The groups of the renditions may be referred by the variant streams
+	// declared after or before them.
+	groups := make(map[string][]playlist.XMedia, 4)
+	for _, s := range p.Master.Streams {
+		for _, m := range s.Medias {
+			key := groupKey(m.Type, m.GroupId)
+			groups[key] = append(groups[key], m)
+		}
+	}
+
+	for _, s := range p.Master.Streams {
+		for _, iframe := range s.IFrameStreams {
+			p.IFrames = append(p.IFrames, PresentationIFrame{
+				Stream: iframe,
+				Media:  p.media(iframe.URI),
+			})
+		}
+
+		variant := PresentationVariant{Stream: s.Stream, Media: p.media(s.Stream.URI)}
+		for _, group := range [...]struct{ Type, Id string }{
+			{Type: playlist.XMediaTypeAudio, Id: s.Stream.Audio},
+			{Type: playlist.XMediaTypeVideo, Id: s.Stream.Video},
+			{Type: playlist.XMediaTypeSubtitles, Id: s.Stream.Subtitles},
+		} {
+			if group.Id == "" {
+				continue
+			}
+
+			for _, m := range groups[groupKey(group.Type, group.Id)] {
+				rendition := PresentationRendition{Rendition: m}
+				if m.URI != "" {
+					rendition.Media = p.media(m.URI)
+				}
+				variant.Renditions = append(variant.Renditions, rendition)
+			}
+		}
+
+		p.Variants = append(p.Variants, variant)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxPresentationConcurrency)
+	for _, media := range p.Medias {
+		if media.Err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(media *PresentationMedia) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			media.Err = c.Get(ctx, media.URL, func(r *http.Response) error {
+				return media.PlayList.Parse(r.Body)
+			}, options...)
+			if media.Err != nil {
+				media.Err = fmt.Errorf("fail to load media playlist '%s': %w", media.URL, media.Err)
+			}
+		}(media)
+	}
+	wg.Wait()
+
+	return
+}
+
+// media returns the media playlist by the uri, which is deduplicated by url.
+func (p *Presentation) media(uri string) *PresentationMedia {
+	url, err := ResolveURL(p.URL, uri)
+	if err != nil {
+		url = uri
+	}
+
+	media, ok := p.Medias[url]
+	if !ok {
+		media = &PresentationMedia{URL: url}
+		if err != nil {
+			media.Err = fmt.Errorf("invalid uri '%s': %w", uri, err)
+		}
+		p.Medias[url] = media
+	}
+	return media
+}
+
+func groupKey(_type, id string) string {
+	return _type + ":" + id
+}
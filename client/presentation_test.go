@@ -0,0 +1,91 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadPresentation(t *testing.T) {
+	files := map[string]string{
+		"/hls/master.m3u8": `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",DEFAULT=YES,URI="audio/en.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="French",URI="audio/fr.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,AUDIO="aac"
+low/index.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=86000,URI="low/iframe.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=2560000,AUDIO="aac"
+/hls/mid/index.m3u8
+`,
+		"/hls/low/index.m3u8":  "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n0.ts\n#EXT-X-ENDLIST\n",
+		"/hls/mid/index.m3u8":  "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n0.ts\n#EXTINF:10,\n1.ts\n#EXT-X-ENDLIST\n",
+		"/hls/low/iframe.m3u8": "#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-TARGETDURATION:10\n#EXT-X-I-FRAMES-ONLY\n#EXT-X-BYTERANGE:100@0\n#EXTINF:10,\n0.ts\n#EXT-X-ENDLIST\n",
+		"/hls/audio/en.m3u8":   "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n0.aac\n#EXT-X-ENDLIST\n",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := files[r.URL.Path]; ok {
+			_, _ = w.Write([]byte(data))
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := LoadPresentation(context.Background(), server.URL+"/hls/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Medias) != 5 {
+		t.Errorf("expect %d media playlists, but got %d", 5, len(p.Medias))
+	}
+
+	if len(p.Variants) != 2 {
+		t.Fatalf("expect %d variants, but got %d", 2, len(p.Variants))
+	}
+	if n := len(p.Variants[0].Media.PlayList.Segments); n != 1 {
+		t.Errorf("expect %d segments in the 1st variant, but got %d", 1, n)
+	}
+	if n := len(p.Variants[1].Media.PlayList.Segments); n != 2 {
+		t.Errorf("expect %d segments in the 2nd variant, but got %d", 2, n)
+	}
+
+	for i, v := range p.Variants {
+		if len(v.Renditions) != 2 {
+			t.Errorf("%d: expect %d renditions, but got %d", i, 2, len(v.Renditions))
+		} else if err := v.Renditions[0].Media.Err; err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		} else if v.Renditions[1].Media.Err == nil {
+			t.Errorf("%d: expect an error, but got nil", i)
+		}
+	}
+
+	if len(p.IFrames) != 1 {
+		t.Errorf("expect %d I-frame streams, but got %d", 1, len(p.IFrames))
+	} else if !p.IFrames[0].Media.PlayList.IFrameOnly {
+		t.Errorf("expect an I-frame only media playlist")
+	}
+
+	if err := p.Err(); err == nil {
+		t.Errorf("expect an error, but got nil")
+	} else if !strings.Contains(err.Error(), "/hls/audio/fr.m3u8") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
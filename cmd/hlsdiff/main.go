@@ -0,0 +1,161 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command hlsdiff compares two HLS playlists, which may be the local files,
+// "-" for the standard input or the http(s) urls, and prints the changes
+// from the old to the new.
+//
+// Usage:
+//
+//	hlsdiff [flags] OLD NEW
+//	hlsdiff [flags] -watch INTERVAL URL
+//
+// In the watch mode, it reloads the live playlist by the interval,
+// and prints the changes between the two continuous reloads.
+//
+// The exit code is 1 if there are changes, 2 if there is an error, or 0.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/xgfone/go-hls/cmd/internal/loader"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+var (
+	watch   = flag.Duration("watch", 0, "The interval to reload the playlist and print the changes.")
+	timeout = flag.Duration("timeout", 10*time.Second, "The timeout to load the playlist by http.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] OLD NEW\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] -watch INTERVAL URL\n\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var changed bool
+	var err error
+	switch {
+	case *watch > 0 && flag.NArg() == 1:
+		err = watchPlayList(ctx, flag.Arg(0), *watch)
+	case *watch <= 0 && flag.NArg() == 2:
+		changed, err = diff(ctx, flag.Arg(0), flag.Arg(1))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, context.Canceled):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	case changed:
+		os.Exit(1)
+	}
+}
+
+func diff(ctx context.Context, oldpath, newpath string) (changed bool, err error) {
+	old, err := load(ctx, oldpath)
+	if err != nil {
+		return
+	}
+
+	new, err := load(ctx, newpath)
+	if err != nil {
+		return
+	}
+
+	changes, err := diffPlayList(old, new)
+	if err == nil && len(changes) > 0 {
+		changed = true
+		fmt.Print(changes)
+	}
+	return
+}
+
+func watchPlayList(ctx context.Context, url string, interval time.Duration) error {
+	last, err := load(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			current, err := load(ctx, url)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", now.Format(time.RFC3339), err)
+				continue
+			}
+
+			changes, err := diffPlayList(last, current)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) > 0 {
+				fmt.Printf("=== %s\n%s", now.Format(time.RFC3339), changes)
+			}
+			last = current
+		}
+	}
+}
+
+func diffPlayList(old, new playlist.PlayList) (playlist.Changes, error) {
+	switch oldpl := old.(type) {
+	case playlist.MediaPlayList:
+		if newpl, ok := new.(playlist.MediaPlayList); ok {
+			return playlist.DiffMedia(oldpl, newpl), nil
+		}
+
+	case playlist.MasterPlayList:
+		if newpl, ok := new.(playlist.MasterPlayList); ok {
+			return playlist.DiffMaster(oldpl, newpl), nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot compare the %s playlist with the %s playlist", old.Type(), new.Type())
+}
+
+func load(ctx context.Context, path string) (pl playlist.PlayList, err error) {
+	if loader.IsURL(path) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if pl, err = loader.LoadPlayList(ctx, path); err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
@@ -0,0 +1,447 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/xgfone/go-hls/client"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// errRecorded is used to stop recording the live stream
+// when the recorded duration reaches the limit.
+var errRecorded = errors.New("recorded")
+
+type downloader struct {
+	client  *client.Client
+	keys    *client.HTTPKeyProvider
+	sem     chan struct{}
+	decrypt bool
+}
+
+// mediaTask downloads the media segments of a media playlist into dir.
+type mediaTask struct {
+	*downloader
+	dir string
+
+	wg   sync.WaitGroup
+	lock sync.Mutex
+	errs []error
+
+	// segments are the recorded media segments, the uris of which
+	// are the resource ids, which are rewritten to the local uris
+	// by locals when converting them to the VOD playlist.
+	segments  []playlist.MediaSegment
+	locals    map[string]string
+	duration  float64 // The total duration of segments.
+	encrypted bool    // Whether some media segments are kept encrypted.
+	nmaps     int
+	nkeys     int
+
+	lastmap playlist.XMap
+	lastseg playlist.MediaSegment
+}
+
+// download downloads the media playlist from url and all its media segments
+// into dir, then writes the local media playlist as "index.m3u8" into dir.
+//
+// For the live media playlist, it is recorded until EXT-X-ENDLIST,
+// the duration limit, or ctx is done.
+func (d *downloader) download(ctx context.Context, url, dir string) (local playlist.MediaPlayList, err error) {
+	pl, err := d.load(ctx, url)
+	if err != nil {
+		return
+	}
+
+	media, ok := pl.(playlist.MediaPlayList)
+	if !ok {
+		return local, fmt.Errorf("'%s' is not a media playlist", url)
+	}
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+
+	t := &mediaTask{downloader: d, dir: dir, locals: make(map[string]string, len(media.Segments)+2)}
+	if media.EndList || media.PlayListType == playlist.MediaPlayListTypeVOD {
+		for _, seg := range media.Segments {
+			if err = t.add(ctx, url, seg); err != nil {
+				break
+			}
+		}
+	} else {
+		err = t.record(ctx, url)
+	}
+
+	t.wg.Wait()
+	if err = errors.Join(append(t.errs, err)...); err != nil {
+		return
+	}
+
+	local, err = playlist.LiveToVOD(t.snapshots(media), func(id string) string {
+		if uri, ok := t.locals[id]; ok {
+			return uri
+		}
+		return id
+	})
+	if err != nil {
+		return
+	}
+
+	err = writePlayList(filepath.Join(dir, "index.m3u8"), local)
+	return
+}
+
+// snapshots splits the recorded media segments into the snapshots
+// with the continuous media sequence numbers for playlist.LiveToVOD,
+// which marks the gaps between them as the discontinuities.
+func (t *mediaTask) snapshots(media playlist.MediaPlayList) (snapshots []playlist.MediaPlayList) {
+	for i, seg := range t.segments {
+		if i == 0 || seg.MediaSequence != t.segments[i-1].MediaSequence+1 {
+			dseq := seg.DiscontinuitySequence
+			if seg.Discontinuity && dseq > 0 {
+				dseq--
+			}
+
+			snapshots = append(snapshots, playlist.MediaPlayList{
+				Version:               media.Version,
+				TargetDuration:        media.TargetDuration,
+				MediaSequence:         seg.MediaSequence,
+				DiscontinuitySequence: dseq,
+				IndependentSegments:   media.IndependentSegments,
+				IFrameOnly:            media.IFrameOnly,
+			})
+		}
+
+		snapshot := &snapshots[len(snapshots)-1]
+		snapshot.Segments = append(snapshot.Segments, seg)
+	}
+	return
+}
+
+// record polls the live media playlist and downloads the newly added
+// media segments until EXT-X-ENDLIST, the duration limit, or ctx is done.
+//
+// The media segments in downloading are completed even if ctx is done.
+func (t *mediaTask) record(ctx context.Context, url string) (err error) {
+	poller := &client.LivePoller{URL: url, Client: t.client}
+	dctx := context.WithoutCancel(ctx)
+	err = poller.Run(ctx, func(seg playlist.MediaSegment) error {
+		if *duration > 0 && t.duration >= duration.Seconds() {
+			return errRecorded
+		}
+		return t.add(dctx, poller.CurrentURL(), seg)
+	})
+
+	switch {
+	case errors.Is(err, errRecorded):
+		err = nil
+	case ctx.Err() != nil:
+		fmt.Fprintf(os.Stderr, "stop recording '%s': %v\n", url, context.Cause(ctx))
+		err = nil
+	}
+	return
+}
+
+// add downloads the media segment in background, and records it
+// with the resource ids as the uris.
+//
+// baseurl is used to resolve the relative uris of the media segment.
+func (t *mediaTask) add(ctx context.Context, baseurl string, seg playlist.MediaSegment) (err error) {
+	// The init section and the implicit offset of the byte range
+	// are inherited from the preceding media segment.
+	if seg.Map.IsZero() {
+		seg.Map = t.lastmap
+	}
+	if seg.ByteRange.Length > 0 && seg.ByteRange.Offset == 0 && seg.URI == t.lastseg.URI {
+		seg.ByteRange.Offset = t.lastseg.ByteRange.Offset + t.lastseg.ByteRange.Length
+	}
+	t.lastmap, t.lastseg = seg.Map, seg
+
+	url, err := client.ResolveURL(baseurl, seg.URI)
+	if err != nil {
+		return fmt.Errorf("invalid segment uri '%s': %w", seg.URI, err)
+	}
+
+	record := seg
+	record.URI = resourceID(url, seg.ByteRange)
+	record.ByteRange = playlist.XByteRange{}
+	record.Parts = nil
+
+	name := fmt.Sprintf("%06d%s", seg.MediaSequence, extension(url, ".ts"))
+	t.locals[record.URI] = name
+
+	if !seg.Map.IsZero() {
+		if record.Map.URI, err = t.initSection(ctx, baseurl, seg); err != nil {
+			return
+		}
+		record.Map.ByteRange = playlist.XByteRange{}
+	}
+
+	var transform func([]byte) ([]byte, error)
+	if key, ok := client.SelectKey(seg); ok && t.decrypt && key.Method == playlist.XKeyMethodAES128 {
+		transform = func(data []byte) ([]byte, error) {
+			return client.DecryptSegment(ctx, t.keys, baseurl, seg, data)
+		}
+
+		// The decrypted media segment must not inherit the preceding keys.
+		record.Keys = nil
+		if t.encrypted {
+			record.Keys = []playlist.XKey{{Method: playlist.XKeyMethodNone}}
+		}
+	} else if record.Keys, err = t.localKeys(ctx, baseurl, seg.Keys); err != nil {
+		return
+	}
+
+	t.segments = append(t.segments, record)
+	t.duration += seg.Duration
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.sem <- struct{}{}
+		defer func() { <-t.sem }()
+
+		err := t.fetch(ctx, url, seg.ByteRange, filepath.Join(t.dir, name), transform)
+		if err != nil {
+			t.lock.Lock()
+			t.errs = append(t.errs, err)
+			t.lock.Unlock()
+		}
+	}()
+
+	return
+}
+
+// initSection downloads the init section of the media segment,
+// and returns its resource id.
+//
+// RFC 8216, 4.3.2.5: the init section encrypted by AES-128 must have
+// the IV attribute. So the one with the AES-128 key but without IV
+// is considered as not encrypted.
+func (t *mediaTask) initSection(ctx context.Context, baseurl string, seg playlist.MediaSegment) (id string, err error) {
+	url, err := client.ResolveURL(baseurl, seg.Map.URI)
+	if err != nil {
+		return "", fmt.Errorf("invalid map uri '%s': %w", seg.Map.URI, err)
+	}
+
+	id = resourceID(url, seg.Map.ByteRange)
+	if _, ok := t.locals[id]; ok {
+		return
+	}
+
+	var transform func([]byte) ([]byte, error)
+	if key, ok := client.SelectKey(seg); ok && t.decrypt && key.Method == playlist.XKeyMethodAES128 && key.IV != "" {
+		transform = func(data []byte) ([]byte, error) {
+			return client.DecryptSegment(ctx, t.keys, baseurl, playlist.MediaSegment{Keys: []playlist.XKey{key}}, data)
+		}
+	}
+
+	name := fmt.Sprintf("init%d%s", t.nmaps, extension(url, ".mp4"))
+	if err = t.fetch(ctx, url, seg.Map.ByteRange, filepath.Join(t.dir, name), transform); err == nil {
+		t.locals[id] = name
+		t.nmaps++
+	}
+	return
+}
+
+// localKeys downloads the keys with the identity key format, and returns
+// the keys with the absolute urls, which are recorded as the resource ids
+// of the local key files.
+//
+// The keys with the other key formats are kept with the absolute urls.
+func (t *mediaTask) localKeys(ctx context.Context, baseurl string, keys []playlist.XKey) ([]playlist.XKey, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	locals := make([]playlist.XKey, len(keys))
+	for i, key := range keys {
+		locals[i] = key
+		if key.URI == "" || key.Method == playlist.XKeyMethodNone {
+			continue
+		}
+
+		url, err := client.ResolveURL(baseurl, key.URI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key uri '%s': %w", key.URI, err)
+		}
+		locals[i].URI = url
+		t.encrypted = true
+
+		if _, ok := t.locals[url]; ok || (key.Format != "" && key.Format != client.KeyFormatIdentity) {
+			continue
+		}
+
+		key.URI = url
+		data, err := t.keys.GetKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("key%d.key", t.nkeys)
+		if err = writeFile(filepath.Join(t.dir, name), data); err != nil {
+			return nil, err
+		}
+		t.locals[url] = name
+		t.nkeys++
+	}
+	return locals, nil
+}
+
+// resourceID returns the id of the resource by the url and the byte range.
+func resourceID(url string, br playlist.XByteRange) string {
+	if br.Length == 0 {
+		return url
+	}
+	return fmt.Sprintf("%s#%d@%d", url, br.Length, br.Offset)
+}
+
+// fetch downloads the resource from url into the file by path,
+// which is skipped if the file has been downloaded.
+func (d *downloader) fetch(ctx context.Context, url string, br playlist.XByteRange,
+	path string, transform func([]byte) ([]byte, error)) (err error) {
+	if _, err = os.Stat(path); err == nil {
+		return
+	}
+
+	var options []client.Option
+	if br.Length > 0 {
+		options = append(options, client.ByteRange(br.Offset, br.Length))
+	}
+
+	var data []byte
+	err = d.client.Get(ctx, url, func(r *http.Response) (err error) {
+		data, err = io.ReadAll(r.Body)
+		return
+	}, options...)
+	if err == nil && transform != nil {
+		data, err = transform(data)
+	}
+	if err != nil {
+		return fmt.Errorf("fail to download '%s': %w", url, err)
+	}
+
+	return writeFile(path, data)
+}
+
+// concat downloads the media playlist from url, and concatenates
+// its init sections and media segments into the file by output.
+//
+// The media segments are downloaded into the directory "OUTPUT.parts",
+// which is removed after concatenating them successfully.
+func (d *downloader) concat(ctx context.Context, url, output string) (err error) {
+	dir := output + ".parts"
+	pl, err := d.download(ctx, url, dir)
+	if err != nil {
+		return
+	}
+
+	tmp := output + ".part"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	var lastmap string
+	for _, seg := range pl.Segments {
+		if _, ok := client.SelectKey(seg); ok {
+			err = fmt.Errorf("cannot concatenate the encrypted media segment %d", seg.MediaSequence)
+			break
+		}
+
+		if seg.Map.URI != "" && seg.Map.URI != lastmap {
+			if err = appendFile(file, filepath.Join(dir, seg.Map.URI)); err != nil {
+				break
+			}
+			lastmap = seg.Map.URI
+		}
+
+		if err = appendFile(file, filepath.Join(dir, seg.URI)); err != nil {
+			break
+		}
+	}
+
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+
+	if err = os.Rename(tmp, output); err == nil {
+		err = os.RemoveAll(dir)
+	}
+	return
+}
+
+func appendFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// writeFile writes the data into a temporary file and renames it to path,
+// so that the file by path is always complete.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".part"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writePlayList(path string, pl interface{ Output(io.Writer) error }) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = pl.Output(file)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// extension returns the file extension of the url path, or _default.
+func extension(rawurl, _default string) string {
+	if u, err := url.Parse(rawurl); err == nil {
+		if ext := path.Ext(u.Path); ext != "" && len(ext) <= 6 {
+			return ext
+		}
+	}
+	return _default
+}
@@ -0,0 +1,318 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command hlsdl downloads a HLS stream by the url of the master or media
+// playlist into a local self-contained HLS tree or a concatenated file.
+//
+// Usage:
+//
+//	hlsdl [flags] URL
+//
+// For the master playlist, a variant stream is chosen by -variant,
+// which may be "highest", "lowest", the height such as "720p",
+// the resolution such as "1280x720", or the maximum bandwidth
+// such as "2500000", "2500k" or "2.5M". In the tree mode, the renditions
+// referenced by the chosen variant stream are also downloaded.
+//
+// The media segments are downloaded concurrently, and the downloaded ones
+// are skipped when running again for the same output. The media segments
+// encrypted by AES-128 are decrypted. For the live stream, it is recorded
+// until EXT-X-ENDLIST, the duration by -duration, or the interrupt signal.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xgfone/go-hls/client"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+var (
+	output      = flag.String("o", "hls", "The output directory of the HLS tree, or the output file if -concat is set.")
+	concat      = flag.Bool("concat", false, "If true, concatenate the media segments of the variant stream into a file.")
+	variant     = flag.String("variant", "highest", "The variant stream to download: highest, lowest, 720p, 1280x720 or the bandwidth such as 2.5M.")
+	concurrency = flag.Int("j", 4, "The number of the media segments downloaded concurrently.")
+	duration    = flag.Duration("duration", 0, "The maximum duration to record the live stream. 0 means until EXT-X-ENDLIST.")
+	timeout     = flag.Duration("timeout", 30*time.Second, "The timeout of each http request.")
+	noDecrypt   = flag.Bool("no-decrypt", false, "If true, do not decrypt the media segments encrypted by AES-128.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] URL\n\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 || *concurrency < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Restore the default behavior of the interrupt signal after the first
+	// one, so that the second one can terminate the in-flight downloads.
+	go func() { <-ctx.Done(); stop() }()
+
+	if err := run(ctx, flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, url string) error {
+	d := newDownloader()
+	pl, err := d.load(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	master, ok := pl.(playlist.MasterPlayList)
+	if !ok {
+		if *concat {
+			return d.concat(ctx, url, *output)
+		}
+		_, err = d.download(ctx, url, *output)
+		return err
+	}
+
+	stream, err := selectVariant(master, *variant)
+	if err != nil {
+		return err
+	}
+
+	if *concat {
+		uri, err := client.ResolveURL(url, stream.Stream.URI)
+		if err != nil {
+			return err
+		}
+		return d.concat(ctx, uri, *output)
+	}
+	return d.downloadMaster(ctx, url, master, stream, *output)
+}
+
+// selectVariant selects the variant stream by the selector.
+func selectVariant(master playlist.MasterPlayList, selector string) (stream playlist.MasterStream, err error) {
+	var streams []playlist.MasterStream
+	for _, v := range master.Variants() {
+		streams = append(streams, v.Primary())
+	}
+	if len(streams) == 0 {
+		return stream, errors.New("no variant streams in the master playlist")
+	}
+
+	better := func(a, b playlist.MasterStream) bool { return a.Stream.Bandwidth > b.Stream.Bandwidth }
+	switch selector = strings.ToLower(selector); {
+	case selector == "highest":
+
+	case selector == "lowest":
+		better = func(a, b playlist.MasterStream) bool { return a.Stream.Bandwidth < b.Stream.Bandwidth }
+
+	case strings.HasSuffix(selector, "p") || strings.Contains(selector, "x"):
+		var width, height uint64
+		if h, ok := strings.CutSuffix(selector, "p"); ok {
+			height, err = strconv.ParseUint(h, 10, 64)
+		} else {
+			w, h, _ := strings.Cut(selector, "x")
+			if width, err = strconv.ParseUint(w, 10, 64); err == nil {
+				height, err = strconv.ParseUint(h, 10, 64)
+			}
+		}
+		if err != nil {
+			return stream, fmt.Errorf("invalid variant selector '%s'", selector)
+		}
+
+		// Prefer the closest resolution, then the higher bandwidth.
+		distance := func(s playlist.MasterStream) uint64 {
+			d := absDiff(s.Stream.Resolution.Height, height)
+			if width > 0 {
+				d += absDiff(s.Stream.Resolution.Width, width)
+			}
+			return d
+		}
+		better = func(a, b playlist.MasterStream) bool {
+			if da, db := distance(a), distance(b); da != db {
+				return da < db
+			}
+			return a.Stream.Bandwidth > b.Stream.Bandwidth
+		}
+
+	default:
+		bandwidth, err := parseBandwidth(selector)
+		if err != nil {
+			return stream, fmt.Errorf("invalid variant selector '%s'", selector)
+		}
+
+		// Prefer the highest bandwidth not exceeding the limit, or the lowest.
+		better = func(a, b playlist.MasterStream) bool {
+			af, bf := a.Stream.Bandwidth <= bandwidth, b.Stream.Bandwidth <= bandwidth
+			switch {
+			case af != bf:
+				return af
+			case af:
+				return a.Stream.Bandwidth > b.Stream.Bandwidth
+			default:
+				return a.Stream.Bandwidth < b.Stream.Bandwidth
+			}
+		}
+	}
+
+	stream = streams[0]
+	for _, s := range streams[1:] {
+		if better(s, stream) {
+			stream = s
+		}
+	}
+	return
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func parseBandwidth(s string) (uint64, error) {
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		s, scale = s[:len(s)-1], 1e3
+	case strings.HasSuffix(s, "m"):
+		s, scale = s[:len(s)-1], 1e6
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, errors.New("invalid bandwidth")
+	}
+	return uint64(value * scale), nil
+}
+
+func newDownloader() *downloader {
+	c := &client.Client{Timeout: *timeout}
+	return &downloader{
+		client:  c,
+		keys:    client.NewHTTPKeyProvider(c),
+		sem:     make(chan struct{}, *concurrency),
+		decrypt: !*noDecrypt,
+	}
+}
+
+func (d *downloader) load(ctx context.Context, url string) (pl playlist.PlayList, err error) {
+	err = d.client.Get(ctx, url, func(r *http.Response) (err error) {
+		pl, err = playlist.Parse(r.Body)
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("fail to load playlist '%s': %w", url, err)
+	}
+	return
+}
+
+// downloadMaster downloads the variant stream and its renditions into dir,
+// and writes the master playlist only containing them.
+func (d *downloader) downloadMaster(ctx context.Context, url string,
+	master playlist.MasterPlayList, stream playlist.MasterStream, dir string) (err error) {
+	var medias []playlist.XMedia
+	for _, s := range master.Streams {
+		for _, m := range s.Medias {
+			if slices.ContainsFunc(medias, func(x playlist.XMedia) bool {
+				return x.Type == m.Type && x.GroupId == m.GroupId && x.Name == m.Name
+			}) {
+				continue
+			}
+
+			switch {
+			case m.Type == playlist.XMediaTypeAudio && m.GroupId == stream.Stream.Audio,
+				m.Type == playlist.XMediaTypeVideo && m.GroupId == stream.Stream.Video,
+				m.Type == playlist.XMediaTypeSubtitles && m.GroupId == stream.Stream.Subtitles,
+				m.Type == playlist.XMediaTypeClosedCaptions && m.GroupId == stream.Stream.ClosedCaptions:
+				medias = append(medias, m)
+			}
+		}
+	}
+
+	// The local directories of the variant stream and the renditions,
+	// the key of which is the url of the media playlist.
+	dirs := make(map[string]string, len(medias)+1)
+	uri, err := client.ResolveURL(url, stream.Stream.URI)
+	if err != nil {
+		return
+	}
+	dirs[uri] = "main"
+	stream.Stream.URI = "main/index.m3u8"
+
+	for i := range medias {
+		if medias[i].URI == "" {
+			continue
+		}
+
+		uri, err := client.ResolveURL(url, medias[i].URI)
+		if err != nil {
+			return err
+		}
+
+		name, ok := dirs[uri]
+		if !ok {
+			name = fmt.Sprintf("%s-%d", strings.ToLower(medias[i].Type), len(dirs))
+			dirs[uri] = name
+		}
+		medias[i].URI = name + "/index.m3u8"
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	for uri, name := range dirs {
+		wg.Add(1)
+		go func(uri, name string) {
+			defer wg.Done()
+			if _, err := d.download(ctx, uri, filepath.Join(dir, name)); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(uri, name)
+	}
+	wg.Wait()
+
+	if err = errors.Join(errs...); err != nil {
+		return
+	}
+
+	local := playlist.MasterPlayList{IndependentSegments: master.IndependentSegments}
+	stream.Medias = medias
+	stream.IFrameStreams = nil
+	local.Streams = []playlist.MasterStream{stream}
+	local.Version = max(master.Version, local.MinVersion())
+	return writePlayList(filepath.Join(dir, "master.m3u8"), local)
+}
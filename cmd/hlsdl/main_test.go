@@ -0,0 +1,305 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+const testMaster = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=640x360
+360p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=6000000,RESOLUTION=1920x1080
+1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1280x720
+720p.m3u8
+`
+
+// The init section is encrypted by the key with IV preceding EXT-X-MAP.
+const testMedia = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-DISCONTINUITY-SEQUENCE:3
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x00000000000000000000000000000001
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+1.m4s
+#EXTINF:4,
+2.m4s
+#EXTINF:3.5,
+3.m4s
+#EXT-X-ENDLIST
+`
+
+var (
+	testKey = []byte("0123456789abcdef")
+	testIV  = append(make([]byte, 15), 1)
+)
+
+type testOrigin struct {
+	*httptest.Server
+	plains map[string][]byte
+
+	lock     sync.Mutex
+	requests map[string]int
+}
+
+func newTestOrigin(t *testing.T) *testOrigin {
+	o := &testOrigin{plains: make(map[string][]byte), requests: make(map[string]int)}
+	files := map[string][]byte{
+		"/master.m3u8": []byte(testMaster),
+		"/720p.m3u8":   []byte(testMedia),
+		"/key.bin":     testKey,
+	}
+
+	for _, name := range []string{"/init.mp4", "/1.m4s", "/2.m4s", "/3.m4s"} {
+		o.plains[name] = bytes.Repeat([]byte(name), 100)
+		encrypted, err := aes128.Encrypt(o.plains[name], testKey, testIV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = encrypted
+	}
+
+	o.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o.lock.Lock()
+		o.requests[r.URL.Path]++
+		o.lock.Unlock()
+
+		if data, ok := files[r.URL.Path]; ok {
+			_, _ = w.Write(data)
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+	return o
+}
+
+func (o *testOrigin) reset() (requests map[string]int) {
+	o.lock.Lock()
+	requests, o.requests = o.requests, make(map[string]int)
+	o.lock.Unlock()
+	return
+}
+
+func TestSelectVariant(t *testing.T) {
+	var master playlist.MasterPlayList
+	if err := master.Parse(strings.NewReader(testMaster)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		Selector string
+		Expect   string
+	}{
+		{Selector: "highest", Expect: "1080p.m3u8"},
+		{Selector: "lowest", Expect: "360p.m3u8"},
+		{Selector: "720p", Expect: "720p.m3u8"},
+		{Selector: "700p", Expect: "720p.m3u8"},
+		{Selector: "1920x1080", Expect: "1080p.m3u8"},
+		{Selector: "2M", Expect: "360p.m3u8"},
+		{Selector: "3000k", Expect: "720p.m3u8"},
+		{Selector: "100k", Expect: "360p.m3u8"},
+		{Selector: "10000000", Expect: "1080p.m3u8"},
+	} {
+		stream, err := selectVariant(master, c.Selector)
+		if err != nil {
+			t.Errorf("%s: %v", c.Selector, err)
+		} else if stream.Stream.URI != c.Expect {
+			t.Errorf("%s: expect variant '%s', but got '%s'", c.Selector, c.Expect, stream.Stream.URI)
+		}
+	}
+
+	for _, selector := range []string{"", "best", "p", "1280x", "-1"} {
+		if _, err := selectVariant(master, selector); err == nil {
+			t.Errorf("%s: expect an error, but got nil", selector)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	for s, expect := range map[string]uint64{
+		"2500000": 2500000,
+		"2500k":   2500000,
+		"2.5m":    2500000,
+	} {
+		if bandwidth, err := parseBandwidth(s); err != nil {
+			t.Errorf("%s: %v", s, err)
+		} else if bandwidth != expect {
+			t.Errorf("%s: expect bandwidth %d, but got %d", s, expect, bandwidth)
+		}
+	}
+
+	for _, s := range []string{"", "0", "-1", "k", "1g"} {
+		if _, err := parseBandwidth(s); err == nil {
+			t.Errorf("%s: expect an error, but got nil", s)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	origin := newTestOrigin(t)
+	defer origin.Close()
+
+	d, dir := newDownloader(), t.TempDir()
+	pl, err := d.load(context.Background(), origin.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := selectVariant(pl.(playlist.MasterPlayList), "720p")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.downloadMaster(context.Background(), origin.URL+"/master.m3u8", pl.(playlist.MasterPlayList), stream, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var master playlist.MasterPlayList
+	parsePlayList(t, filepath.Join(dir, "master.m3u8"), &master)
+	if len(master.Streams) != 1 || master.Streams[0].Stream.URI != "main/index.m3u8" {
+		t.Errorf("unexpected master streams: %+v", master.Streams)
+	}
+
+	var media playlist.MediaPlayList
+	parsePlayList(t, filepath.Join(dir, "main", "index.m3u8"), &media)
+	if media.DiscontinuitySequence != 3 || media.MediaSequence != 1 {
+		t.Errorf("expect sequences %d/%d, but got %d/%d", 1, 3, media.MediaSequence, media.DiscontinuitySequence)
+	} else if len(media.Segments) != 3 || !media.Segments[0].Discontinuity {
+		t.Fatalf("unexpected media segments: %+v", media.Segments)
+	} else if media.Segments[0].Map.URI != "init0.mp4" {
+		t.Errorf("expect map uri '%s', but got '%s'", "init0.mp4", media.Segments[0].Map.URI)
+	}
+
+	for i, seg := range media.Segments {
+		if len(seg.Keys) != 0 {
+			t.Errorf("%d: expect the decrypted media segment without keys, but got %+v", i, seg.Keys)
+		}
+		if expect := fmt.Sprintf("%06d.m4s", i+1); seg.URI != expect {
+			t.Errorf("%d: expect segment uri '%s', but got '%s'", i, expect, seg.URI)
+		}
+		checkFile(t, filepath.Join(dir, "main", seg.URI), origin.plains[fmt.Sprintf("/%d.m4s", i+1)])
+	}
+	checkFile(t, filepath.Join(dir, "main", "init0.mp4"), origin.plains["/init.mp4"])
+
+	// Resume: only download the missing media segment.
+	origin.reset()
+	if err := os.Remove(filepath.Join(dir, "main", "000002.m4s")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.download(context.Background(), origin.URL+"/720p.m3u8", filepath.Join(dir, "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := origin.reset()
+	for _, name := range []string{"/init.mp4", "/1.m4s", "/3.m4s"} {
+		if requests[name] != 0 {
+			t.Errorf("expect not to download '%s' again, but got %d requests", name, requests[name])
+		}
+	}
+	if requests["/2.m4s"] != 1 {
+		t.Errorf("expect to download '%s' once, but got %d requests", "/2.m4s", requests["/2.m4s"])
+	}
+	checkFile(t, filepath.Join(dir, "main", "000002.m4s"), origin.plains["/2.m4s"])
+}
+
+func TestConcat(t *testing.T) {
+	origin := newTestOrigin(t)
+	defer origin.Close()
+
+	output := filepath.Join(t.TempDir(), "output.mp4")
+	if err := newDownloader().concat(context.Background(), origin.URL+"/720p.m3u8", output); err != nil {
+		t.Fatal(err)
+	}
+
+	var expect []byte
+	for _, name := range []string{"/init.mp4", "/1.m4s", "/2.m4s", "/3.m4s"} {
+		expect = append(expect, origin.plains[name]...)
+	}
+	checkFile(t, output, expect)
+
+	if _, err := os.Stat(output + ".parts"); !os.IsNotExist(err) {
+		t.Errorf("expect the parts directory is removed, but got %v", err)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	task := &mediaTask{locals: map[string]string{"http://localhost/5.ts": "000005.ts"}}
+	for _, seq := range []uint64{1, 2, 5} {
+		task.segments = append(task.segments, playlist.MediaSegment{
+			URI:                   fmt.Sprintf("http://localhost/%d.ts", seq),
+			Duration:              4,
+			MediaSequence:         seq,
+			DiscontinuitySequence: 2,
+		})
+	}
+
+	snapshots := task.snapshots(playlist.MediaPlayList{TargetDuration: 4})
+	if len(snapshots) != 2 {
+		t.Fatalf("expect %d snapshots, but got %d", 2, len(snapshots))
+	}
+
+	vod, err := playlist.LiveToVOD(snapshots, func(id string) string { return task.locals[id] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vod.Segments) != 3 || !vod.Segments[2].Discontinuity || vod.Segments[1].Discontinuity {
+		t.Errorf("expect the gap is marked as the discontinuity, but got %+v", vod.Segments)
+	} else if vod.Segments[2].URI != "000005.ts" || vod.DiscontinuitySequence != 2 {
+		t.Errorf("unexpected VOD playlist: %+v", vod)
+	}
+}
+
+func parsePlayList(t *testing.T, path string, pl interface{ Parse(io.Reader) error }) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := pl.Parse(file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkFile(t *testing.T, path string, expect []byte) {
+	t.Helper()
+	if data, err := os.ReadFile(path); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(data, expect) {
+		t.Errorf("%s: unexpected content with %d bytes", filepath.Base(path), len(data))
+	}
+}
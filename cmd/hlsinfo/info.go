@@ -0,0 +1,232 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Info is the summary of a master or media playlist.
+type Info struct {
+	Path            string
+	Type            string // Master or Media
+	Version         uint64 `json:",omitempty"`
+	RequiredVersion uint64
+
+	// Only for the master playlist.
+	Variants []VariantInfo   `json:",omitempty"`
+	IFrames  []VariantInfo   `json:",omitempty"`
+	Groups   []RenditionInfo `json:",omitempty"`
+
+	// Only for the media playlist.
+	Media *MediaInfo `json:",omitempty"`
+}
+
+// VariantInfo is the summary of a variant stream or an I-frame stream.
+type VariantInfo struct {
+	URI              string
+	Bandwidth        uint64
+	AverageBandwidth uint64  `json:",omitempty"`
+	Resolution       string  `json:",omitempty"`
+	Codecs           string  `json:",omitempty"`
+	FrameRate        float64 `json:",omitempty"`
+	HDCP             string  `json:",omitempty"`
+	Audio            string  `json:",omitempty"`
+	Video            string  `json:",omitempty"`
+	Subtitles        string  `json:",omitempty"`
+	ClosedCaptions   string  `json:",omitempty"`
+
+	Media *MediaInfo `json:",omitempty"`
+	Error string     `json:",omitempty"` // The error to load the media playlist.
+}
+
+// RenditionInfo is the summary of the renditions in a group.
+type RenditionInfo struct {
+	Type       string
+	GroupId    string
+	Renditions []Rendition
+}
+
+// Rendition is the summary of a rendition.
+type Rendition struct {
+	Name       string
+	Language   string `json:",omitempty"`
+	Channels   string `json:",omitempty"`
+	Default    bool   `json:",omitempty"`
+	AutoSelect bool   `json:",omitempty"`
+	Forced     bool   `json:",omitempty"`
+	URI        string `json:",omitempty"`
+
+	Media *MediaInfo `json:",omitempty"`
+	Error string     `json:",omitempty"` // The error to load the media playlist.
+}
+
+// MediaInfo is the summary of a media playlist.
+type MediaInfo struct {
+	PlayListType    string `json:",omitempty"` // VOD, EVENT or empty for live.
+	Live            bool
+	IFrameOnly      bool `json:",omitempty"`
+	Version         uint64
+	RequiredVersion uint64
+
+	MediaSequence  uint64
+	TargetDuration uint64 // Unit: second
+	Segments       int
+	TotalDuration  float64 // Unit: second
+
+	MinSegmentDuration float64 // Unit: second
+	MaxSegmentDuration float64 // Unit: second
+	AvgSegmentDuration float64 // Unit: second
+
+	Encryptions     []string `json:",omitempty"` // The key methods.
+	Discontinuities int
+
+	ProgramDateTimeStart *time.Time `json:",omitempty"`
+	ProgramDateTimeEnd   *time.Time `json:",omitempty"`
+}
+
+// masterInfo returns the summary of the master playlist
+// without the media playlists.
+func masterInfo(path string, pl playlist.MasterPlayList) Info {
+	info := Info{Path: path, Type: pl.Type(), Version: pl.Version}
+	pl.Version = 0
+	info.RequiredVersion = pl.MinVersion()
+
+	for _, s := range pl.Streams {
+		for _, m := range s.Medias {
+			info.addRendition(m)
+		}
+
+		for _, x := range s.IFrameStreams {
+			info.IFrames = append(info.IFrames, VariantInfo{
+				URI:              x.URI,
+				Bandwidth:        x.Bandwidth,
+				AverageBandwidth: x.AverageBandwidth,
+				Resolution:       resolution(x.Resolution),
+				Codecs:           strings.Join(x.Codecs, ","),
+				HDCP:             x.HdcpLevel,
+				Video:            x.Video,
+			})
+		}
+
+		if x := s.Stream; !x.IsZero() {
+			info.Variants = append(info.Variants, VariantInfo{
+				URI:              x.URI,
+				Bandwidth:        x.Bandwidth,
+				AverageBandwidth: x.AverageBandwidth,
+				Resolution:       resolution(x.Resolution),
+				Codecs:           strings.Join(x.Codecs, ","),
+				FrameRate:        x.FrameRate,
+				HDCP:             x.HdcpLevel,
+				Audio:            x.Audio,
+				Video:            x.Video,
+				Subtitles:        x.Subtitles,
+				ClosedCaptions:   x.ClosedCaptions,
+			})
+		}
+	}
+
+	// Sort the variant ladder from the highest to the lowest.
+	slices.SortStableFunc(info.Variants, func(a, b VariantInfo) int { return cmp.Compare(b.Bandwidth, a.Bandwidth) })
+	slices.SortStableFunc(info.IFrames, func(a, b VariantInfo) int { return cmp.Compare(b.Bandwidth, a.Bandwidth) })
+	return info
+}
+
+func (info *Info) addRendition(m playlist.XMedia) {
+	index := slices.IndexFunc(info.Groups, func(g RenditionInfo) bool {
+		return g.Type == m.Type && g.GroupId == m.GroupId
+	})
+	if index < 0 {
+		index = len(info.Groups)
+		info.Groups = append(info.Groups, RenditionInfo{Type: m.Type, GroupId: m.GroupId})
+	}
+
+	group := &info.Groups[index]
+	group.Renditions = append(group.Renditions, Rendition{
+		Name:       m.Name,
+		Language:   m.Language,
+		Channels:   m.Channels,
+		Default:    m.Default,
+		AutoSelect: m.AutoSelect,
+		Forced:     m.Forced,
+		URI:        m.URI,
+	})
+}
+
+// mediaInfo returns the summary of the media playlist.
+func mediaInfo(pl playlist.MediaPlayList) *MediaInfo {
+	info := &MediaInfo{
+		PlayListType:   pl.PlayListType,
+		Live:           !pl.EndList && pl.PlayListType != playlist.MediaPlayListTypeVOD,
+		IFrameOnly:     pl.IFrameOnly,
+		Version:        pl.Version,
+		MediaSequence:  pl.MediaSequence,
+		TargetDuration: pl.TargetDuration,
+		Segments:       len(pl.Segments),
+		TotalDuration:  pl.TotalDuration(),
+	}
+
+	version := pl.Version
+	pl.Version = 0
+	info.RequiredVersion = pl.MinVersion()
+	pl.Version = version
+
+	for i, seg := range pl.Segments {
+		if i == 0 {
+			info.MinSegmentDuration = seg.Duration
+			info.MaxSegmentDuration = seg.Duration
+		} else {
+			info.MinSegmentDuration = min(info.MinSegmentDuration, seg.Duration)
+			info.MaxSegmentDuration = max(info.MaxSegmentDuration, seg.Duration)
+		}
+
+		if seg.Discontinuity {
+			info.Discontinuities++
+		}
+
+		for _, key := range seg.Keys {
+			if key.Method != playlist.XKeyMethodNone && !slices.Contains(info.Encryptions, key.Method) {
+				info.Encryptions = append(info.Encryptions, key.Method)
+			}
+		}
+	}
+	slices.Sort(info.Encryptions)
+
+	if n := len(pl.Segments); n > 0 {
+		info.AvgSegmentDuration = info.TotalDuration / float64(n)
+
+		// The program date time of each media segment has been calculated
+		// by the preceding or following one when parsing.
+		if first, last := pl.Segments[0], pl.Segments[n-1]; !first.ProgramDateTime.IsZero() {
+			start := first.ProgramDateTime
+			end := last.ProgramDateTime.Add(time.Duration(last.Duration * float64(time.Second)))
+			info.ProgramDateTimeStart, info.ProgramDateTimeEnd = &start, &end
+		}
+	}
+
+	return info
+}
+
+func resolution(r playlist.XResolution) string {
+	if r.IsZero() {
+		return ""
+	}
+	return r.String()
+}
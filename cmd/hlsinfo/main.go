@@ -0,0 +1,355 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command hlsinfo summarizes a HLS presentation by the master or media
+// playlist, which may be a local file, "-" for the standard input
+// or a http(s) url.
+//
+// Usage:
+//
+//	hlsinfo [flags] PLAYLIST
+//
+// For the master playlist, it prints the variant ladder, the I-frame streams
+// and the renditions per group. And the media playlists referenced by it
+// are also loaded and summarized unless -media=false.
+//
+// The output format may be "table" or "json".
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xgfone/go-hls/cmd/internal/loader"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// maxConcurrency is the maximum number of the media playlists
+// loaded concurrently.
+const maxConcurrency = 8
+
+var (
+	format  = flag.String("format", "table", "The output format, such as table or json.")
+	media   = flag.Bool("media", true, "If true, summarize the media playlists referenced by the master playlist.")
+	timeout = flag.Duration("timeout", 10*time.Second, "The timeout to load the playlist by http.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] PLAYLIST\n\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	var output func(io.Writer, Info) error
+	switch *format {
+	case "table":
+		output = outputTable
+	case "json":
+		output = outputJSON
+	}
+
+	if output == nil || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	info, err := inspect(ctx, flag.Arg(0))
+	if err == nil {
+		err = output(os.Stdout, info)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func inspect(ctx context.Context, path string) (info Info, err error) {
+	pl, err := load(ctx, path)
+	if err != nil {
+		return
+	}
+
+	switch v := pl.(type) {
+	case playlist.MediaPlayList:
+		info = Info{Path: path, Type: v.Type(), Version: v.Version, Media: mediaInfo(v)}
+		info.RequiredVersion = info.Media.RequiredVersion
+
+	case playlist.MasterPlayList:
+		info = masterInfo(path, v)
+		if *media {
+			loadMedias(ctx, path, &info)
+		}
+	}
+	return
+}
+
+func load(ctx context.Context, path string) (playlist.PlayList, error) {
+	if loader.IsURL(path) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	pl, err := loader.LoadPlayList(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to load playlist '%s': %w", path, err)
+	}
+	return pl, nil
+}
+
+type mediaResult struct {
+	Info *MediaInfo
+	Err  error
+}
+
+// loadMedias concurrently loads and summarizes the media playlists
+// referenced by the master playlist, which are deduplicated by the path.
+func loadMedias(ctx context.Context, base string, info *Info) {
+	results := make(map[string]*mediaResult)
+	get := func(uri string, set func(*MediaInfo, string)) func() {
+		path, err := loader.Resolve(base, uri)
+		if err != nil {
+			return func() { set(nil, err.Error()) }
+		}
+
+		result, ok := results[path]
+		if !ok {
+			result = new(mediaResult)
+			results[path] = result
+		}
+
+		return func() {
+			if result.Err != nil {
+				set(nil, result.Err.Error())
+			} else {
+				set(result.Info, "")
+			}
+		}
+	}
+
+	var sets []func()
+	for i := range info.Variants {
+		v := &info.Variants[i]
+		sets = append(sets, get(v.URI, func(m *MediaInfo, e string) { v.Media, v.Error = m, e }))
+	}
+	for i := range info.IFrames {
+		v := &info.IFrames[i]
+		sets = append(sets, get(v.URI, func(m *MediaInfo, e string) { v.Media, v.Error = m, e }))
+	}
+	for i := range info.Groups {
+		for j := range info.Groups[i].Renditions {
+			if r := &info.Groups[i].Renditions[j]; r.URI != "" {
+				sets = append(sets, get(r.URI, func(m *MediaInfo, e string) { r.Media, r.Error = m, e }))
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrency)
+	for path, result := range results {
+		wg.Add(1)
+		go func(path string, result *mediaResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			pl, err := load(ctx, path)
+			if err != nil {
+				result.Err = err
+			} else if v, ok := pl.(playlist.MediaPlayList); ok {
+				result.Info = mediaInfo(v)
+			} else {
+				result.Err = errors.New("expect a media playlist, but got a master playlist")
+			}
+		}(path, result)
+	}
+	wg.Wait()
+
+	for _, set := range sets {
+		set()
+	}
+}
+
+func outputJSON(w io.Writer, info Info) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(info)
+}
+
+func outputTable(w io.Writer, info Info) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s playlist: %s\n", info.Type, info.Path)
+	if info.Media != nil {
+		writeMedia(tw, info.Media)
+		return tw.Flush()
+	}
+	fmt.Fprintf(tw, "Version:\t%s\n", version(info.Version, info.RequiredVersion))
+
+	if len(info.Variants) > 0 {
+		fmt.Fprintln(tw, "\nVariants:")
+		fmt.Fprintln(tw, "BANDWIDTH\tAVERAGE\tRESOLUTION\tCODECS\tFRAME-RATE\tHDCP\tGROUPS\tSEGMENTS\tDURATION\tURI")
+		for _, v := range info.Variants {
+			frameRate := ""
+			if v.FrameRate > 0 {
+				frameRate = strconv.FormatFloat(v.FrameRate, 'f', -1, 64)
+			}
+
+			var groups []string
+			for _, g := range [...]string{v.Audio, v.Video, v.Subtitles, v.ClosedCaptions} {
+				if g != "" {
+					groups = append(groups, g)
+				}
+			}
+
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.Bandwidth, uintString(v.AverageBandwidth),
+				dash(v.Resolution), dash(v.Codecs), dash(frameRate), dash(v.HDCP),
+				dash(strings.Join(groups, ",")), mediaColumns(v.Media, v.Error), v.URI)
+		}
+	}
+
+	if len(info.IFrames) > 0 {
+		fmt.Fprintln(tw, "\nI-Frame Streams:")
+		fmt.Fprintln(tw, "BANDWIDTH\tAVERAGE\tRESOLUTION\tCODECS\tHDCP\tSEGMENTS\tDURATION\tURI")
+		for _, v := range info.IFrames {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", v.Bandwidth, uintString(v.AverageBandwidth),
+				dash(v.Resolution), dash(v.Codecs), dash(v.HDCP), mediaColumns(v.Media, v.Error), v.URI)
+		}
+	}
+
+	if len(info.Groups) > 0 {
+		fmt.Fprintln(tw, "\nRenditions:")
+		fmt.Fprintln(tw, "TYPE\tGROUP-ID\tNAME\tLANGUAGE\tCHANNELS\tFLAGS\tSEGMENTS\tDURATION\tURI")
+		for _, g := range info.Groups {
+			for _, r := range g.Renditions {
+				var flags []string
+				if r.Default {
+					flags = append(flags, "default")
+				}
+				if r.AutoSelect {
+					flags = append(flags, "autoselect")
+				}
+				if r.Forced {
+					flags = append(flags, "forced")
+				}
+
+				columns := "-\t-"
+				if r.URI != "" {
+					columns = mediaColumns(r.Media, r.Error)
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", g.Type, g.GroupId, r.Name,
+					dash(r.Language), dash(r.Channels), dash(strings.Join(flags, ",")), columns, dash(r.URI))
+			}
+		}
+	}
+
+	// Print the details of the media playlists of the variant streams.
+	printed := make(map[string]struct{}, len(info.Variants))
+	for _, v := range info.Variants {
+		if _, ok := printed[v.URI]; ok {
+			continue
+		}
+		printed[v.URI] = struct{}{}
+
+		if v.Media != nil {
+			fmt.Fprintf(tw, "\nMedia playlist: %s\n", v.URI)
+			writeMedia(tw, v.Media)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeMedia(w io.Writer, m *MediaInfo) {
+	_type := m.PlayListType
+	switch {
+	case m.Live && _type == "":
+		_type = "LIVE"
+	case m.Live:
+		_type += " (live)"
+	case _type == "":
+		_type = "-"
+	}
+	if m.IFrameOnly {
+		_type += ", I-frames only"
+	}
+
+	fmt.Fprintf(w, "Playlist type:\t%s\n", _type)
+	fmt.Fprintf(w, "Version:\t%s\n", version(m.Version, m.RequiredVersion))
+	fmt.Fprintf(w, "Media sequence:\t%d\n", m.MediaSequence)
+	fmt.Fprintf(w, "Segments:\t%d\n", m.Segments)
+	fmt.Fprintf(w, "Total duration:\t%s\n", seconds(m.TotalDuration))
+	fmt.Fprintf(w, "Target duration:\t%ds\n", m.TargetDuration)
+	fmt.Fprintf(w, "Segment duration:\tmin %s, max %s, avg %s\n",
+		seconds(m.MinSegmentDuration), seconds(m.MaxSegmentDuration), seconds(m.AvgSegmentDuration))
+	fmt.Fprintf(w, "Encryption:\t%s\n", dash(strings.Join(m.Encryptions, ", ")))
+	fmt.Fprintf(w, "Discontinuities:\t%d\n", m.Discontinuities)
+	if m.ProgramDateTimeStart != nil {
+		fmt.Fprintf(w, "Program date time:\t%s ~ %s\n",
+			m.ProgramDateTimeStart.Format(time.RFC3339Nano), m.ProgramDateTimeEnd.Format(time.RFC3339Nano))
+	}
+}
+
+func mediaColumns(m *MediaInfo, err string) string {
+	switch {
+	case err != "":
+		return "error\t" + err
+	case m == nil:
+		return "-\t-"
+	default:
+		return fmt.Sprintf("%d\t%s", m.Segments, seconds(m.TotalDuration))
+	}
+}
+
+func version(version, required uint64) string {
+	if version == 0 {
+		return fmt.Sprintf("- (required %d)", required)
+	}
+	return fmt.Sprintf("%d (required %d)", version, required)
+}
+
+func seconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64) + "s"
+}
+
+func uintString(v uint64) string {
+	if v == 0 {
+		return "-"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,301 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command hlslint checks the HLS playlists, which may be the local files,
+// "-" for the standard input or the http(s) urls, and prints the findings.
+//
+// Usage:
+//
+//	hlslint [flags] PLAYLIST...
+//
+// The output format may be "text", "json" or "junit" (JUnit XML).
+// If -follow is set, the media playlists referenced by the master playlist
+// are also checked.
+//
+// The exit code is 2 if there are errors, 1 if there are warnings, or 0.
+// And it is 3 for the invalid usage, such as an undefined flag.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/xgfone/go-hls/cmd/internal/loader"
+	"github.com/xgfone/go-hls/lint"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+var (
+	format      = flag.String("format", "text", "The output format, such as text, json or junit.")
+	follow      = flag.Bool("follow", false, "If true, check the media playlists referenced by the master playlist.")
+	minSeverity = flag.String("min-severity", "info", "The minimum severity of the findings to be printed, such as info, warning or error.")
+	timeout     = flag.Duration("timeout", 10*time.Second, "The timeout to load the playlist by http.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] PLAYLIST...\n\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+// Result is the findings of a playlist.
+type Result struct {
+	Path     string
+	Findings lint.Findings
+}
+
+func main() {
+	// Exit with the usage code 3 instead of 2 for the invalid flags,
+	// which is reserved for the errors found in the playlists.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.Usage = usage
+	if err := flag.CommandLine.Parse(os.Args[1:]); errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(3)
+	}
+
+	severity, err := lint.ParseSeverity(*minSeverity)
+	if err != nil || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(3)
+	}
+
+	var output func(io.Writer, []Result) error
+	switch *format {
+	case "text":
+		output = outputText
+	case "json":
+		output = outputJSON
+	case "junit":
+		output = outputJUnit
+	default:
+		flag.Usage()
+		os.Exit(3)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := checker{seen: make(map[string]struct{})}
+	for _, path := range flag.Args() {
+		c.check(ctx, path, "")
+	}
+
+	worst := lint.Severity(-1)
+	results := make([]Result, 0, len(c.results))
+	for _, result := range c.results {
+		worst = max(worst, result.Findings.Max())
+
+		var findings lint.Findings
+		for _, f := range result.Findings {
+			if f.Severity >= severity {
+				findings = append(findings, f)
+			}
+		}
+		results = append(results, Result{Path: result.Path, Findings: findings})
+	}
+
+	if err := output(os.Stdout, results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(3)
+	}
+
+	switch worst {
+	case lint.SeverityError:
+		os.Exit(2)
+	case lint.SeverityWarning:
+		os.Exit(1)
+	}
+}
+
+type checker struct {
+	seen    map[string]struct{}
+	results []Result
+}
+
+// check checks the playlist at path, and expects the playlist type
+// if it is not empty, such as "iframe" for the I-frame playlist.
+func (c *checker) check(ctx context.Context, path, expect string) {
+	if _, ok := c.seen[path]; ok {
+		return
+	}
+	c.seen[path] = struct{}{}
+
+	data, err := c.load(ctx, path)
+	if err != nil {
+		c.add(path, lint.Findings{{Severity: lint.SeverityError, Rule: "load", Message: err.Error()}})
+		return
+	}
+
+	pl, findings := lint.Parse(bytes.NewReader(data))
+	switch v := pl.(type) {
+	case playlist.MediaPlayList:
+		if expect == "iframe" && !v.IFrameOnly {
+			findings = append(findings, lint.Finding{Severity: lint.SeverityError, Rule: "iframe",
+				Message: "the I-frame playlist misses EXT-X-I-FRAMES-ONLY"})
+		}
+
+	case playlist.MasterPlayList:
+		if expect != "" {
+			findings = append(findings, lint.Finding{Severity: lint.SeverityError, Rule: "syntax",
+				Message: "expect a media playlist, but got a master playlist"})
+		}
+	}
+
+	c.add(path, findings)
+	if master, ok := pl.(playlist.MasterPlayList); ok && *follow {
+		c.follow(ctx, path, master)
+	}
+}
+
+func (c *checker) follow(ctx context.Context, base string, master playlist.MasterPlayList) {
+	check := func(uri, expect string) {
+		path, err := loader.Resolve(base, uri)
+		if err != nil {
+			c.add(uri, lint.Findings{{Severity: lint.SeverityError, Rule: "load", Message: err.Error()}})
+			return
+		}
+		c.check(ctx, path, expect)
+	}
+
+	for _, s := range master.Streams {
+		for _, m := range s.Medias {
+			if m.URI != "" {
+				check(m.URI, "media")
+			}
+		}
+		for _, iframe := range s.IFrameStreams {
+			check(iframe.URI, "iframe")
+		}
+		if s.Stream.URI != "" {
+			check(s.Stream.URI, "media")
+		}
+	}
+}
+
+func (c *checker) load(ctx context.Context, path string) ([]byte, error) {
+	if loader.IsURL(path) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	return loader.Load(ctx, path)
+}
+
+func (c *checker) add(path string, findings lint.Findings) {
+	c.results = append(c.results, Result{Path: path, Findings: findings.WithURI(path)})
+}
+
+func outputText(w io.Writer, results []Result) error {
+	counts := make(map[lint.Severity]int, 3)
+	for _, result := range results {
+		for _, f := range result.Findings {
+			counts[f.Severity]++
+			if _, err := fmt.Fprintln(w, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d playlists: %d errors, %d warnings, %d infos\n", len(results),
+		counts[lint.SeverityError], counts[lint.SeverityWarning], counts[lint.SeverityInfo])
+	return err
+}
+
+func outputJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func outputJUnit(w io.Writer, results []Result) error {
+	suite := junitTestSuite{Name: "hlslint", Tests: len(results)}
+	for _, result := range results {
+		tcase := junitTestCase{Name: result.Path, ClassName: "hlslint"}
+
+		var failures, infos lint.Findings
+		for _, f := range result.Findings {
+			if f.Severity >= lint.SeverityWarning {
+				failures = append(failures, f)
+			} else {
+				infos = append(infos, f)
+			}
+		}
+
+		if len(failures) > 0 {
+			suite.Failures++
+			tcase.Failure = &junitFailure{
+				Type:    failures.Max().String(),
+				Message: fmt.Sprintf("%d problem(s), the first: %s", len(failures), failures[0].Message),
+				Text:    failures.String(),
+			}
+		}
+		tcase.SystemOut = infos.String()
+		suite.Cases = append(suite.Cases, tcase)
+	}
+
+	suites := junitTestSuites{
+		Suites:   []junitTestSuite{suite},
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loader loads the playlists and the resources for the commands
+// from the local files, the standard input or the http(s) urls.
+package loader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xgfone/go-hls/client"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Stdin is the path to read from the standard input.
+const Stdin = "-"
+
+// IsURL reports whether the path is a http(s) url.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Load reads all the data from the path, which is a local file,
+// Stdin or a http(s) url downloaded by client.Get.
+func Load(ctx context.Context, path string, options ...client.Option) (data []byte, err error) {
+	switch {
+	case path == Stdin:
+		return io.ReadAll(os.Stdin)
+
+	case IsURL(path):
+		err = client.Get(ctx, path, func(r *http.Response) (err error) {
+			data, err = io.ReadAll(r.Body)
+			return
+		}, options...)
+		return
+
+	default:
+		return os.ReadFile(path)
+	}
+}
+
+// LoadPlayList loads the data from the path by Load,
+// and parses it as the master or media playlist.
+func LoadPlayList(ctx context.Context, path string, options ...client.Option) (playlist.PlayList, error) {
+	data, err := Load(ctx, path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return playlist.Parse(bytes.NewReader(data))
+}
+
+// Resolve resolves the uri in the playlist loaded from the path base.
+//
+// If base is a url, resolve it as the url. Or, resolve it as the local
+// file path relative to the directory of base if it is not a url.
+func Resolve(base, uri string) (string, error) {
+	if IsURL(base) || IsURL(uri) {
+		return client.ResolveURL(base, uri)
+	}
+
+	if filepath.IsAbs(uri) || base == Stdin {
+		return uri, nil
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(uri)), nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fmp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var errInvalidBox = errors.New("invalid box")
+
+// Box is an ISO BMFF box.
+type Box struct {
+	Type   string // The four-character code, such as "moov".
+	Offset int    // The offset of the box in the data passed to ReadBoxes.
+	Header int    // The size of the box header.
+	Data   []byte // The whole box, including the header.
+}
+
+// Payload returns the payload of the box, not including the header.
+func (b Box) Payload() []byte { return b.Data[b.Header:] }
+
+// Children parses the payload of the container box as the child boxes.
+func (b Box) Children() ([]Box, error) {
+	return readBoxes(b.Payload(), b.Offset+b.Header)
+}
+
+// ChildrenAt is the same as Children, but skips the first skip bytes
+// of the payload, such as the fields of "stsd" or the sample entries.
+func (b Box) ChildrenAt(skip int) ([]Box, error) {
+	payload := b.Payload()
+	if skip > len(payload) {
+		return nil, fmt.Errorf("%w: '%s' is too short", errInvalidBox, b.Type)
+	}
+	return readBoxes(payload[skip:], b.Offset+b.Header+skip)
+}
+
+// FullBox parses the payload of the full box, and returns the version,
+// the flags and the remaining data.
+func (b Box) FullBox() (version uint8, flags uint32, data []byte, err error) {
+	payload := b.Payload()
+	if len(payload) < 4 {
+		err = fmt.Errorf("%w: '%s' is too short", errInvalidBox, b.Type)
+		return
+	}
+
+	version = payload[0]
+	flags = uint32(payload[1])<<16 | uint32(payload[2])<<8 | uint32(payload[3])
+	return version, flags, payload[4:], nil
+}
+
+// ReadBoxes parses the data as a sequence of the boxes.
+func ReadBoxes(data []byte) ([]Box, error) { return readBoxes(data, 0) }
+
+func readBoxes(data []byte, base int) (boxes []Box, err error) {
+	for offset := 0; offset < len(data); {
+		var box Box
+		if box, err = readBox(data[offset:]); err != nil {
+			return nil, fmt.Errorf("offset %d: %w", base+offset, err)
+		}
+
+		box.Offset = base + offset
+		boxes = append(boxes, box)
+		offset += len(box.Data)
+	}
+	return
+}
+
+func readBox(data []byte) (box Box, err error) {
+	if len(data) < 8 {
+		return box, fmt.Errorf("%w: too short header", errInvalidBox)
+	}
+
+	size := uint64(binary.BigEndian.Uint32(data[:4]))
+	box.Type, box.Header = string(data[4:8]), 8
+
+	switch size {
+	case 0: // Extend to the end.
+		size = uint64(len(data))
+
+	case 1: // Large size
+		if len(data) < 16 {
+			return box, fmt.Errorf("%w: too short large size header", errInvalidBox)
+		}
+		size, box.Header = binary.BigEndian.Uint64(data[8:16]), 16
+	}
+
+	if box.Type == "uuid" {
+		box.Header += 16
+	}
+
+	if size < uint64(box.Header) || size > uint64(len(data)) {
+		return box, fmt.Errorf("%w: '%s' with size %d", errInvalidBox, box.Type, size)
+	}
+
+	box.Data = data[:size]
+	return
+}
+
+// FindBox finds the first box by the path of the box types,
+// such as FindBox(boxes, "moov", "mvex", "trex").
+//
+// The container boxes in the path must be pure, that's,
+// the payload only contains the child boxes.
+func FindBox(boxes []Box, path ...string) (box Box, ok bool) {
+	for i, _type := range path {
+		if i > 0 {
+			var err error
+			if boxes, err = box.Children(); err != nil {
+				return Box{}, false
+			}
+		}
+
+		if box, ok = findBox(boxes, _type); !ok {
+			return
+		}
+	}
+	return
+}
+
+// FindBoxes is the same as FindBox, but returns all the matched boxes
+// of the last type in the path.
+func FindBoxes(boxes []Box, path ...string) (matches []Box) {
+	if len(path) == 0 {
+		return
+	}
+
+	if len(path) > 1 {
+		parent, ok := FindBox(boxes, path[:len(path)-1]...)
+		if !ok {
+			return
+		}
+
+		var err error
+		if boxes, err = parent.Children(); err != nil {
+			return
+		}
+	}
+
+	for _, box := range boxes {
+		if box.Type == path[len(path)-1] {
+			matches = append(matches, box)
+		}
+	}
+	return
+}
+
+func findBox(boxes []Box, _type string) (Box, bool) {
+	for _, box := range boxes {
+		if box.Type == _type {
+			return box, true
+		}
+	}
+	return Box{}, false
+}
+
+// AppendBox appends a box with the type and the payloads into dst,
+// and returns the extended buffer.
+func AppendBox(dst []byte, _type string, payloads ...[]byte) []byte {
+	size := 8
+	for _, p := range payloads {
+		size += len(p)
+	}
+
+	dst = binary.BigEndian.AppendUint32(dst, uint32(size))
+	dst = append(dst, _type[:4]...)
+	for _, p := range payloads {
+		dst = append(dst, p...)
+	}
+	return dst
+}
+
+// AppendFullBox is the same as AppendBox, but appends a full box
+// with the version and flags.
+func AppendFullBox(dst []byte, _type string, version uint8, flags uint32, payloads ...[]byte) []byte {
+	header := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return AppendBox(dst, _type, append([][]byte{header}, payloads...)...)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fmp4
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func TestReadBoxes(t *testing.T) {
+	data := AppendBox(nil, "ftyp", []byte("iso6"), u32(0))
+	data = AppendBox(data, "moov", AppendBox(nil, "mvex",
+		AppendFullBox(nil, "trex", 0, 0, u32(1), u32(1), u32(1000), u32(188), u32(0x10000)),
+	))
+
+	// Large size
+	data = append(data, 0, 0, 0, 1, 'f', 'r', 'e', 'e', 0, 0, 0, 0, 0, 0, 0, 18, 1, 2)
+
+	boxes, err := ReadBoxes(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(boxes) != 3 {
+		t.Fatalf("expect %d boxes, but got %d", 3, len(boxes))
+	}
+
+	if box := boxes[2]; box.Type != "free" || box.Header != 16 || len(box.Payload()) != 2 {
+		t.Errorf("unexpected large box: %+v", box)
+	}
+
+	trexs, err := ParseTrexs(boxes)
+	if err != nil {
+		t.Fatal(err)
+	} else if trex := trexs[1]; trex.DefaultSampleDuration != 1000 || trex.DefaultSampleSize != 188 {
+		t.Errorf("unexpected trex: %+v", trex)
+	}
+
+	if box, ok := FindBox(boxes, "moov", "mvex", "trex"); !ok {
+		t.Errorf("not found the box 'trex'")
+	} else if box.Offset != 16+8+8 {
+		t.Errorf("expect the offset %d, but got %d", 16+8+8, box.Offset)
+	}
+
+	if _, err := ReadBoxes(data[:len(data)-1]); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
+
+func TestParseMovieFragment(t *testing.T) {
+	trexs := map[uint32]TrackExtends{1: {TrackID: 1, DefaultSampleDuration: 1000, DefaultSampleSize: 10}}
+
+	moof := func(offset uint32) []byte {
+		return AppendBox(nil, "moof",
+			AppendFullBox(nil, "mfhd", 0, 0, u32(1)),
+			AppendBox(nil, "traf",
+				AppendFullBox(nil, "tfhd", 0, TfhdDefaultBaseIsMoof, u32(1)),
+				AppendFullBox(nil, "trun", 0, TrunDataOffsetPresent|TrunFirstSampleFlagsPresent,
+					u32(2), u32(offset), u32(0)),
+				AppendFullBox(nil, "trun", 0, TrunSampleSizePresent|TrunSampleFlagsPresent,
+					u32(2), u32(20), u32(0x10000), u32(30), u32(0x10000)),
+			),
+			AppendBox(nil, "traf",
+				AppendFullBox(nil, "tfhd", 0, TfhdDefaultSampleDurationPresent|TfhdDefaultSampleSizePresent,
+					u32(2), u32(512), u32(5)),
+				AppendFullBox(nil, "trun", 0, 0, u32(3)),
+			),
+		)
+	}
+
+	size := uint32(len(moof(0)))
+	data := AppendBox(moof(size+8), "mdat", make([]byte, 10+10+20+30+5*3))
+	boxes, err := ReadBoxes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trafs, err := ParseMovieFragment(boxes[0], trexs)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(trafs) != 2 {
+		t.Fatalf("expect %d track fragments, but got %d", 2, len(trafs))
+	}
+
+	expects := []Sample{
+		{Offset: int(size) + 8, Size: 10, Duration: 1000},
+		{Offset: int(size) + 18, Size: 10, Duration: 1000},
+		{Offset: int(size) + 28, Size: 20, Duration: 1000, Flags: 0x10000},
+		{Offset: int(size) + 48, Size: 30, Duration: 1000, Flags: 0x10000},
+		{Offset: int(size) + 78, Size: 5, Duration: 512},
+		{Offset: int(size) + 83, Size: 5, Duration: 512},
+		{Offset: int(size) + 88, Size: 5, Duration: 512},
+	}
+
+	samples := append(trafs[0].Samples, trafs[1].Samples...)
+	if len(samples) != len(expects) {
+		t.Fatalf("expect %d samples, but got %d", len(expects), len(samples))
+	}
+
+	for i, s := range samples {
+		if s != expects[i] {
+			t.Errorf("%d: expect sample %+v, but got %+v", i, expects[i], s)
+		}
+	}
+
+	if !samples[0].IsSync() || samples[2].IsSync() {
+		t.Errorf("unexpected sync samples")
+	}
+}
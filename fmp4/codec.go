@@ -0,0 +1,220 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fmp4
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// codecString returns the codec string defined in RFC 6381
+// by the format of the sample entry and the codec configuration box.
+//
+// Return the format itself if the codec configuration is unsupported.
+func codecString(format string, children []Box) string {
+	var codec string
+	switch format {
+	case "avc1", "avc3":
+		if b, ok := findBox(children, "avcC"); ok {
+			codec = avcCodec(format, b.Payload())
+		}
+
+	case "hvc1", "hev1":
+		if b, ok := findBox(children, "hvcC"); ok {
+			codec = hevcCodec(format, b.Payload())
+		}
+
+	case "av01":
+		if b, ok := findBox(children, "av1C"); ok {
+			codec = av1Codec(format, b.Payload())
+		}
+
+	case "vp08", "vp09":
+		if b, ok := findBox(children, "vpcC"); ok {
+			codec = vpxCodec(format, b)
+		}
+
+	case "mp4a":
+		codec = "mp4a.40.2" // Default: AAC-LC
+		if b, ok := findBox(children, "esds"); ok {
+			if c := mp4aCodec(b); c != "" {
+				codec = c
+			}
+		}
+	}
+
+	if codec == "" {
+		codec = format
+	}
+	return codec
+}
+
+// avcCodec returns the codec string, such as "avc1.64001f",
+// from AVCDecoderConfigurationRecord.
+func avcCodec(format string, avcC []byte) string {
+	if len(avcC) < 4 {
+		return ""
+	}
+	return fmt.Sprintf("%s.%02x%02x%02x", format, avcC[1], avcC[2], avcC[3])
+}
+
+// hevcCodec returns the codec string, such as "hvc1.1.6.L93.B0",
+// from HEVCDecoderConfigurationRecord.
+func hevcCodec(format string, hvcC []byte) string {
+	if len(hvcC) < 13 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(format)
+	b.WriteByte('.')
+	if space := hvcC[1] >> 6; space > 0 {
+		b.WriteByte('A' + space - 1)
+	}
+	b.WriteString(strconv.Itoa(int(hvcC[1] & 0x1F)))
+
+	// The profile compatibility flags are in the reverse bit order.
+	compat := bits.Reverse32(binary.BigEndian.Uint32(hvcC[2:6]))
+	fmt.Fprintf(&b, ".%X", compat)
+
+	if hvcC[1]&0x20 != 0 {
+		b.WriteString(".H")
+	} else {
+		b.WriteString(".L")
+	}
+	b.WriteString(strconv.Itoa(int(hvcC[12])))
+
+	// The trailing zero bytes of the constraint flags may be omitted.
+	constraints := hvcC[6:12]
+	for len(constraints) > 0 && constraints[len(constraints)-1] == 0 {
+		constraints = constraints[:len(constraints)-1]
+	}
+	for _, c := range constraints {
+		fmt.Fprintf(&b, ".%X", c)
+	}
+
+	return b.String()
+}
+
+// av1Codec returns the codec string, such as "av01.0.04M.08",
+// from AV1CodecConfigurationRecord.
+func av1Codec(format string, av1C []byte) string {
+	if len(av1C) < 3 {
+		return ""
+	}
+
+	profile := av1C[1] >> 5
+	level := av1C[1] & 0x1F
+	tier := byte('M')
+	if av1C[2]&0x80 != 0 {
+		tier = 'H'
+	}
+
+	depth := 8
+	if av1C[2]&0x40 != 0 {
+		if depth = 10; profile == 2 && av1C[2]&0x20 != 0 {
+			depth = 12
+		}
+	}
+
+	return fmt.Sprintf("%s.%d.%02d%c.%02d", format, profile, level, tier, depth)
+}
+
+// vpxCodec returns the codec string, such as "vp09.00.10.08",
+// from the box "vpcC".
+func vpxCodec(format string, vpcC Box) string {
+	_, _, data, err := vpcC.FullBox()
+	if err != nil || len(data) < 3 {
+		return ""
+	}
+	return fmt.Sprintf("%s.%02d.%02d.%02d", format, data[0], data[1], data[2]>>4)
+}
+
+// mp4aCodec returns the codec string, such as "mp4a.40.2",
+// from the elementary stream descriptor box "esds".
+func mp4aCodec(esds Box) string {
+	_, _, data, err := esds.FullBox()
+	if err != nil {
+		return ""
+	}
+
+	// ES_Descriptor
+	data, ok := readDescriptor(data, 0x03)
+	if !ok || len(data) < 3 {
+		return ""
+	}
+
+	flags := data[2]
+	data = data[3:]
+	if flags&0x80 != 0 { // streamDependenceFlag
+		data = data[min(2, len(data)):]
+	}
+	if flags&0x40 != 0 && len(data) > 0 { // URL_Flag
+		data = data[min(1+int(data[0]), len(data)):]
+	}
+	if flags&0x20 != 0 { // OCRstreamFlag
+		data = data[min(2, len(data)):]
+	}
+
+	// DecoderConfigDescriptor
+	data, ok = readDescriptor(data, 0x04)
+	if !ok || len(data) < 13 {
+		return ""
+	}
+
+	oti := data[0]
+	if oti != 0x40 {
+		return fmt.Sprintf("mp4a.%02X", oti)
+	}
+
+	// DecoderSpecificInfo, that's, AudioSpecificConfig.
+	data, ok = readDescriptor(data[13:], 0x05)
+	if !ok || len(data) < 1 {
+		return "mp4a.40"
+	}
+
+	aot := data[0] >> 3
+	if aot == 31 && len(data) >= 2 {
+		aot = 32 + ((data[0]&0x07)<<3 | data[1]>>5)
+	}
+	return fmt.Sprintf("mp4a.40.%d", aot)
+}
+
+// readDescriptor reads the MPEG-4 descriptor with the tag, and returns
+// its payload.
+func readDescriptor(data []byte, tag byte) ([]byte, bool) {
+	if len(data) < 2 || data[0] != tag {
+		return nil, false
+	}
+
+	var size int
+	data = data[1:]
+	for i := 0; i < 4 && len(data) > 0; i++ {
+		b := data[0]
+		data = data[1:]
+		size = size<<7 | int(b&0x7F)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if size > len(data) {
+		return nil, false
+	}
+	return data[:size], true
+}
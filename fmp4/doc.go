@@ -0,0 +1,24 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fmp4 provides some functions to parse the fragmented MP4,
+// that's, ISO Base Media File Format defined in ISO/IEC 14496-12,
+// such as the init sections and the media segments of HLS.
+//
+// ParseMovie extracts the tracks from the init section, including
+// the timescales and the codec strings used by the CODECS attribute,
+// and Movie.InspectSegment reports the base media decode time,
+// the sample count and the duration of each track fragment,
+// which can be used to check the EXTINF durations.
+package fmp4
@@ -0,0 +1,410 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fmp4
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Some flags of the track fragment header box "tfhd".
+const (
+	TfhdBaseDataOffsetPresent         = 0x000001
+	TfhdSampleDescriptionIndexPresent = 0x000002
+	TfhdDefaultSampleDurationPresent  = 0x000008
+	TfhdDefaultSampleSizePresent      = 0x000010
+	TfhdDefaultSampleFlagsPresent     = 0x000020
+	TfhdDurationIsEmpty               = 0x010000
+	TfhdDefaultBaseIsMoof             = 0x020000
+)
+
+// Some flags of the track fragment run box "trun".
+const (
+	TrunDataOffsetPresent                  = 0x000001
+	TrunFirstSampleFlagsPresent            = 0x000004
+	TrunSampleDurationPresent              = 0x000100
+	TrunSampleSizePresent                  = 0x000200
+	TrunSampleFlagsPresent                 = 0x000400
+	TrunSampleCompositionTimeOffsetPresent = 0x000800
+)
+
+// maxSampleCount is the maximum number of the samples in a track run.
+const maxSampleCount = 1 << 20
+
+// TrackExtends is the track extends box "trex" in "moov/mvex",
+// which sets up the default values used by the movie fragments.
+type TrackExtends struct {
+	TrackID                       uint32
+	DefaultSampleDescriptionIndex uint32
+	DefaultSampleDuration         uint32
+	DefaultSampleSize             uint32
+	DefaultSampleFlags            uint32
+}
+
+// ParseTrex parses the track extends box "trex".
+func ParseTrex(b Box) (trex TrackExtends, err error) {
+	_, _, data, err := b.FullBox()
+	if err != nil {
+		return
+	} else if len(data) < 20 {
+		return trex, fmt.Errorf("%w: 'trex' is too short", errInvalidBox)
+	}
+
+	trex.TrackID = binary.BigEndian.Uint32(data[0:4])
+	trex.DefaultSampleDescriptionIndex = binary.BigEndian.Uint32(data[4:8])
+	trex.DefaultSampleDuration = binary.BigEndian.Uint32(data[8:12])
+	trex.DefaultSampleSize = binary.BigEndian.Uint32(data[12:16])
+	trex.DefaultSampleFlags = binary.BigEndian.Uint32(data[16:20])
+	return
+}
+
+// ParseTrexs parses all the track extends boxes in "moov/mvex" of the init
+// section, the key of which is the track id.
+func ParseTrexs(init []Box) (trexs map[uint32]TrackExtends, err error) {
+	boxes := FindBoxes(init, "moov", "mvex", "trex")
+	trexs = make(map[uint32]TrackExtends, len(boxes))
+	for _, box := range boxes {
+		trex, err := ParseTrex(box)
+		if err != nil {
+			return nil, err
+		}
+		trexs[trex.TrackID] = trex
+	}
+	return
+}
+
+// TrackFragmentHeader is the track fragment header box "tfhd".
+type TrackFragmentHeader struct {
+	Flags   uint32
+	TrackID uint32
+
+	BaseDataOffset         uint64
+	SampleDescriptionIndex uint32
+	DefaultSampleDuration  uint32
+	DefaultSampleSize      uint32
+	DefaultSampleFlags     uint32
+}
+
+// ParseTfhd parses the track fragment header box "tfhd".
+func ParseTfhd(b Box) (tfhd TrackFragmentHeader, err error) {
+	_, flags, data, err := b.FullBox()
+	if err != nil {
+		return
+	}
+
+	tfhd.Flags = flags
+	r := reader{data: data}
+	tfhd.TrackID = r.uint32()
+	if tfhd.Flags&TfhdBaseDataOffsetPresent != 0 {
+		tfhd.BaseDataOffset = r.uint64()
+	}
+	if tfhd.Flags&TfhdSampleDescriptionIndexPresent != 0 {
+		tfhd.SampleDescriptionIndex = r.uint32()
+	}
+	if tfhd.Flags&TfhdDefaultSampleDurationPresent != 0 {
+		tfhd.DefaultSampleDuration = r.uint32()
+	}
+	if tfhd.Flags&TfhdDefaultSampleSizePresent != 0 {
+		tfhd.DefaultSampleSize = r.uint32()
+	}
+	if tfhd.Flags&TfhdDefaultSampleFlagsPresent != 0 {
+		tfhd.DefaultSampleFlags = r.uint32()
+	}
+
+	err = r.check("tfhd")
+	return
+}
+
+// TrackRunSample is a sample in the track fragment run box "trun",
+// the absent fields of which are 0.
+type TrackRunSample struct {
+	Duration              uint32
+	Size                  uint32
+	Flags                 uint32
+	CompositionTimeOffset int32
+}
+
+// TrackRun is the track fragment run box "trun".
+type TrackRun struct {
+	Flags            uint32
+	DataOffset       int32
+	FirstSampleFlags uint32
+	Samples          []TrackRunSample
+}
+
+// ParseTrun parses the track fragment run box "trun".
+func ParseTrun(b Box) (trun TrackRun, err error) {
+	_, flags, data, err := b.FullBox()
+	if err != nil {
+		return
+	}
+
+	trun.Flags = flags
+	r := reader{data: data}
+	count := r.uint32()
+	if trun.Flags&TrunDataOffsetPresent != 0 {
+		trun.DataOffset = int32(r.uint32())
+	}
+	if trun.Flags&TrunFirstSampleFlagsPresent != 0 {
+		trun.FirstSampleFlags = r.uint32()
+	}
+
+	var size uint64 // The size of each sample.
+	for _, flag := range []uint32{TrunSampleDurationPresent, TrunSampleSizePresent,
+		TrunSampleFlagsPresent, TrunSampleCompositionTimeOffsetPresent} {
+		if trun.Flags&flag != 0 {
+			size += 4
+		}
+	}
+
+	if r.err != nil || uint64(count)*size > uint64(len(r.data)) || count > maxSampleCount {
+		return trun, fmt.Errorf("%w: invalid 'trun'", errInvalidBox)
+	}
+
+	trun.Samples = make([]TrackRunSample, count)
+	for i := range trun.Samples {
+		s := &trun.Samples[i]
+		if trun.Flags&TrunSampleDurationPresent != 0 {
+			s.Duration = r.uint32()
+		}
+		if trun.Flags&TrunSampleSizePresent != 0 {
+			s.Size = r.uint32()
+		}
+		if trun.Flags&TrunSampleFlagsPresent != 0 {
+			s.Flags = r.uint32()
+		}
+		if trun.Flags&TrunSampleCompositionTimeOffsetPresent != 0 {
+			s.CompositionTimeOffset = int32(r.uint32())
+		}
+	}
+
+	err = r.check("trun")
+	return
+}
+
+// Sample is a sample of a track fragment, the fields of which
+// have been resolved with the default values.
+type Sample struct {
+	Offset                int // The offset of the sample data in the segment.
+	Size                  uint32
+	Duration              uint32
+	Flags                 uint32
+	CompositionTimeOffset int32
+}
+
+// IsSync reports whether the sample is a sync sample, such as IDR,
+// that's, sample_is_non_sync_sample is 0.
+func (s Sample) IsSync() bool { return s.Flags&0x00010000 == 0 }
+
+// TrackFragment is a track fragment box "traf" in the movie fragment.
+type TrackFragment struct {
+	Box     Box // The box "traf".
+	Header  TrackFragmentHeader
+	Runs    []TrackRun
+	Samples []Sample
+
+	// BaseDataOffset is the resolved base data offset in the segment,
+	// which is also used by the box "saio".
+	BaseDataOffset int
+
+	// BaseMediaDecodeTime is the decode time of the first sample
+	// in the box "tfdt", the unit of which is the timescale of the track.
+	BaseMediaDecodeTime uint64
+}
+
+// ParseTfdt parses the track fragment decode time box "tfdt",
+// and returns the base media decode time.
+func ParseTfdt(b Box) (baseMediaDecodeTime uint64, err error) {
+	version, _, data, err := b.FullBox()
+	switch {
+	case err != nil:
+	case version == 1 && len(data) >= 8:
+		baseMediaDecodeTime = binary.BigEndian.Uint64(data[:8])
+	case version == 0 && len(data) >= 4:
+		baseMediaDecodeTime = uint64(binary.BigEndian.Uint32(data[:4]))
+	default:
+		err = fmt.Errorf("%w: invalid 'tfdt'", errInvalidBox)
+	}
+	return
+}
+
+// ParseMovieFragment parses the track fragments in the movie fragment box
+// "moof", and resolves the samples with the default values in trexs,
+// which may be nil.
+func ParseMovieFragment(moof Box, trexs map[uint32]TrackExtends) (trafs []TrackFragment, err error) {
+	boxes, err := moof.Children()
+	if err != nil {
+		return
+	}
+
+	// The end of the data of the preceding track fragment.
+	end := moof.Offset
+	for _, box := range boxes {
+		if box.Type != "traf" {
+			continue
+		}
+
+		traf, err := parseTrackFragment(moof, box, end, trexs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range traf.Samples {
+			end = max(end, s.Offset+int(s.Size))
+		}
+		trafs = append(trafs, traf)
+	}
+
+	return
+}
+
+func parseTrackFragment(moof, box Box, end int, trexs map[uint32]TrackExtends) (traf TrackFragment, err error) {
+	traf.Box = box
+	boxes, err := box.Children()
+	if err != nil {
+		return
+	}
+
+	tfhd, ok := findBox(boxes, "tfhd")
+	if !ok {
+		return traf, fmt.Errorf("%w: missing 'tfhd' in 'traf'", errInvalidBox)
+	} else if traf.Header, err = ParseTfhd(tfhd); err != nil {
+		return
+	}
+
+	if tfdt, ok := findBox(boxes, "tfdt"); ok {
+		if traf.BaseMediaDecodeTime, err = ParseTfdt(tfdt); err != nil {
+			return
+		}
+	}
+
+	h := traf.Header
+	switch {
+	case h.Flags&TfhdBaseDataOffsetPresent != 0:
+		traf.BaseDataOffset = int(h.BaseDataOffset)
+	case h.Flags&TfhdDefaultBaseIsMoof != 0:
+		traf.BaseDataOffset = moof.Offset
+	default:
+		traf.BaseDataOffset = end
+	}
+
+	trex := trexs[h.TrackID]
+	duration, size, flags := trex.DefaultSampleDuration, trex.DefaultSampleSize, trex.DefaultSampleFlags
+	if h.Flags&TfhdDefaultSampleDurationPresent != 0 {
+		duration = h.DefaultSampleDuration
+	}
+	if h.Flags&TfhdDefaultSampleSizePresent != 0 {
+		size = h.DefaultSampleSize
+	}
+	if h.Flags&TfhdDefaultSampleFlagsPresent != 0 {
+		flags = h.DefaultSampleFlags
+	}
+
+	offset := traf.BaseDataOffset
+	for _, box := range boxes {
+		if box.Type != "trun" {
+			continue
+		}
+
+		trun, err := ParseTrun(box)
+		if err != nil {
+			return traf, err
+		}
+
+		if trun.Flags&TrunDataOffsetPresent != 0 {
+			offset = traf.BaseDataOffset + int(trun.DataOffset)
+		}
+
+		for i, s := range trun.Samples {
+			sample := Sample{Offset: offset, Size: size, Duration: duration, Flags: flags,
+				CompositionTimeOffset: s.CompositionTimeOffset}
+
+			if trun.Flags&TrunSampleDurationPresent != 0 {
+				sample.Duration = s.Duration
+			}
+			if trun.Flags&TrunSampleSizePresent != 0 {
+				sample.Size = s.Size
+			}
+			if trun.Flags&TrunSampleFlagsPresent != 0 {
+				sample.Flags = s.Flags
+			} else if i == 0 && trun.Flags&TrunFirstSampleFlagsPresent != 0 {
+				sample.Flags = trun.FirstSampleFlags
+			}
+
+			traf.Samples = append(traf.Samples, sample)
+			offset += int(sample.Size)
+		}
+
+		traf.Runs = append(traf.Runs, trun)
+	}
+
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// reader is a big-endian reader, which records the first error.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	} else if n > len(r.data) {
+		r.err = errInvalidBox
+		return nil
+	}
+
+	data := r.data[:n]
+	r.data = r.data[n:]
+	return data
+}
+
+func (r *reader) uint8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) check(_type string) error {
+	if r.err != nil {
+		return fmt.Errorf("%w: '%s' is too short", errInvalidBox, _type)
+	}
+	return nil
+}
@@ -0,0 +1,468 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fmp4
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Some handler types of the tracks.
+const (
+	HandlerVideo    = "vide"
+	HandlerAudio    = "soun"
+	HandlerSubtitle = "subt"
+	HandlerText     = "text"
+)
+
+// FileType is the file type box "ftyp".
+type FileType struct {
+	MajorBrand       string
+	MinorVersion     uint32
+	CompatibleBrands []string
+}
+
+// ParseFtyp parses the file type box "ftyp" or the segment type box "styp".
+func ParseFtyp(b Box) (ftyp FileType, err error) {
+	payload := b.Payload()
+	if len(payload) < 8 || len(payload)%4 != 0 {
+		return ftyp, fmt.Errorf("%w: invalid '%s'", errInvalidBox, b.Type)
+	}
+
+	ftyp.MajorBrand = string(payload[:4])
+	ftyp.MinorVersion = binary.BigEndian.Uint32(payload[4:8])
+	for payload = payload[8:]; len(payload) > 0; payload = payload[4:] {
+		ftyp.CompatibleBrands = append(ftyp.CompatibleBrands, string(payload[:4]))
+	}
+	return
+}
+
+// Track is the information of a track in the movie box "moov".
+type Track struct {
+	ID          uint32
+	Handler     string // Such as "vide" or "soun".
+	Timescale   uint32
+	Duration    uint64 // The unit is Timescale, which is 0 for the fragmented movie.
+	Language    string
+	SampleEntry string // Such as "avc1", "mp4a" or "encv".
+	Codec       string // The codec string defined in RFC 6381, such as "avc1.64001f".
+
+	// Only for video
+	Width, Height uint16
+
+	// Only for audio
+	Channels   uint16
+	SampleRate uint32
+}
+
+// Movie is the information of the init section parsed from the box "moov".
+type Movie struct {
+	FileType  FileType
+	Timescale uint32
+	Tracks    []Track
+	Trexs     map[uint32]TrackExtends
+}
+
+// ParseMovie parses the init section, that's, the media initialization
+// section referred by EXT-X-MAP.
+func ParseMovie(init []byte) (m Movie, err error) {
+	boxes, err := ReadBoxes(init)
+	if err != nil {
+		return
+	}
+
+	if ftyp, ok := FindBox(boxes, "ftyp"); ok {
+		if m.FileType, err = ParseFtyp(ftyp); err != nil {
+			return
+		}
+	}
+
+	moov, ok := FindBox(boxes, "moov")
+	if !ok {
+		return m, fmt.Errorf("%w: missing 'moov'", errInvalidBox)
+	}
+
+	if mvhd, ok := FindBox(boxes, "moov", "mvhd"); ok {
+		version, _, data, err := mvhd.FullBox()
+		if err != nil {
+			return m, err
+		} else if version == 1 && len(data) >= 20 {
+			m.Timescale = binary.BigEndian.Uint32(data[16:20])
+		} else if version == 0 && len(data) >= 12 {
+			m.Timescale = binary.BigEndian.Uint32(data[8:12])
+		}
+	}
+
+	if m.Trexs, err = ParseTrexs(boxes); err != nil {
+		return
+	}
+
+	children, err := moov.Children()
+	if err != nil {
+		return
+	}
+
+	for _, trak := range children {
+		if trak.Type == "trak" {
+			track, err := parseTrack(trak)
+			if err != nil {
+				return m, err
+			}
+			m.Tracks = append(m.Tracks, track)
+		}
+	}
+
+	return
+}
+
+// Track returns the track by the track id.
+func (m Movie) Track(id uint32) (Track, bool) {
+	for _, t := range m.Tracks {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Track{}, false
+}
+
+// MainTrack returns the main track, which is the first video track,
+// or the first track if no video.
+func (m Movie) MainTrack() (Track, bool) {
+	for _, t := range m.Tracks {
+		if t.Handler == HandlerVideo {
+			return t, true
+		}
+	}
+
+	if len(m.Tracks) > 0 {
+		return m.Tracks[0], true
+	}
+	return Track{}, false
+}
+
+// Codecs returns the codec strings of all the tracks, which may be used
+// by the CODECS attribute of EXT-X-STREAM-INF.
+func (m Movie) Codecs() []string {
+	codecs := make([]string, 0, len(m.Tracks))
+	for _, t := range m.Tracks {
+		if t.Codec != "" {
+			codecs = append(codecs, t.Codec)
+		}
+	}
+	return codecs
+}
+
+func parseTrack(trak Box) (track Track, err error) {
+	boxes, err := trak.Children()
+	if err != nil {
+		return
+	}
+
+	tkhd, ok := FindBox(boxes, "tkhd")
+	if !ok {
+		return track, fmt.Errorf("%w: missing 'tkhd'", errInvalidBox)
+	}
+
+	version, _, data, err := tkhd.FullBox()
+	if err != nil {
+		return
+	} else if version == 1 && len(data) >= 20 {
+		track.ID = binary.BigEndian.Uint32(data[16:20])
+	} else if version == 0 && len(data) >= 12 {
+		track.ID = binary.BigEndian.Uint32(data[8:12])
+	} else {
+		return track, fmt.Errorf("%w: invalid 'tkhd'", errInvalidBox)
+	}
+
+	if mdhd, ok := FindBox(boxes, "mdia", "mdhd"); ok {
+		if err = track.parseMdhd(mdhd); err != nil {
+			return
+		}
+	}
+
+	if hdlr, ok := FindBox(boxes, "mdia", "hdlr"); ok {
+		if _, _, data, err := hdlr.FullBox(); err == nil && len(data) >= 8 {
+			track.Handler = string(data[4:8])
+		}
+	}
+
+	if stsd, ok := FindBox(boxes, "mdia", "minf", "stbl", "stsd"); ok {
+		entries, err := stsd.ChildrenAt(8) // version, flags and entry_count
+		if err != nil {
+			return track, err
+		} else if len(entries) > 0 {
+			track.parseSampleEntry(entries[0])
+		}
+	}
+
+	return
+}
+
+func (t *Track) parseMdhd(mdhd Box) error {
+	version, _, data, err := mdhd.FullBox()
+	if err != nil {
+		return err
+	}
+
+	var lang []byte
+	switch {
+	case version == 1 && len(data) >= 30:
+		t.Timescale = binary.BigEndian.Uint32(data[16:20])
+		t.Duration = binary.BigEndian.Uint64(data[20:28])
+		lang = data[28:30]
+
+	case version == 0 && len(data) >= 18:
+		t.Timescale = binary.BigEndian.Uint32(data[8:12])
+		t.Duration = uint64(binary.BigEndian.Uint32(data[12:16]))
+		lang = data[16:18]
+
+	default:
+		return fmt.Errorf("%w: invalid 'mdhd'", errInvalidBox)
+	}
+
+	// ISO-639-2/T language code, which is packed as three 5-bit characters.
+	if code := binary.BigEndian.Uint16(lang); code != 0 {
+		t.Language = string([]byte{byte(code>>10&0x1F) + 0x60, byte(code>>5&0x1F) + 0x60, byte(code&0x1F) + 0x60})
+	}
+	return nil
+}
+
+func (t *Track) parseSampleEntry(entry Box) {
+	t.SampleEntry = entry.Type
+	payload := entry.Payload()
+
+	var skip int
+	switch t.Handler {
+	case HandlerVideo:
+		skip = 78 // VisualSampleEntry
+		if len(payload) >= skip {
+			t.Width = binary.BigEndian.Uint16(payload[24:26])
+			t.Height = binary.BigEndian.Uint16(payload[26:28])
+		}
+
+	case HandlerAudio:
+		skip = 28 // AudioSampleEntry
+		if len(payload) >= skip {
+			t.Channels = binary.BigEndian.Uint16(payload[16:18])
+			t.SampleRate = uint32(binary.BigEndian.Uint16(payload[24:26]))
+			switch binary.BigEndian.Uint16(payload[8:10]) { // QuickTime sound version
+			case 1:
+				skip += 16
+			case 2:
+				skip += 36
+			}
+		}
+
+	default:
+		t.Codec = entry.Type
+		return
+	}
+
+	children, err := entry.ChildrenAt(skip)
+	if err != nil {
+		t.Codec = entry.Type
+		return
+	}
+
+	// The encrypted sample entry keeps the original format in "sinf/frma".
+	format := entry.Type
+	if frma, ok := FindBox(children, "sinf", "frma"); ok && len(frma.Payload()) >= 4 {
+		format = string(frma.Payload()[:4])
+	}
+
+	t.Codec = codecString(format, children)
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// SegmentReference is a reference in the segment index box "sidx".
+type SegmentReference struct {
+	ReferenceType      uint8 // 1 means referring to a "sidx", or 0 to the media.
+	ReferencedSize     uint32
+	SubsegmentDuration uint32 // The unit is the timescale of "sidx".
+	StartsWithSAP      bool
+	SAPType            uint8
+	SAPDeltaTime       uint32
+}
+
+// SegmentIndex is the segment index box "sidx".
+type SegmentIndex struct {
+	ReferenceID              uint32
+	Timescale                uint32
+	EarliestPresentationTime uint64
+
+	// FirstOffset is the offset of the first referenced byte,
+	// which is relative to the end of the "sidx" box.
+	FirstOffset uint64
+	References  []SegmentReference
+}
+
+// ParseSidx parses the segment index box "sidx".
+func ParseSidx(b Box) (sidx SegmentIndex, err error) {
+	version, _, data, err := b.FullBox()
+	if err != nil {
+		return
+	}
+
+	r := reader{data: data}
+	sidx.ReferenceID = r.uint32()
+	sidx.Timescale = r.uint32()
+	if version == 0 {
+		sidx.EarliestPresentationTime = uint64(r.uint32())
+		sidx.FirstOffset = uint64(r.uint32())
+	} else {
+		sidx.EarliestPresentationTime = r.uint64()
+		sidx.FirstOffset = r.uint64()
+	}
+
+	r.uint16() // reserved
+	count := int(r.uint16())
+	if r.err != nil || len(r.data) < count*12 {
+		return sidx, fmt.Errorf("%w: invalid 'sidx'", errInvalidBox)
+	}
+
+	sidx.References = make([]SegmentReference, count)
+	for i := range sidx.References {
+		v1, v2, v3 := r.uint32(), r.uint32(), r.uint32()
+		sidx.References[i] = SegmentReference{
+			ReferenceType:      uint8(v1 >> 31),
+			ReferencedSize:     v1 & 0x7FFFFFFF,
+			SubsegmentDuration: v2,
+			StartsWithSAP:      v3>>31 != 0,
+			SAPType:            uint8(v3 >> 28 & 0x07),
+			SAPDeltaTime:       v3 & 0x0FFFFFFF,
+		}
+	}
+
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// FragmentInfo is the information of a track fragment in a media segment.
+type FragmentInfo struct {
+	SequenceNumber      uint32 // The sequence number in "mfhd".
+	TrackID             uint32
+	Timescale           uint32 // The timescale of the track.
+	BaseMediaDecodeTime uint64 // The unit is Timescale.
+	SampleCount         int
+	Duration            uint64 // The unit is Timescale.
+	Samples             []Sample
+}
+
+// StartTime returns the base media decode time as time.Duration.
+func (f FragmentInfo) StartTime() time.Duration {
+	return scaleDuration(f.BaseMediaDecodeTime, f.Timescale)
+}
+
+// DurationTime returns the duration as time.Duration.
+func (f FragmentInfo) DurationTime() time.Duration {
+	return scaleDuration(f.Duration, f.Timescale)
+}
+
+// SegmentInfo is the information of a media segment.
+type SegmentInfo struct {
+	Indexes   []SegmentIndex
+	Fragments []FragmentInfo
+}
+
+// Duration returns the total duration of the fragments of the track.
+func (s SegmentInfo) Duration(trackID uint32) (duration time.Duration) {
+	for _, f := range s.Fragments {
+		if f.TrackID == trackID {
+			duration += f.DurationTime()
+		}
+	}
+	return
+}
+
+// StartTime returns the base media decode time of the first fragment
+// of the track. Return -1 if the track has no fragment.
+func (s SegmentInfo) StartTime(trackID uint32) time.Duration {
+	for _, f := range s.Fragments {
+		if f.TrackID == trackID {
+			return f.StartTime()
+		}
+	}
+	return -1
+}
+
+// InspectSegment parses the media segment, which uses the default values
+// and timescales of the tracks in the movie.
+func (m Movie) InspectSegment(data []byte) (info SegmentInfo, err error) {
+	boxes, err := ReadBoxes(data)
+	if err != nil {
+		return
+	}
+
+	for _, box := range boxes {
+		switch box.Type {
+		case "sidx":
+			sidx, err := ParseSidx(box)
+			if err != nil {
+				return info, err
+			}
+			info.Indexes = append(info.Indexes, sidx)
+
+		case "moof":
+			if err = m.inspectFragment(&info, box); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func (m Movie) inspectFragment(info *SegmentInfo, moof Box) error {
+	trafs, err := ParseMovieFragment(moof, m.Trexs)
+	if err != nil {
+		return err
+	}
+
+	var seq uint32
+	if mfhd, ok := FindBox([]Box{moof}, "moof", "mfhd"); ok {
+		if _, _, data, err := mfhd.FullBox(); err == nil && len(data) >= 4 {
+			seq = binary.BigEndian.Uint32(data[:4])
+		}
+	}
+
+	for _, traf := range trafs {
+		track, _ := m.Track(traf.Header.TrackID)
+		f := FragmentInfo{
+			SequenceNumber:      seq,
+			TrackID:             traf.Header.TrackID,
+			Timescale:           track.Timescale,
+			BaseMediaDecodeTime: traf.BaseMediaDecodeTime,
+			SampleCount:         len(traf.Samples),
+			Samples:             traf.Samples,
+		}
+
+		for _, s := range traf.Samples {
+			f.Duration += uint64(s.Duration)
+		}
+		info.Fragments = append(info.Fragments, f)
+	}
+
+	return nil
+}
+
+func scaleDuration(value uint64, timescale uint32) time.Duration {
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(float64(value) / float64(timescale) * float64(time.Second))
+}
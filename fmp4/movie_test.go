@@ -0,0 +1,182 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fmp4
+
+import (
+	"testing"
+	"time"
+)
+
+func u16(v uint16) []byte { return []byte{byte(v >> 8), byte(v)} }
+
+func newTestInit() []byte {
+	trak := func(id uint32, handler string, timescale uint32, entry []byte) []byte {
+		return AppendBox(nil, "trak",
+			AppendFullBox(nil, "tkhd", 0, 3, u32(0), u32(0), u32(id), make([]byte, 68)),
+			AppendBox(nil, "mdia",
+				AppendFullBox(nil, "mdhd", 0, 0, u32(0), u32(0), u32(timescale), u32(0), u16(0x55C4), u16(0)),
+				AppendFullBox(nil, "hdlr", 0, 0, u32(0), []byte(handler), make([]byte, 13)),
+				AppendBox(nil, "minf", AppendBox(nil, "stbl",
+					AppendFullBox(nil, "stsd", 0, 0, u32(1), entry),
+				)),
+			),
+		)
+	}
+
+	visual := make([]byte, 78)
+	copy(visual[24:], u16(1280))
+	copy(visual[26:], u16(720))
+	avc1 := AppendBox(nil, "avc1", visual, AppendBox(nil, "avcC", []byte{1, 0x64, 0x00, 0x1F, 0xFF}))
+
+	audio := make([]byte, 28)
+	copy(audio[16:], u16(2))
+	copy(audio[24:], u16(48000))
+	esds := AppendFullBox(nil, "esds", 0, 0,
+		[]byte{0x03, 0x19, 0, 1, 0},                      // ES_Descriptor
+		[]byte{0x04, 0x11, 0x40, 0x15}, make([]byte, 11), // DecoderConfigDescriptor
+		[]byte{0x05, 0x02, 0x12, 0x10}, // AudioSpecificConfig: AAC-LC
+	)
+	mp4a := AppendBox(nil, "mp4a", audio, esds)
+
+	return AppendBox(AppendBox(nil, "ftyp", []byte("iso6"), u32(0), []byte("iso6cmfc")), "moov",
+		AppendFullBox(nil, "mvhd", 0, 0, u32(0), u32(0), u32(1000), u32(0), make([]byte, 80)),
+		trak(1, HandlerVideo, 90000, avc1),
+		trak(2, HandlerAudio, 48000, mp4a),
+		AppendBox(nil, "mvex",
+			AppendFullBox(nil, "trex", 0, 0, u32(1), u32(1), u32(3000), u32(10), u32(0)),
+			AppendFullBox(nil, "trex", 0, 0, u32(2), u32(1), u32(1024), u32(5), u32(0)),
+		),
+	)
+}
+
+func TestParseMovie(t *testing.T) {
+	m, err := ParseMovie(newTestInit())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.FileType.MajorBrand != "iso6" || len(m.FileType.CompatibleBrands) != 2 {
+		t.Errorf("unexpected file type: %+v", m.FileType)
+	}
+	if m.Timescale != 1000 {
+		t.Errorf("expect timescale %d, but got %d", 1000, m.Timescale)
+	}
+
+	expects := []Track{
+		{ID: 1, Handler: HandlerVideo, Timescale: 90000, Language: "und", SampleEntry: "avc1",
+			Codec: "avc1.64001f", Width: 1280, Height: 720},
+		{ID: 2, Handler: HandlerAudio, Timescale: 48000, Language: "und", SampleEntry: "mp4a",
+			Codec: "mp4a.40.2", Channels: 2, SampleRate: 48000},
+	}
+
+	if len(m.Tracks) != len(expects) {
+		t.Fatalf("expect %d tracks, but got %d", len(expects), len(m.Tracks))
+	}
+	for i, track := range m.Tracks {
+		if track != expects[i] {
+			t.Errorf("%d: expect track %+v, but got %+v", i, expects[i], track)
+		}
+	}
+
+	if track, _ := m.MainTrack(); track.ID != 1 {
+		t.Errorf("expect the main track %d, but got %d", 1, track.ID)
+	}
+}
+
+func TestCodecString(t *testing.T) {
+	hvcC := []byte{1, 0x01, 0x60, 0, 0, 0, 0xB0, 0, 0, 0, 0, 0, 93}
+	av1C := []byte{0x81, 0x04, 0x0C, 0}
+
+	tests := []struct {
+		Format string
+		Boxes  []byte
+		Expect string
+	}{
+		{Format: "hvc1", Boxes: AppendBox(nil, "hvcC", hvcC), Expect: "hvc1.1.6.L93.B0"},
+		{Format: "av01", Boxes: AppendBox(nil, "av1C", av1C), Expect: "av01.0.04M.08"},
+		{Format: "vp09", Boxes: AppendFullBox(nil, "vpcC", 1, 0, []byte{0, 10, 0x80}), Expect: "vp09.00.10.08"},
+		{Format: "ec-3", Expect: "ec-3"},
+	}
+
+	for _, test := range tests {
+		boxes, err := ReadBoxes(test.Boxes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if codec := codecString(test.Format, boxes); codec != test.Expect {
+			t.Errorf("expect codec '%s', but got '%s'", test.Expect, codec)
+		}
+	}
+}
+
+func TestInspectSegment(t *testing.T) {
+	m, err := ParseMovie(newTestInit())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sidx := AppendFullBox(nil, "sidx", 0, 0, u32(1), u32(90000), u32(180000), u32(0),
+		u16(0), u16(1), u32(1000), u32(9000), u32(0x90000000))
+
+	moof := AppendBox(nil, "moof",
+		AppendFullBox(nil, "mfhd", 0, 0, u32(7)),
+		AppendBox(nil, "traf",
+			AppendFullBox(nil, "tfhd", 0, TfhdDefaultBaseIsMoof, u32(1)),
+			AppendFullBox(nil, "tfdt", 1, 0, u32(0), u32(180000)),
+			AppendFullBox(nil, "trun", 0, 0, u32(3)),
+		),
+		AppendBox(nil, "traf",
+			AppendFullBox(nil, "tfhd", 0, TfhdDefaultBaseIsMoof, u32(2)),
+			AppendFullBox(nil, "tfdt", 0, 0, u32(96000)),
+			AppendFullBox(nil, "trun", 0, 0, u32(4)),
+		),
+	)
+
+	data := AppendBox(append(sidx, moof...), "mdat", make([]byte, 3*10+4*5))
+	info, err := m.InspectSegment(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(info.Indexes) != 1 {
+		t.Fatalf("expect %d segment index, but got %d", 1, len(info.Indexes))
+	} else if index := info.Indexes[0]; index.EarliestPresentationTime != 180000 ||
+		len(index.References) != 1 || index.References[0].SubsegmentDuration != 9000 ||
+		!index.References[0].StartsWithSAP || index.References[0].SAPType != 1 {
+		t.Errorf("unexpected segment index: %+v", index)
+	}
+
+	if len(info.Fragments) != 2 {
+		t.Fatalf("expect %d fragments, but got %d", 2, len(info.Fragments))
+	}
+
+	if f := info.Fragments[0]; f.SequenceNumber != 7 || f.SampleCount != 3 || f.Duration != 9000 {
+		t.Errorf("unexpected video fragment: %+v", f)
+	}
+	if f := info.Fragments[1]; f.SampleCount != 4 || f.Duration != 4096 {
+		t.Errorf("unexpected audio fragment: %+v", f)
+	}
+
+	if d := info.Duration(1); d != 100*time.Millisecond {
+		t.Errorf("expect duration %s, but got %s", 100*time.Millisecond, d)
+	}
+	if d := info.StartTime(2); d != 2*time.Second {
+		t.Errorf("expect start time %s, but got %s", 2*time.Second, d)
+	}
+	if d := info.StartTime(3); d != -1 {
+		t.Errorf("expect start time %d, but got %s", -1, d)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Severity is the severity of a finding.
+type Severity int
+
+// Define the severities of the findings.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the name of the severity, such as "error".
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// MarshalText implements the interface encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses the name of the severity.
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	default:
+		return 0, fmt.Errorf("unknown severity '%s'", name)
+	}
+}
+
+// Finding is a problem found in a playlist.
+type Finding struct {
+	Severity Severity
+	Rule     string // The name of the rule, such as "target-duration".
+	Message  string
+
+	URI  string `json:",omitempty,omitzero"` // The uri of the playlist, which is set by the caller.
+	Line int    `json:",omitempty,omitzero"` // The line number, which is only set for the syntax error.
+}
+
+// String returns the text representation of the finding.
+func (f Finding) String() string {
+	var b strings.Builder
+	if f.URI != "" {
+		b.WriteString(f.URI)
+		if f.Line > 0 {
+			fmt.Fprintf(&b, ":%d", f.Line)
+		}
+		b.WriteString(": ")
+	}
+
+	fmt.Fprintf(&b, "%s: [%s] %s", f.Severity, f.Rule, f.Message)
+	return b.String()
+}
+
+// Findings is a set of findings.
+type Findings []Finding
+
+// String returns the text representation of the findings, one per line.
+func (fs Findings) String() string {
+	var b strings.Builder
+	for _, f := range fs {
+		fmt.Fprintln(&b, f)
+	}
+	return b.String()
+}
+
+// Max returns the highest severity of the findings,
+// which is -1 if there are no findings.
+func (fs Findings) Max() Severity {
+	max := Severity(-1)
+	for _, f := range fs {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max
+}
+
+// WithURI sets the uri of all the findings, and returns itself.
+func (fs Findings) WithURI(uri string) Findings {
+	for i := range fs {
+		fs[i].URI = uri
+	}
+	return fs
+}
+
+type _Linter struct {
+	findings Findings
+}
+
+func (l *_Linter) report(severity Severity, rule, format string, args ...any) {
+	l.findings = append(l.findings, Finding{
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Parse parses the playlist from r in the strict mode, and checks it.
+//
+// If failing to parse it, return the nil playlist and the finding
+// of the rule "syntax" with the line number.
+func Parse(r io.Reader) (pl playlist.PlayList, findings Findings) {
+	pl, err := playlist.Parse(r, playlist.Strict())
+	if err != nil {
+		finding := Finding{Severity: SeverityError, Rule: "syntax", Message: err.Error()}
+
+		var perr playlist.ParseError
+		if errors.As(err, &perr) {
+			finding.Line = perr.Line
+			finding.Message = fmt.Sprintf("%s: %s", perr.Data, perr.Err)
+		}
+
+		return nil, Findings{finding}
+	}
+
+	return pl, PlayList(pl)
+}
+
+// PlayList checks the master or media playlist.
+func PlayList(pl playlist.PlayList) Findings {
+	switch v := pl.(type) {
+	case playlist.MediaPlayList:
+		return Media(v)
+	case *playlist.MediaPlayList:
+		return Media(*v)
+	case playlist.MasterPlayList:
+		return Master(v)
+	case *playlist.MasterPlayList:
+		return Master(*v)
+	default:
+		return Findings{{Severity: SeverityError, Rule: "syntax",
+			Message: fmt.Sprintf("unsupported playlist type %T", pl)}}
+	}
+}
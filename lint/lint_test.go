@@ -0,0 +1,165 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+func hasFinding(findings Findings, severity Severity, rule string) bool {
+	for _, f := range findings {
+		if f.Severity == severity && f.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParse(t *testing.T) {
+	pl, findings := Parse(strings.NewReader("#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\n0.ts\n"))
+	if pl != nil {
+		t.Errorf("expect nil playlist, but got %T", pl)
+	}
+
+	if len(findings) != 1 || findings[0].Rule != "syntax" || findings[0].Line != 3 {
+		t.Errorf("unexpected findings: %+v", findings)
+	} else if s := findings.WithURI("a.m3u8").String(); !strings.HasPrefix(s, "a.m3u8:3: error: [syntax] ") {
+		t.Errorf("unexpected text '%s'", s)
+	}
+
+	if findings.Max() != SeverityError {
+		t.Errorf("expect max severity '%s', but got '%s'", SeverityError, findings.Max())
+	}
+	if max := (Findings{}).Max(); max != -1 {
+		t.Errorf("expect max severity %d, but got %d", -1, max)
+	}
+}
+
+func TestMedia(t *testing.T) {
+	pl, findings := Parse(strings.NewReader(`#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:10
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-PROGRAM-DATE-TIME:2025-01-01T00:00:00Z
+#EXTINF:6,
+0.m4s
+#EXTINF:2,
+1.m4s
+#EXT-X-PROGRAM-DATE-TIME:2025-01-01T00:01:00Z
+#EXTINF:6,
+2.m4s
+`))
+	if pl == nil {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+
+	expects := []struct {
+		Severity Severity
+		Rule     string
+	}{
+		{Severity: SeverityWarning, Rule: "target-duration"},
+		{Severity: SeverityInfo, Rule: "short-segment"},
+		{Severity: SeverityWarning, Rule: "map"},
+		{Severity: SeverityWarning, Rule: "program-date-time"},
+		{Severity: SeverityWarning, Rule: "playlist-type"},
+		{Severity: SeverityInfo, Rule: "independent-segments"},
+	}
+
+	for _, expect := range expects {
+		if !hasFinding(findings, expect.Severity, expect.Rule) {
+			t.Errorf("missing the %s finding '%s'", expect.Severity, expect.Rule)
+		}
+	}
+	if hasFinding(findings, SeverityWarning, "live-window") {
+		t.Errorf("unexpected finding 'live-window' for VOD")
+	}
+
+	media := pl.(playlist.MediaPlayList)
+	media.Segments[0].Duration = 12
+	if findings := Media(media); !hasFinding(findings, SeverityError, "target-duration") {
+		t.Errorf("missing the error finding 'target-duration': %+v", findings)
+	}
+
+	media.Segments[0].URI = ""
+	if findings := Media(media); len(findings) != 1 || findings[0].Rule != "segment" {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
+
+func TestLowLatency(t *testing.T) {
+	pl := playlist.MediaPlayList{
+		Version:        9,
+		TargetDuration: 4,
+		ServerControl:  playlist.XServerControl{PartHoldBack: 1.5, HoldBack: 6},
+		PartInf:        playlist.XPartInf{PartTarget: 1},
+		Segments: []playlist.MediaSegment{
+			{URI: "0.ts", Duration: 4, Parts: []playlist.XPart{{URI: "0.0.ts", Duration: 1.2}}},
+		},
+	}
+
+	findings := Media(pl)
+	for _, msg := range []string{"CAN-BLOCK-RELOAD", "HOLD-BACK 6s", "PART-HOLD-BACK 1.5s", "'0.0.ts'"} {
+		var found bool
+		for _, f := range findings {
+			found = found || f.Rule == "low-latency" && strings.Contains(f.Message, msg)
+		}
+		if !found {
+			t.Errorf("missing the low-latency finding about '%s'", msg)
+		}
+	}
+}
+
+func TestMaster(t *testing.T) {
+	pl, findings := Parse(strings.NewReader(`#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="en",URI="en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,CODECS="avc1.64001f,mp4a.40.2",AUDIO="aac",SUBTITLES="subs"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=100000,AVERAGE-BANDWIDTH=90000
+audio.m3u8
+`))
+	if pl == nil {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+
+	expects := []struct {
+		Severity Severity
+		Message  string
+	}{
+		{Severity: SeverityError, Message: "undefined SUBTITLES group 'subs'"},
+		{Severity: SeverityWarning, Message: "AUDIO rendition group 'aac' should have a rendition with DEFAULT=YES"},
+		{Severity: SeverityWarning, Message: "'audio.m3u8' should have CODECS"},
+		{Severity: SeverityWarning, Message: "'720p.m3u8' should have RESOLUTION"},
+		{Severity: SeverityInfo, Message: "'720p.m3u8' should have FRAME-RATE"},
+		{Severity: SeverityInfo, Message: "'720p.m3u8' should have AVERAGE-BANDWIDTH"},
+		{Severity: SeverityInfo, Message: "I-frame playlists"},
+	}
+
+	if len(findings) != len(expects) {
+		t.Errorf("expect %d findings, but got %d:\n%s", len(expects), len(findings), findings)
+	}
+
+	for _, expect := range expects {
+		var found bool
+		for _, f := range findings {
+			found = found || f.Severity == expect.Severity && strings.Contains(f.Message, expect.Message)
+		}
+		if !found {
+			t.Errorf("missing the %s finding '%s'", expect.Severity, expect.Message)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Master checks the master playlist, which reports
+//
+//   - "validate": the playlist cannot be encoded.
+//   - "rendition-group": the variant stream references an undefined
+//     rendition group, or the rendition group has no default rendition.
+//   - "codecs": the variant stream misses CODECS.
+//   - "resolution": the video variant stream misses RESOLUTION.
+//   - "frame-rate": the video variant stream misses FRAME-RATE.
+//   - "average-bandwidth": the variant stream misses AVERAGE-BANDWIDTH.
+//   - "iframe": there are no I-frame playlists for the trick play.
+func Master(pl playlist.MasterPlayList) Findings {
+	var l _Linter
+	if err := pl.Output(io.Discard); err != nil {
+		l.report(SeverityError, "validate", "%s", err)
+	}
+
+	var keys []string
+	var iframes int
+	groups := make(map[string][]playlist.XMedia)
+	for _, s := range pl.Streams {
+		for _, m := range s.Medias {
+			key := groupKey(m.Type, m.GroupId)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], m)
+		}
+		iframes += len(s.IFrameStreams)
+	}
+
+	for _, s := range pl.Streams {
+		if s.Stream.IsZero() {
+			continue
+		}
+
+		lintStream(&l, s.Stream, groups)
+	}
+
+	for _, key := range keys {
+		medias := groups[key]
+		if medias[0].Type == playlist.XMediaTypeClosedCaptions {
+			continue
+		}
+
+		var hasDefault bool
+		for _, m := range medias {
+			hasDefault = hasDefault || m.Default
+		}
+		if !hasDefault {
+			l.report(SeverityWarning, "rendition-group",
+				"the %s rendition group '%s' should have a rendition with DEFAULT=YES",
+				medias[0].Type, medias[0].GroupId)
+		}
+	}
+
+	if iframes == 0 {
+		l.report(SeverityInfo, "iframe", "the I-frame playlists should be provided for the trick play")
+	}
+
+	return l.findings
+}
+
+func groupKey(_type, id string) string {
+	return _type + "/" + id
+}
+
+func lintStream(l *_Linter, s playlist.XStreamInf, groups map[string][]playlist.XMedia) {
+	references := []struct {
+		Type  string
+		Group string
+	}{
+		{Type: playlist.XMediaTypeAudio, Group: s.Audio},
+		{Type: playlist.XMediaTypeVideo, Group: s.Video},
+		{Type: playlist.XMediaTypeSubtitles, Group: s.Subtitles},
+		{Type: playlist.XMediaTypeClosedCaptions, Group: s.ClosedCaptions},
+	}
+
+	for _, ref := range references {
+		if ref.Group == "" || ref.Group == "NONE" && ref.Type == playlist.XMediaTypeClosedCaptions {
+			continue
+		}
+
+		if _, ok := groups[groupKey(ref.Type, ref.Group)]; !ok {
+			l.report(SeverityError, "rendition-group",
+				"the variant stream '%s' references the undefined %s group '%s'", s.URI, ref.Type, ref.Group)
+		}
+	}
+
+	if len(s.Codecs) == 0 {
+		l.report(SeverityWarning, "codecs", "the variant stream '%s' should have CODECS", s.URI)
+	}
+
+	if s.AverageBandwidth == 0 {
+		l.report(SeverityInfo, "average-bandwidth",
+			"the variant stream '%s' should have AVERAGE-BANDWIDTH", s.URI)
+	}
+
+	if !hasVideoCodec(s.Codecs) {
+		return
+	}
+
+	if s.Resolution.Width == 0 || s.Resolution.Height == 0 {
+		l.report(SeverityWarning, "resolution",
+			"the video variant stream '%s' should have RESOLUTION", s.URI)
+	}
+	if s.FrameRate == 0 {
+		l.report(SeverityInfo, "frame-rate",
+			"the video variant stream '%s' should have FRAME-RATE", s.URI)
+	}
+}
+
+var videoCodecs = []string{"avc1", "avc3", "hvc1", "hev1", "dvh1", "dvhe", "av01", "vp08", "vp09"}
+
+func hasVideoCodec(codecs []string) bool {
+	for _, codec := range codecs {
+		codec, _, _ = strings.Cut(strings.TrimSpace(codec), ".")
+		if slices.Contains(videoCodecs, codec) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"io"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Media checks the media playlist, which reports
+//
+//   - "segment": the media segment misses the uri or the duration.
+//   - "validate": the playlist cannot be encoded, such as the too low version.
+//   - "target-duration": the rounded duration of a media segment exceeds
+//     the target duration, or the target duration is too large.
+//   - "live-window": the live playlist contains less than three target durations.
+//   - "playlist-type": the VOD playlist misses EXT-X-ENDLIST.
+//   - "short-segment": the media segment is too shorter than the target duration.
+//   - "independent-segments": EXT-X-INDEPENDENT-SEGMENTS is missing.
+//   - "program-date-time": the program date time jumps without the discontinuity.
+//   - "map": the fragmented MP4 media segment misses EXT-X-MAP.
+//   - "key": the key misses the uri, or has an invalid iv.
+//   - "low-latency": the Low-Latency tags violate the rules.
+func Media(pl playlist.MediaPlayList) Findings {
+	var l _Linter
+	if !lintMediaSegments(&l, pl) {
+		return l.findings
+	}
+
+	if err := pl.Output(io.Discard); err != nil {
+		l.report(SeverityError, "validate", "%s", err)
+	}
+
+	lintTargetDuration(&l, pl)
+	lintMediaSegmentTags(&l, pl)
+	lintLowLatency(&l, pl)
+
+	live := !pl.EndList && pl.PlayListType != playlist.MediaPlayListTypeVOD
+	if live && pl.TotalDuration() < float64(3*pl.TargetDuration) {
+		l.report(SeverityWarning, "live-window",
+			"the live playlist should contain at least three target durations, but got %gs",
+			pl.TotalDuration())
+	}
+
+	if pl.PlayListType == playlist.MediaPlayListTypeVOD && !pl.EndList {
+		l.report(SeverityWarning, "playlist-type", "the VOD playlist should contain EXT-X-ENDLIST")
+	}
+
+	if !pl.IndependentSegments && !pl.IFrameOnly {
+		l.report(SeverityInfo, "independent-segments",
+			"EXT-X-INDEPENDENT-SEGMENTS should be present if all the media segments start with a key frame")
+	}
+
+	return l.findings
+}
+
+func lintMediaSegments(l *_Linter, pl playlist.MediaPlayList) (ok bool) {
+	if len(pl.Segments) == 0 {
+		l.report(SeverityError, "segment", "no media segments")
+		return false
+	}
+
+	ok = true
+	for _, seg := range pl.Segments {
+		if seg.URI == "" {
+			l.report(SeverityError, "segment", "media segment %d misses the uri", seg.MediaSequence)
+			ok = false
+		}
+		if seg.Duration <= 0 {
+			l.report(SeverityError, "segment", "media segment %d misses the duration", seg.MediaSequence)
+			ok = false
+		}
+	}
+	return
+}
+
+func lintTargetDuration(l *_Linter, pl playlist.MediaPlayList) {
+	var maxDuration float64
+	for i, seg := range pl.Segments {
+		maxDuration = max(maxDuration, seg.Duration)
+		if uint64(math.Round(seg.Duration)) > pl.TargetDuration {
+			l.report(SeverityError, "target-duration",
+				"the duration %gs of media segment %d exceeds the target duration %ds",
+				seg.Duration, seg.MediaSequence, pl.TargetDuration)
+		}
+
+		// The last media segment may be shorter.
+		if i < len(pl.Segments)-1 && !pl.IFrameOnly && seg.Duration < float64(pl.TargetDuration)/2 {
+			l.report(SeverityInfo, "short-segment",
+				"the duration %gs of media segment %d is less than a half of the target duration %ds",
+				seg.Duration, seg.MediaSequence, pl.TargetDuration)
+		}
+	}
+
+	if expect := uint64(math.Round(maxDuration)); pl.TargetDuration > expect && expect > 0 {
+		l.report(SeverityWarning, "target-duration",
+			"the target duration %ds should be the rounded maximum duration %ds of the media segments",
+			pl.TargetDuration, expect)
+	}
+}
+
+func lintMediaSegmentTags(l *_Linter, pl playlist.MediaPlayList) {
+	var xmap playlist.XMap
+	var last *playlist.MediaSegment
+	for i := range pl.Segments {
+		seg := &pl.Segments[i]
+		if !seg.Map.IsZero() {
+			xmap = seg.Map
+		}
+		if xmap.IsZero() && isFMP4(seg.URI) {
+			l.report(SeverityWarning, "map",
+				"the fragmented MP4 media segment %d should have EXT-X-MAP", seg.MediaSequence)
+		}
+
+		if len(seg.Keys) > 0 {
+			for _, key := range seg.Keys {
+				if key.Method != playlist.XKeyMethodNone && key.URI == "" {
+					l.report(SeverityError, "key", "the key of media segment %d misses the uri", seg.MediaSequence)
+				}
+			}
+
+			if _, err := seg.IV(); err != nil {
+				l.report(SeverityError, "key", "the iv of media segment %d is invalid: %s", seg.MediaSequence, err)
+			}
+		}
+
+		if last != nil && !seg.Discontinuity && !last.ProgramDateTime.IsZero() {
+			expect := last.ProgramDateTime.Add(time.Duration(last.Duration * float64(time.Second)))
+			if diff := seg.ProgramDateTime.Sub(expect).Abs(); diff > time.Second {
+				l.report(SeverityWarning, "program-date-time",
+					"the program date time of media segment %d jumps %s without EXT-X-DISCONTINUITY",
+					seg.MediaSequence, diff)
+			}
+		}
+		last = seg
+	}
+}
+
+func isFMP4(uri string) bool {
+	if index := strings.IndexAny(uri, "?#"); index >= 0 {
+		uri = uri[:index]
+	}
+
+	switch strings.ToLower(path.Ext(uri)) {
+	case ".m4s", ".mp4", ".m4v", ".m4a", ".cmfv", ".cmfa":
+		return true
+	default:
+		return false
+	}
+}
+
+func lintLowLatency(l *_Linter, pl playlist.MediaPlayList) {
+	control := pl.ServerControl
+	if control.HoldBack > 0 && control.HoldBack < float64(3*pl.TargetDuration) {
+		l.report(SeverityError, "low-latency",
+			"HOLD-BACK %gs must be at least three times the target duration %ds",
+			control.HoldBack, pl.TargetDuration)
+	}
+
+	if pl.PartInf.IsZero() {
+		return
+	}
+
+	target := pl.PartInf.PartTarget
+	if !control.CanBlockReload {
+		l.report(SeverityError, "low-latency", "the Low-Latency playlist must have CAN-BLOCK-RELOAD=YES")
+	}
+
+	switch {
+	case control.PartHoldBack == 0:
+		l.report(SeverityError, "low-latency", "the Low-Latency playlist must have PART-HOLD-BACK")
+	case control.PartHoldBack < 2*target:
+		l.report(SeverityError, "low-latency",
+			"PART-HOLD-BACK %gs must be at least twice PART-TARGET %gs", control.PartHoldBack, target)
+	case control.PartHoldBack < 3*target:
+		l.report(SeverityWarning, "low-latency",
+			"PART-HOLD-BACK %gs should be at least three times PART-TARGET %gs", control.PartHoldBack, target)
+	}
+
+	checkParts := func(parts []playlist.XPart) {
+		for _, part := range parts {
+			if part.Duration > target {
+				l.report(SeverityError, "low-latency",
+					"the duration %gs of part '%s' exceeds PART-TARGET %gs", part.Duration, part.URI, target)
+			}
+		}
+	}
+
+	for _, seg := range pl.Segments {
+		checkParts(seg.Parts)
+	}
+	checkParts(pl.Parts)
+}
@@ -0,0 +1,367 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/xgfone/go-hls/fmp4"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+// DefaultSegmentDuration is the default minimum duration of the media segment.
+const DefaultSegmentDuration = 6 * time.Second
+
+// ByteRangeGenerator is used to generate the media playlist that refers to
+// the media segments in a single fMP4 or MPEG-TS file by EXT-X-BYTERANGE.
+type ByteRangeGenerator struct {
+	// URI is the URI of the single file in the playlist.
+	URI string
+
+	// SegmentDuration is the minimum duration of each media segment,
+	// which is split at the first fragment or key frame boundary
+	// after reaching it. So it may be longer than SegmentDuration.
+	//
+	// Default: DefaultSegmentDuration
+	SegmentDuration time.Duration
+}
+
+// chunk is the minimum splittable unit, that's, a movie fragment
+// of fMP4 or the packets from a key frame to the next of MPEG-TS.
+type chunk struct {
+	Offset   int
+	Duration float64 // Unit: second
+	Key      bool    // Whether it starts with a key frame.
+}
+
+// Generate scans the data of the single file and generates a VOD media
+// playlist, the media segments of which have the byte ranges.
+//
+// For fMP4, the media segments are split at the boundaries of the "sidx"
+// references or the "moof" boxes, and EXT-X-MAP refers to the init section
+// with the byte range. For MPEG-TS, they are split at the key frames
+// of the video stream, and EXT-X-MAP refers to the leading PAT and PMT
+// only if they are not repeated at the start of each media segment.
+func (g ByteRangeGenerator) Generate(data []byte) (pl playlist.MediaPlayList, err error) {
+	if g.URI == "" {
+		return pl, errors.New("missing the uri of the file")
+	}
+
+	var xmap playlist.XMap
+	var chunks []chunk
+	var end int
+	if len(data) > 0 && data[0] == ts.SyncByte {
+		chunks, xmap, end, err = g.scanTS(data)
+	} else {
+		chunks, xmap, end, err = g.scanFMP4(data)
+	}
+
+	if err != nil {
+		return
+	} else if len(chunks) == 0 {
+		return pl, errors.New("no media segment is found")
+	}
+
+	target := g.SegmentDuration
+	if target <= 0 {
+		target = DefaultSegmentDuration
+	}
+
+	// Merge the chunks into the media segments.
+	type segment struct {
+		chunk
+		End int
+	}
+
+	segments := make([]segment, 0, len(chunks))
+	for i, c := range chunks {
+		last := len(segments) - 1
+		if i == 0 || (c.Key && segments[last].Duration >= target.Seconds()) {
+			segments = append(segments, segment{chunk: c})
+		} else {
+			segments[last].Duration += c.Duration
+		}
+	}
+	for i := range segments {
+		if i+1 < len(segments) {
+			segments[i].End = segments[i+1].Offset
+		} else {
+			segments[i].End = end
+		}
+	}
+
+	pl.PlayListType = playlist.MediaPlayListTypeVOD
+	pl.EndList = true
+	pl.IndependentSegments = true
+	pl.Segments = make([]playlist.MediaSegment, len(segments))
+	for i, s := range segments {
+		duration := math.Round(s.Duration*1000) / 1000
+		pl.TargetDuration = max(pl.TargetDuration, uint64(duration+0.5), 1)
+		pl.IndependentSegments = pl.IndependentSegments && s.Key
+		pl.Segments[i] = playlist.MediaSegment{
+			URI:       g.URI,
+			Duration:  duration,
+			Map:       xmap,
+			ByteRange: playlist.XByteRange{Offset: uint64(s.Offset), Length: uint64(s.End - s.Offset)},
+		}
+	}
+
+	pl.Version = pl.MinVersion()
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+func (g ByteRangeGenerator) scanFMP4(data []byte) (chunks []chunk, xmap playlist.XMap, end int, err error) {
+	movie, err := fmp4.ParseMovie(data)
+	if err != nil {
+		return
+	}
+
+	track, ok := movie.MainTrack()
+	if !ok {
+		return nil, xmap, 0, errors.New("no track in the movie")
+	} else if track.Timescale == 0 {
+		return nil, xmap, 0, fmt.Errorf("track %d: missing timescale", track.ID)
+	}
+
+	boxes, err := fmp4.ReadBoxes(data)
+	if err != nil {
+		return
+	}
+
+	for _, box := range boxes {
+		if box.Type == "moov" {
+			xmap = playlist.XMap{URI: g.URI, ByteRange: playlist.XByteRange{
+				Length: uint64(box.Offset + len(box.Data)),
+			}}
+			break
+		}
+	}
+
+	if chunks, end, ok = scanSidx(boxes); ok {
+		return
+	}
+
+	// The boxes, such as "styp" and "emsg", before "moof" belong to the fragment.
+	start := -1
+	for _, box := range boxes {
+		switch box.Type {
+		case "styp", "prft", "emsg", "sidx":
+			if start < 0 {
+				start = box.Offset
+			}
+
+		case "moof":
+			if start < 0 {
+				start = box.Offset
+			}
+
+			trafs, err := fmp4.ParseMovieFragment(box, movie.Trexs)
+			if err != nil {
+				return nil, xmap, 0, fmt.Errorf("moof at %d: %w", box.Offset, err)
+			}
+
+			c := chunk{Offset: start, Key: track.Handler != fmp4.HandlerVideo}
+			for _, traf := range trafs {
+				if traf.Header.TrackID != track.ID {
+					continue
+				}
+
+				for i, s := range traf.Samples {
+					if i == 0 && s.IsSync() {
+						c.Key = true
+					}
+					c.Duration += float64(s.Duration) / float64(track.Timescale)
+				}
+			}
+
+			chunks = append(chunks, c)
+			start = -1
+
+		case "mdat":
+			end = box.Offset + len(box.Data)
+		}
+	}
+
+	return
+}
+
+// scanSidx returns the chunks by the references of the top-level "sidx".
+//
+// Return false if there is no "sidx" or it refers to other "sidx" boxes.
+func scanSidx(boxes []fmp4.Box) (chunks []chunk, end int, ok bool) {
+	box, ok := fmp4.FindBox(boxes, "sidx")
+	if !ok {
+		return
+	}
+
+	sidx, err := fmp4.ParseSidx(box)
+	if err != nil || sidx.Timescale == 0 || len(sidx.References) == 0 {
+		return nil, 0, false
+	}
+
+	offset := box.Offset + len(box.Data) + int(sidx.FirstOffset)
+	chunks = make([]chunk, len(sidx.References))
+	for i, ref := range sidx.References {
+		if ref.ReferenceType != 0 {
+			return nil, 0, false
+		}
+
+		chunks[i] = chunk{
+			Offset:   offset,
+			Duration: float64(ref.SubsegmentDuration) / float64(sidx.Timescale),
+			Key:      ref.StartsWithSAP,
+		}
+		offset += int(ref.ReferencedSize)
+	}
+
+	return chunks, offset, true
+}
+
+/// ----------------------------------------------------------------------- ///
+
+func (g ByteRangeGenerator) scanTS(data []byte) (chunks []chunk, xmap playlist.XMap, end int, err error) {
+	packets, err := ts.Packets(data)
+	if err != nil {
+		return
+	}
+
+	var (
+		main    ts.Stream
+		pts     []int64 // The start PTS of each chunk.
+		lastPTS int64   // The maximum PTS of the last chunk.
+		frames  int     // The number of the frames in the last chunk.
+		header  = -1    // The number of the leading PAT and PMT packets.
+		psi     = -1    // The index of the first PSI packet preceding the current packet.
+		repeat  = true  // Whether each chunk starts with PAT and PMT.
+	)
+
+	var programs ts.ProgramTracker
+	for i, p := range packets {
+		switch pid := p.PID(); {
+		case programs.IsPSI(pid):
+			_, pmt, err := programs.Parse(p)
+			if err != nil {
+				return nil, xmap, 0, fmt.Errorf("packet %d: %w", i, err)
+			} else if pmt != nil && main.PID == 0 {
+				main = pmt.MainStream()
+			}
+			if psi < 0 {
+				psi = i
+			}
+			continue
+
+		case pid != main.PID || main.PID == 0 || !p.PayloadUnitStart():
+			if header < 0 && pid != ts.PIDNull {
+				header = i
+			}
+			psi = -1
+			continue
+		}
+
+		if header < 0 {
+			header = i
+		}
+
+		h, err := ts.ParsePESHeader(p.Payload())
+		if err != nil {
+			return nil, xmap, 0, fmt.Errorf("packet %d: %w", i, err)
+		}
+
+		if h.PTS >= 0 && (len(chunks) == 0 || !ts.IsVideo(main.Type) || isKeyFrame(main.Type, p, h)) {
+			start := i
+			if psi >= 0 {
+				start = psi
+			} else if len(chunks) > 0 {
+				repeat = false
+			}
+
+			if len(chunks) == 0 {
+				start = 0
+			}
+
+			chunks = append(chunks, chunk{Offset: start * ts.PacketSize, Key: true})
+			pts = append(pts, h.PTS)
+			lastPTS, frames = 0, 0
+		}
+
+		if h.PTS >= 0 && len(pts) > 0 {
+			lastPTS = max(lastPTS, ts.TimestampDelta(pts[len(pts)-1], h.PTS))
+			frames++
+		}
+		psi = -1
+	}
+
+	if main.PID == 0 {
+		return nil, xmap, 0, errors.New("no video or audio stream in the transport stream")
+	}
+
+	for i := range chunks {
+		var ticks int64
+		if i+1 < len(chunks) {
+			ticks = ts.TimestampDelta(pts[i], pts[i+1])
+		} else if ticks = lastPTS; frames > 1 {
+			ticks += lastPTS / int64(frames-1) // Add the last frame.
+		}
+		chunks[i].Duration = float64(ticks) / ts.ClockRate
+	}
+
+	if !repeat && header > 0 {
+		xmap = playlist.XMap{URI: g.URI, ByteRange: playlist.XByteRange{
+			Length: uint64(header * ts.PacketSize),
+		}}
+	}
+
+	return chunks, xmap, len(packets) * ts.PacketSize, nil
+}
+
+// isKeyFrame reports whether the PES packet starting in the packet p
+// is a key frame, by the random access indicator or the NAL unit type
+// of H.264 IDR or H.265 IRAP.
+func isKeyFrame(streamType uint8, p ts.Packet, h ts.PESHeader) bool {
+	if p.RandomAccessIndicator() {
+		return true
+	}
+
+	payload := p.Payload()
+	if h.Size >= len(payload) {
+		return false
+	}
+
+	payload = payload[h.Size:]
+	for i := 0; i+3 < len(payload); i++ {
+		if payload[i] != 0 || payload[i+1] != 0 || payload[i+2] != 1 {
+			continue
+		}
+
+		switch nal := payload[i+3]; streamType {
+		case ts.StreamTypeH264:
+			if nal&0x1F == 5 {
+				return true
+			}
+
+		case ts.StreamTypeH265:
+			if t := nal >> 1 & 0x3F; t >= 16 && t <= 21 {
+				return true
+			}
+		}
+	}
+	return false
+}
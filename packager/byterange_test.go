@@ -0,0 +1,166 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/fmp4"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func checkSegments(t *testing.T, pl playlist.MediaPlayList, durations []float64, offsets []uint64) {
+	t.Helper()
+	if len(pl.Segments) != len(durations) {
+		t.Fatalf("expect %d segments, but got %d", len(durations), len(pl.Segments))
+	}
+
+	for i, seg := range pl.Segments {
+		if seg.Duration != durations[i] {
+			t.Errorf("%d: expect duration %v, but got %v", i, durations[i], seg.Duration)
+		}
+		if seg.ByteRange.Offset != offsets[i] {
+			t.Errorf("%d: expect offset %d, but got %d", i, offsets[i], seg.ByteRange.Offset)
+		}
+		if i > 0 && pl.Segments[i-1].ByteRange.Offset+pl.Segments[i-1].ByteRange.Length != seg.ByteRange.Offset {
+			t.Errorf("%d: the byte range is not contiguous", i)
+		}
+	}
+}
+
+func newTestInit() []byte {
+	stsd := fmp4.AppendFullBox(nil, "stsd", 0, 0, u32(1), fmp4.AppendBox(nil, "avc1",
+		make([]byte, 78), fmp4.AppendBox(nil, "avcC", []byte{1, 0x64, 0, 0x1F})))
+
+	data := fmp4.AppendBox(nil, "ftyp", []byte("iso6"), u32(0))
+	return fmp4.AppendBox(data, "moov",
+		fmp4.AppendBox(nil, "trak",
+			fmp4.AppendFullBox(nil, "tkhd", 0, 3, u32(0), u32(0), u32(1), make([]byte, 68)),
+			fmp4.AppendBox(nil, "mdia",
+				fmp4.AppendFullBox(nil, "mdhd", 0, 0, u32(0), u32(0), u32(90000), u32(0), u32(0)),
+				fmp4.AppendFullBox(nil, "hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 13)),
+				fmp4.AppendBox(nil, "minf", fmp4.AppendBox(nil, "stbl", stsd)),
+			),
+		),
+		fmp4.AppendBox(nil, "mvex",
+			fmp4.AppendFullBox(nil, "trex", 0, 0, u32(1), u32(1), u32(90000), u32(100), u32(0x10000)),
+		),
+	)
+}
+
+// appendTestFragment appends a movie fragment with 2 samples of 1 second,
+// the first of which is a sync sample.
+func appendTestFragment(data []byte, seq, decodeTime uint32) []byte {
+	moof := func(offset uint32) []byte {
+		return fmp4.AppendBox(nil, "moof",
+			fmp4.AppendFullBox(nil, "mfhd", 0, 0, u32(seq)),
+			fmp4.AppendBox(nil, "traf",
+				fmp4.AppendFullBox(nil, "tfhd", 0, fmp4.TfhdDefaultBaseIsMoof, u32(1)),
+				fmp4.AppendFullBox(nil, "tfdt", 0, 0, u32(decodeTime)),
+				fmp4.AppendFullBox(nil, "trun", 0, fmp4.TrunDataOffsetPresent|fmp4.TrunFirstSampleFlagsPresent,
+					u32(2), u32(offset), u32(0)),
+			),
+		)
+	}
+
+	data = append(data, moof(uint32(len(moof(0))+8))...)
+	return fmp4.AppendBox(data, "mdat", make([]byte, 200))
+}
+
+func TestByteRangeGeneratorFMP4(t *testing.T) {
+	data := newTestInit()
+	initSize := uint64(len(data))
+
+	var offsets []uint64
+	for i := range 3 {
+		offsets = append(offsets, uint64(len(data)))
+		data = appendTestFragment(data, uint32(i+1), uint32(i*180000))
+	}
+
+	g := ByteRangeGenerator{URI: "video.mp4", SegmentDuration: 4 * time.Second}
+	pl, err := g.Generate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkSegments(t, pl, []float64{4, 2}, []uint64{offsets[0], offsets[2]})
+	if last := pl.Segments[1]; last.ByteRange.Offset+last.ByteRange.Length != uint64(len(data)) {
+		t.Errorf("the last segment does not end at the end of the file")
+	}
+
+	xmap := playlist.XMap{URI: "video.mp4", ByteRange: playlist.XByteRange{Length: initSize}}
+	if pl.Segments[0].Map != xmap {
+		t.Errorf("expect map %+v, but got %+v", xmap, pl.Segments[0].Map)
+	}
+
+	if pl.TargetDuration != 4 || pl.Version != 6 || !pl.EndList || !pl.IndependentSegments {
+		t.Errorf("unexpected playlist: target=%d, version=%d, endlist=%v, independent=%v",
+			pl.TargetDuration, pl.Version, pl.EndList, pl.IndependentSegments)
+	}
+
+	var buf bytes.Buffer
+	if err := pl.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestByteRangeGeneratorTS(t *testing.T) {
+	var buf bytes.Buffer
+	w := ts.NewWriter(&buf)
+	_ = w.WritePAT(ts.PAT{Programs: []ts.Program{{Number: 1, PID: 0x1000}}})
+	_ = w.WritePMT(0x1000, ts.PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: []ts.Stream{
+		{Type: ts.StreamTypeH264, PID: 0x100},
+		{Type: ts.StreamTypeAAC, PID: 0x101},
+	}})
+
+	// 25 fps with a key frame per 2 seconds.
+	var offsets []uint64
+	for i := range 150 {
+		pts := int64(i * 3600)
+		if i%50 == 0 {
+			offsets = append(offsets, uint64(buf.Len()))
+		}
+
+		_ = w.WritePES(0x100, ts.AppendPES(nil, 0xE0, pts, -1, []byte{0, 0, 0, 1, 0x09, 0xF0}), pts*300, i%50 == 0)
+		_ = w.WritePES(0x101, ts.AppendPES(nil, 0xC0, pts, -1, []byte{0xFF, 0xF1}), -1, true)
+	}
+
+	g := ByteRangeGenerator{URI: "video.ts", SegmentDuration: 4 * time.Second}
+	pl, err := g.Generate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkSegments(t, pl, []float64{4, 2}, []uint64{0, offsets[2]})
+	if last := pl.Segments[1]; last.ByteRange.Offset+last.ByteRange.Length != uint64(buf.Len()) {
+		t.Errorf("the last segment does not end at the end of the file")
+	}
+
+	// PAT and PMT are not repeated, so EXT-X-MAP refers to them.
+	xmap := playlist.XMap{URI: "video.ts", ByteRange: playlist.XByteRange{Length: 2 * ts.PacketSize}}
+	if pl.Segments[1].Map != xmap {
+		t.Errorf("expect map %+v, but got %+v", xmap, pl.Segments[1].Map)
+	}
+
+	if pl.TargetDuration != 4 {
+		t.Errorf("expect target duration %d, but got %d", 4, pl.TargetDuration)
+	}
+}
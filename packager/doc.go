@@ -0,0 +1,19 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package packager provides some tools to package the HLS renditions,
+// such as encrypting the clear media segments and generating the media
+// playlist with the byte ranges of a single fMP4 or MPEG-TS file
+// and the I-frame playlist, or segmenting the MPEG-TS stream.
+package packager
@@ -0,0 +1,320 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Storage is used to store the output files, such as the encrypted
+// media segments and the key files.
+type Storage interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirStorage is a storage based on the local directory.
+type DirStorage string
+
+// Create implements the interface Storage, which creates the parent
+// directories if not existing.
+func (d DirStorage) Create(name string) (io.WriteCloser, error) {
+	filename := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(filename)
+}
+
+// KeyFormat is an extra KEYFORMAT of the key besides "identity",
+// such as "com.apple.streamingkeydelivery" for FairPlay Streaming.
+type KeyFormat struct {
+	Format   string
+	Versions string
+
+	// URI returns the URI of the key in the key format, such as "skd://key0".
+	//
+	// Default: the URI of the key file
+	URI func(key Key) string
+}
+
+// Key is a content key used to encrypt the media segments.
+type Key struct {
+	Index int    // The index of the key, starting with 0.
+	Name  string // The name of the key file in the storage.
+	URI   string // The URI of the key file in the playlist.
+	Data  []byte // The 16-octet key.
+
+	// The number of the media segments encrypted by the key,
+	// and the index of the first one.
+	First, Count int
+}
+
+// Encryptor is used to encrypt the clear media segments by AES-128,
+// which rotates the keys periodically.
+type Encryptor struct {
+	// RotateSegments is the number of the media segments encrypted
+	// by a key before rotating it. 0 means no rotation by the segment count.
+	RotateSegments int
+
+	// RotateDuration is the total duration of the media segments encrypted
+	// by a key before rotating it. 0 means no rotation by the duration.
+	RotateDuration time.Duration
+
+	// If true, generate a random IV for each media segment, which is
+	// written into the IV attribute of its EXT-X-KEY. Or, the IV is derived
+	// from the media sequence number of each media segment.
+	//
+	// Each media segment has its own IV either way, because reusing
+	// an IV with the same key under CBC leaks the equal leading blocks.
+	ExplicitIV bool
+
+	// KeyFormats is the extra KEYFORMATs of each key,
+	// the EXT-X-KEY tags of which are added after the "identity" one.
+	KeyFormats []KeyFormat
+
+	// NewKey generates a new 16-octet key.
+	//
+	// Default: read from crypto/rand
+	NewKey func() ([]byte, error)
+
+	// KeyName returns the name of the key file with the key index.
+	//
+	// Default: fmt.Sprintf("key%d.key", index)
+	KeyName func(index int) string
+
+	// KeyURI returns the URI of the key file in the playlist.
+	//
+	// Default: the name of the key file
+	KeyURI func(name string) string
+
+	// SegmentName returns the name of the encrypted media segment file
+	// in the storage with the original URI.
+	//
+	// Default: the original URI
+	SegmentName func(uri string) string
+}
+
+func newRandom() ([]byte, error) {
+	data := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, data)
+	return data, err
+}
+
+// Encrypt reads the clear media segments of the media playlist from src
+// by their URIs, encrypts and writes them into dst with the keys,
+// which are also written into dst.
+//
+// It returns a new media playlist with the EXT-X-KEY tags and the keys.
+// The media segments with the byte range and the ones already encrypted
+// are not supported.
+func (e Encryptor) Encrypt(pl playlist.MediaPlayList, src fs.FS, dst Storage) (
+	out playlist.MediaPlayList, keys []Key, err error) {
+	out = pl
+	out.Segments = make([]playlist.MediaSegment, len(pl.Segments))
+	out.Version = 0
+
+	var key Key
+	var duration time.Duration
+	for i, seg := range pl.Segments {
+		if !seg.ByteRange.IsZero() {
+			return out, nil, fmt.Errorf("segment %d: unsupported byte range", i)
+		} else if len(seg.Keys) > 0 && seg.Keys[0].Method != playlist.XKeyMethodNone {
+			return out, nil, fmt.Errorf("segment %d: already encrypted", i)
+		}
+
+		// The implicit IV is derived from the Media Sequence Number,
+		// which is not set for the media playlist built by hand.
+		seg.MediaSequence = pl.MediaSequence + pl.Skip.SkippedSegments + uint64(i)
+
+		if i == 0 || e.rotate(key.Count, duration) {
+			if key, err = e.newKey(len(keys), i, dst); err != nil {
+				return out, nil, err
+			}
+			keys = append(keys, key)
+			duration = 0
+		}
+
+		if seg.Keys, err = e.xkeys(key); err != nil {
+			return out, nil, fmt.Errorf("segment %d: %w", i, err)
+		} else if err = e.encrypt(seg, key, src, dst); err != nil {
+			return out, nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+
+		out.Segments[i] = seg
+		keys[len(keys)-1].Count++
+		key.Count++
+		duration += time.Duration(seg.Duration * float64(time.Second))
+	}
+
+	out.Version = max(pl.Version, out.MinVersion())
+	return
+}
+
+func (e Encryptor) rotate(count int, duration time.Duration) bool {
+	return (e.RotateSegments > 0 && count >= e.RotateSegments) ||
+		(e.RotateDuration > 0 && duration >= e.RotateDuration)
+}
+
+func (e Encryptor) newKey(index, first int, dst Storage) (key Key, err error) {
+	key = Key{Index: index, First: first}
+	if e.NewKey != nil {
+		key.Data, err = e.NewKey()
+	} else {
+		key.Data, err = newRandom()
+	}
+
+	if err != nil {
+		return key, fmt.Errorf("fail to generate key %d: %w", index, err)
+	} else if len(key.Data) != 16 {
+		return key, fmt.Errorf("invalid key %d: not 16-octet", index)
+	}
+
+	if e.KeyName != nil {
+		key.Name = e.KeyName(index)
+	} else {
+		key.Name = fmt.Sprintf("key%d.key", index)
+	}
+
+	if e.KeyURI != nil {
+		key.URI = e.KeyURI(key.Name)
+	} else {
+		key.URI = key.Name
+	}
+
+	err = writeFile(dst, key.Name, func(w io.Writer) error {
+		_, err := w.Write(key.Data)
+		return err
+	})
+	return
+}
+
+// xkeys returns the EXT-X-KEY tags of a media segment encrypted by key.
+func (e Encryptor) xkeys(key Key) ([]playlist.XKey, error) {
+	var iv string
+	if e.ExplicitIV {
+		data, err := newRandom()
+		if err != nil {
+			return nil, fmt.Errorf("fail to generate the iv: %w", err)
+		}
+		iv = playlist.FormatIV(data, true)
+	}
+
+	xkeys := make([]playlist.XKey, 0, 1+len(e.KeyFormats))
+	xkeys = append(xkeys, playlist.XKey{Method: playlist.XKeyMethodAES128, URI: key.URI, IV: iv})
+	for _, f := range e.KeyFormats {
+		uri := key.URI
+		if f.URI != nil {
+			uri = f.URI(key)
+		}
+
+		xkeys = append(xkeys, playlist.XKey{
+			Method:  playlist.XKeyMethodAES128,
+			URI:     uri,
+			IV:      iv,
+			Format:  f.Format,
+			Version: f.Versions,
+		})
+	}
+	return xkeys, nil
+}
+
+func (e Encryptor) encrypt(seg playlist.MediaSegment, key Key, src fs.FS, dst Storage) (err error) {
+	iv, err := seg.IV()
+	if err != nil {
+		return
+	}
+
+	name := seg.URI
+	if e.SegmentName != nil {
+		name = e.SegmentName(seg.URI)
+	}
+
+	r, err := src.Open(path.Clean(seg.URI))
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	return writeFile(dst, name, func(w io.Writer) error {
+		ew, err := aes128.NewEncryptWriter(w, key.Data, iv)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(ew, r); err != nil {
+			return err
+		}
+		return ew.Close()
+	})
+}
+
+func writeFile(dst Storage, name string, write func(io.Writer) error) (err error) {
+	w, err := dst.Create(name)
+	if err != nil {
+		return
+	}
+
+	err = write(w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		err = fmt.Errorf("fail to write '%s': %w", name, err)
+	}
+	return
+}
+
+// AddSessionKeys adds the EXT-X-SESSION-KEY tags into the master playlist,
+// which allows the client to preload the keys, such as the first keys
+// of the media playlists encrypted by Encryptor.
+//
+// The duplicated keys are ignored.
+func AddSessionKeys(master *playlist.MasterPlayList, keys ...playlist.XKey) error {
+	if len(master.Streams) == 0 {
+		return errors.New("no variant stream in the master playlist")
+	}
+
+	exists := make(map[playlist.XKey]struct{}, len(keys))
+	for _, s := range master.Streams {
+		for _, key := range s.SessionKeys {
+			exists[key] = struct{}{}
+		}
+	}
+
+	for _, key := range keys {
+		if key.Method == "" || key.Method == playlist.XKeyMethodNone {
+			continue
+		} else if _, ok := exists[key]; ok {
+			continue
+		}
+
+		exists[key] = struct{}{}
+		master.Streams[0].SessionKeys = append(master.Streams[0].SessionKeys, key)
+	}
+
+	return nil
+}
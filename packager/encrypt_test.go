@@ -0,0 +1,240 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
+)
+
+type memStorage map[string]*bytes.Buffer
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func (s memStorage) Create(name string) (io.WriteCloser, error) {
+	buf := new(bytes.Buffer)
+	s[name] = buf
+	return nopCloser{buf}, nil
+}
+
+func TestEncryptor(t *testing.T) {
+	src := make(fstest.MapFS)
+	pl := playlist.MediaPlayList{TargetDuration: 10, MediaSequence: 100, EndList: true}
+	for i := range 7 {
+		uri := fmt.Sprintf("seg%d.ts", i)
+		src[uri] = &fstest.MapFile{Data: bytes.Repeat([]byte{byte(i)}, 1000+i)}
+		pl.Segments = append(pl.Segments, playlist.MediaSegment{URI: uri, Duration: 4})
+	}
+	pl.Segments[0].Duration = 10
+
+	var index byte
+	e := Encryptor{
+		RotateSegments: 3,
+		RotateDuration: 12 * time.Second,
+		KeyURI:         func(name string) string { return "https://example.com/keys/" + name },
+		KeyFormats: []KeyFormat{{
+			Format:   "com.apple.streamingkeydelivery",
+			Versions: "1",
+			URI:      func(key Key) string { return fmt.Sprintf("skd://key%d", key.Index) },
+		}},
+		NewKey: func() ([]byte, error) {
+			index++
+			return bytes.Repeat([]byte{index}, 16), nil
+		},
+	}
+
+	dst := make(memStorage)
+	out, keys, err := e.Encrypt(pl, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10+4 => 4+4+4 => 4
+	expects := []struct{ First, Count int }{{0, 2}, {2, 3}, {5, 2}}
+	if len(keys) != len(expects) {
+		t.Fatalf("expect %d keys, but got %d", len(expects), len(keys))
+	}
+	for i, key := range keys {
+		if key.First != expects[i].First || key.Count != expects[i].Count {
+			t.Errorf("key %d: expect first=%d and count=%d, but got first=%d and count=%d",
+				i, expects[i].First, expects[i].Count, key.First, key.Count)
+		}
+
+		if buf, ok := dst[key.Name]; !ok {
+			t.Errorf("missing the key file '%s'", key.Name)
+		} else if !bytes.Equal(buf.Bytes(), key.Data) {
+			t.Errorf("the key file '%s' is not equal to the key", key.Name)
+		}
+	}
+
+	for i, seg := range out.Segments {
+		key := keys[0]
+		for _, k := range keys {
+			if i >= k.First {
+				key = k
+			}
+		}
+
+		if len(seg.Keys) != 2 {
+			t.Errorf("segment %d: expect %d keys, but got %d", i, 2, len(seg.Keys))
+		} else if seg.Keys[0].URI != "https://example.com/keys/"+key.Name {
+			t.Errorf("segment %d: unexpected key uri '%s'", i, seg.Keys[0].URI)
+		} else if uri := fmt.Sprintf("skd://key%d", key.Index); seg.Keys[1].URI != uri {
+			t.Errorf("segment %d: expect key uri '%s', but got '%s'", i, uri, seg.Keys[1].URI)
+		}
+
+		decrypted, err := seg.AES128Decrypt(dst[seg.URI].Bytes(), key.Data, true)
+		if err != nil {
+			t.Errorf("segment %d: %v", i, err)
+		} else if !bytes.Equal(decrypted, src[seg.URI].Data) {
+			t.Errorf("segment %d: the decrypted data is not equal to the original", i)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := out.Output(&buf); err != nil {
+		t.Fatal(err)
+	} else if n := strings.Count(buf.String(), "#EXT-X-KEY:"); n != 6 {
+		t.Errorf("expect %d EXT-X-KEY tags, but got %d", 6, n)
+	} else if out.Version != 5 {
+		t.Errorf("expect version %d, but got %d", 5, out.Version)
+	}
+
+	master := playlist.MasterPlayList{Streams: []playlist.MasterStream{
+		{Stream: playlist.XStreamInf{URI: "low.m3u8", Bandwidth: 1000}},
+		{Stream: playlist.XStreamInf{URI: "high.m3u8", Bandwidth: 2000}},
+	}}
+	if err := AddSessionKeys(&master, out.Segments[0].Keys...); err != nil {
+		t.Fatal(err)
+	}
+	_ = AddSessionKeys(&master, out.Segments[0].Keys...)
+	if n := len(master.Streams[0].SessionKeys); n != 2 {
+		t.Errorf("expect %d session keys, but got %d", 2, n)
+	}
+}
+
+func TestEncryptorImplicitIV(t *testing.T) {
+	src := make(fstest.MapFS)
+	pl := playlist.MediaPlayList{TargetDuration: 10, MediaSequence: 100, EndList: true}
+	for i := range 3 {
+		uri := fmt.Sprintf("seg%d.ts", i)
+		src[uri] = &fstest.MapFile{Data: []byte("0123456789abcdef")}
+		pl.Segments = append(pl.Segments, playlist.MediaSegment{URI: uri, Duration: 10})
+	}
+
+	dst := make(memStorage)
+	out, keys, err := Encryptor{}.Encrypt(pl, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := out.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed playlist.MediaPlayList
+	if err := parsed.Parse(&buf); err != nil {
+		t.Fatal(err)
+	} else if len(parsed.Segments) != len(pl.Segments) {
+		t.Fatalf("expect %d segments, but got %d", len(pl.Segments), len(parsed.Segments))
+	}
+
+	ciphers := make(map[string]struct{}, len(parsed.Segments))
+	for i, seg := range parsed.Segments {
+		if seg.Keys[0].IV != "" {
+			t.Errorf("%d: expect no explicit iv, but got '%s'", i, seg.Keys[0].IV)
+		}
+
+		encrypted := dst[seg.URI].Bytes()
+		ciphers[string(encrypted)] = struct{}{}
+
+		if data, err := seg.AES128Decrypt(encrypted, keys[0].Data, true); err != nil {
+			t.Errorf("%d: %v", i, err)
+		} else if expect := string(src[seg.URI].Data); string(data) != expect {
+			t.Errorf("%d: expect decrypted data '%s', but got '%s'", i, expect, data)
+		}
+	}
+
+	if len(ciphers) != len(parsed.Segments) {
+		t.Errorf("expect %d different encrypted segments, but got %d", len(parsed.Segments), len(ciphers))
+	}
+}
+
+func TestEncryptorExplicitIV(t *testing.T) {
+	src := fstest.MapFS{
+		"0.ts": &fstest.MapFile{Data: []byte("0123456789")},
+		"1.ts": &fstest.MapFile{Data: []byte("9876543210")},
+	}
+	pl := playlist.MediaPlayList{TargetDuration: 10, Segments: []playlist.MediaSegment{
+		{URI: "0.ts", Duration: 10},
+		{URI: "1.ts", Duration: 10},
+	}}
+
+	dst := make(memStorage)
+	out, keys, err := Encryptor{
+		ExplicitIV:  true,
+		KeyFormats:  []KeyFormat{{Format: "com.example"}},
+		SegmentName: func(uri string) string { return "enc/" + uri },
+	}.Encrypt(pl, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 1 {
+		t.Fatalf("expect %d key, but got %d", 1, len(keys))
+	}
+
+	ivs := make(map[string]struct{}, len(out.Segments))
+	for i, seg := range out.Segments {
+		if seg.Keys[0].IV == "" {
+			t.Errorf("%d: expect an explicit iv, but got nothing", i)
+		} else if seg.Keys[1].IV != seg.Keys[0].IV {
+			t.Errorf("%d: expect iv '%s', but got '%s'", i, seg.Keys[0].IV, seg.Keys[1].IV)
+		}
+		if seg.Keys[1].URI != keys[0].URI {
+			t.Errorf("%d: expect key uri '%s', but got '%s'", i, keys[0].URI, seg.Keys[1].URI)
+		}
+		ivs[seg.Keys[0].IV] = struct{}{}
+
+		iv, err := seg.IV()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data, err := aes128.Decrypt(dst["enc/"+seg.URI].Bytes(), keys[0].Data, iv, true); err != nil {
+			t.Error(err)
+		} else if expect := string(src[seg.URI].Data); string(data) != expect {
+			t.Errorf("%d: expect decrypted data '%s', but got '%s'", i, expect, data)
+		}
+	}
+
+	if len(ivs) != len(out.Segments) {
+		t.Errorf("expect %d different ivs, but got %d", len(out.Segments), len(ivs))
+	}
+
+	pl.Segments[0].ByteRange = playlist.XByteRange{Length: 5}
+	if _, _, err := (Encryptor{}).Encrypt(pl, src, dst); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
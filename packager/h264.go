@@ -0,0 +1,199 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errInvalidSPS = errors.New("invalid h264 sps")
+
+// findH264SPS finds the first H.264 SPS NAL unit in the Annex B byte stream,
+// and returns its RBSP without the NAL header.
+func findH264SPS(es []byte) ([]byte, bool) {
+	for i := 0; i+3 < len(es); i++ {
+		if es[i] != 0 || es[i+1] != 0 || es[i+2] != 1 || es[i+3]&0x1F != 7 {
+			continue
+		}
+
+		nal := es[i+4:]
+		for j := 0; j+2 < len(nal); j++ {
+			if nal[j] == 0 && nal[j+1] == 0 && nal[j+2] <= 1 {
+				nal = nal[:j]
+				break
+			}
+		}
+
+		// Remove the emulation prevention bytes.
+		rbsp := make([]byte, 0, len(nal))
+		for j := 0; j < len(nal); j++ {
+			if j >= 2 && nal[j] == 3 && nal[j-1] == 0 && nal[j-2] == 0 {
+				continue
+			}
+			rbsp = append(rbsp, nal[j])
+		}
+		return rbsp, true
+	}
+	return nil, false
+}
+
+// h264Codec returns the codec string, such as "avc1.64001f", from the SPS.
+func h264Codec(sps []byte) string {
+	if len(sps) < 3 {
+		return ""
+	}
+	return fmt.Sprintf("avc1.%02x%02x%02x", sps[0], sps[1], sps[2])
+}
+
+// h264Resolution parses the width and height of the picture from the SPS.
+func h264Resolution(sps []byte) (width, height int, err error) {
+	if len(sps) < 4 {
+		return 0, 0, errInvalidSPS
+	}
+
+	r := bitReader{data: sps[3:]}
+	r.ue() // seq_parameter_set_id
+
+	chroma := 1
+	switch profile := sps[0]; profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		if chroma = r.ue(); chroma == 3 && r.bit() == 1 { // separate_colour_plane_flag
+			chroma = 0
+		}
+
+		r.ue()  // bit_depth_luma_minus8
+		r.ue()  // bit_depth_chroma_minus8
+		r.bit() // qpprime_y_zero_transform_bypass_flag
+
+		if r.bit() == 1 { // seq_scaling_matrix_present_flag
+			count := 8
+			if chroma == 3 {
+				count = 12
+			}
+
+			for i := range count {
+				if r.bit() == 1 {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					r.skipScalingList(size)
+				}
+			}
+		}
+	}
+
+	r.ue() // log2_max_frame_num_minus4
+
+	switch r.ue() { // pic_order_cnt_type
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.bit() // delta_pic_order_always_zero_flag
+		r.se()  // offset_for_non_ref_pic
+		r.se()  // offset_for_top_to_bottom_field
+		for range r.ue() {
+			r.se() // offset_for_ref_frame
+		}
+	}
+
+	r.ue()  // max_num_ref_frames
+	r.bit() // gaps_in_frame_num_value_allowed_flag
+	widthInMbs := r.ue() + 1
+	heightInMapUnits := r.ue() + 1
+	frameMbsOnly := r.bit()
+	if frameMbsOnly == 0 {
+		r.bit() // mb_adaptive_frame_field_flag
+	}
+	r.bit() // direct_8x8_inference_flag
+
+	var left, right, top, bottom int
+	if r.bit() == 1 { // frame_cropping_flag
+		left, right, top, bottom = r.ue(), r.ue(), r.ue(), r.ue()
+	}
+
+	if r.err != nil {
+		return 0, 0, errInvalidSPS
+	}
+
+	cropX, cropY := 1, 2-frameMbsOnly
+	switch chroma {
+	case 1:
+		cropX, cropY = 2, 2*(2-frameMbsOnly)
+	case 2:
+		cropX = 2
+	}
+
+	width = widthInMbs*16 - cropX*(left+right)
+	height = (2-frameMbsOnly)*heightInMapUnits*16 - cropY*(top+bottom)
+	return
+}
+
+// bitReader is a reader to read the bits, which records the first error.
+type bitReader struct {
+	data []byte
+	pos  int // The bit position.
+	err  error
+}
+
+func (r *bitReader) bit() int {
+	if r.pos >= len(r.data)*8 {
+		r.err = errInvalidSPS
+		return 0
+	}
+
+	bit := int(r.data[r.pos/8]>>(7-r.pos%8)) & 1
+	r.pos++
+	return bit
+}
+
+// ue reads an unsigned Exp-Golomb code.
+func (r *bitReader) ue() int {
+	var zeros int
+	for r.bit() == 0 && r.err == nil {
+		if zeros++; zeros > 31 {
+			r.err = errInvalidSPS
+			return 0
+		}
+	}
+
+	value := 1
+	for range zeros {
+		value = value<<1 | r.bit()
+	}
+	return value - 1
+}
+
+// se reads a signed Exp-Golomb code.
+func (r *bitReader) se() int {
+	v := r.ue()
+	if v%2 == 0 {
+		return -v / 2
+	}
+	return (v + 1) / 2
+}
+
+func (r *bitReader) skipScalingList(size int) {
+	last, next := 8, 8
+	for range size {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
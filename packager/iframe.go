@@ -0,0 +1,410 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/xgfone/go-hls/fmp4"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+// IFrameGenerator is used to generate the I-frame playlist of a media
+// playlist, the media segments of which are MPEG-TS or fMP4.
+type IFrameGenerator struct {
+	// Load returns the data of the media segment. It is also used to load
+	// the init section referred by EXT-X-MAP, which is passed as a media
+	// segment with the URI and the byte range of EXT-X-MAP.
+	//
+	// If the media segment has the byte range, it should return the data
+	// in the byte range, not the whole resource.
+	Load func(seg playlist.MediaSegment) ([]byte, error)
+}
+
+// iframe is an I-frame located in a media segment.
+type iframe struct {
+	Offset, Length int     // The byte range in the media segment.
+	Time           float64 // The time relative to the media segment. Unit: second
+}
+
+// iframeTrack is the information of the video stream of the I-frames.
+type iframeTrack struct {
+	Codec         string
+	Width, Height int
+}
+
+// Generate locates the I-frames, that's, the IDR frames of MPEG-TS or the sync
+// samples of fMP4, in the media segments of the media playlist, and returns
+// the I-frame playlist with EXT-X-I-FRAMES-ONLY, the byte range of each
+// I-frame and the duration until the next I-frame.
+//
+// It also returns the EXT-X-I-FRAME-STREAM-INF with the bandwidth, the codec
+// and the resolution of the I-frames, but without URI, which should be
+// added into the master playlist by AddIFrameStream.
+func (g IFrameGenerator) Generate(pl playlist.MediaPlayList) (
+	out playlist.MediaPlayList, stream playlist.XIFrameStreamInf, err error) {
+	if g.Load == nil {
+		return out, stream, errors.New("missing the function to load the media segments")
+	}
+
+	type entry struct {
+		playlist.MediaSegment
+		Time float64 // The time relative to the media playlist.
+	}
+
+	var (
+		track         iframeTrack
+		entries       []entry
+		start         float64
+		discontinuity bool
+		movies        = make(map[playlist.XMap]fmp4.Movie, 1)
+	)
+
+	for i, seg := range pl.Segments {
+		discontinuity = discontinuity || seg.Discontinuity
+
+		data, err := g.Load(seg)
+		if err != nil {
+			return out, stream, fmt.Errorf("segment %d: %w", i, err)
+		}
+
+		var xmap playlist.XMap
+		var iframes []iframe
+		if len(data) > 0 && data[0] == ts.SyncByte {
+			iframes, xmap, err = scanIFramesTS(data, seg, &track)
+		} else {
+			movie, ok := movies[seg.Map]
+			if !ok {
+				if movie, err = g.loadMovie(seg.Map); err != nil {
+					return out, stream, fmt.Errorf("segment %d: %w", i, err)
+				}
+				movies[seg.Map] = movie
+			}
+			xmap = seg.Map
+			iframes, err = scanIFramesFMP4(data, movie, &track)
+		}
+
+		if err != nil {
+			return out, stream, fmt.Errorf("segment %d: %w", i, err)
+		}
+
+		for _, f := range iframes {
+			entries = append(entries, entry{
+				Time: start + f.Time,
+				MediaSegment: playlist.MediaSegment{
+					URI:           seg.URI,
+					Map:           xmap,
+					Discontinuity: discontinuity,
+					ByteRange: playlist.XByteRange{
+						Offset: seg.ByteRange.Offset + uint64(f.Offset),
+						Length: uint64(f.Length),
+					},
+				},
+			})
+			discontinuity = false
+		}
+
+		start += seg.Duration
+	}
+
+	if len(entries) == 0 {
+		return out, stream, errors.New("no I-frame is found")
+	}
+
+	out.IFrameOnly = true
+	out.EndList = pl.EndList
+	out.PlayListType = pl.PlayListType
+	out.MediaSequence = pl.MediaSequence
+	out.DiscontinuitySequence = pl.DiscontinuitySequence
+	out.Segments = make([]playlist.MediaSegment, len(entries))
+
+	var size uint64
+	for i, e := range entries {
+		end := start
+		if i+1 < len(entries) {
+			end = entries[i+1].Time
+		}
+
+		e.Duration = math.Round((end-e.Time)*1000) / 1000
+		out.TargetDuration = max(out.TargetDuration, uint64(e.Duration+0.5), 1)
+		out.Segments[i] = e.MediaSegment
+
+		size += e.ByteRange.Length
+		if e.Duration > 0 {
+			bandwidth := float64(e.ByteRange.Length*8) / e.Duration
+			stream.Bandwidth = max(stream.Bandwidth, uint64(math.Ceil(bandwidth)))
+		}
+	}
+	out.Version = out.MinVersion()
+
+	if start > 0 {
+		stream.AverageBandwidth = uint64(math.Ceil(float64(size*8) / start))
+	}
+	if track.Codec != "" {
+		stream.Codecs = []string{track.Codec}
+	}
+	if track.Width > 0 && track.Height > 0 {
+		stream.Resolution = playlist.XResolution{Width: uint64(track.Width), Height: uint64(track.Height)}
+	}
+
+	return
+}
+
+func (g IFrameGenerator) loadMovie(xmap playlist.XMap) (movie fmp4.Movie, err error) {
+	if xmap.IsZero() {
+		return movie, errors.New("missing EXT-X-MAP for the fMP4 segment")
+	}
+
+	init, err := g.Load(playlist.MediaSegment{URI: xmap.URI, ByteRange: xmap.ByteRange})
+	if err != nil {
+		return movie, fmt.Errorf("fail to load the init section '%s': %w", xmap.URI, err)
+	}
+
+	if movie, err = fmp4.ParseMovie(init); err != nil {
+		return movie, fmt.Errorf("invalid init section '%s': %w", xmap.URI, err)
+	}
+	return
+}
+
+// AddIFrameStream adds the EXT-X-I-FRAME-STREAM-INF into the master playlist
+// after the variant stream with the URI, which replaces the existed one
+// with the same URI.
+//
+// If the VIDEO attribute is empty, it is inherited from the variant stream.
+func AddIFrameStream(master *playlist.MasterPlayList, variantURI string, iframe playlist.XIFrameStreamInf) error {
+	if iframe.URI == "" {
+		return errors.New("missing the uri of the I-frame stream")
+	}
+
+	for i := range master.Streams {
+		if master.Streams[i].Stream.URI != variantURI {
+			continue
+		}
+
+		s := &master.Streams[i]
+		if iframe.Video == "" {
+			iframe.Video = s.Stream.Video
+		}
+
+		for j := range s.IFrameStreams {
+			if s.IFrameStreams[j].URI == iframe.URI {
+				s.IFrameStreams[j] = iframe
+				return nil
+			}
+		}
+
+		s.IFrameStreams = append(s.IFrameStreams, iframe)
+		return nil
+	}
+
+	return fmt.Errorf("not found the variant stream '%s'", variantURI)
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// scanIFramesTS locates the I-frames in the transport stream segment.
+//
+// The byte range of an I-frame starts at the PAT and PMT preceding it,
+// or else EXT-X-MAP refers to the leading PAT and PMT of the segment.
+func scanIFramesTS(data []byte, seg playlist.MediaSegment, track *iframeTrack) (
+	iframes []iframe, xmap playlist.XMap, err error) {
+	packets, err := ts.Packets(data)
+	if err != nil {
+		return
+	}
+
+	var (
+		main    ts.Stream
+		pts     []int64 // The PTS of each I-frame.
+		minPTS  int64   // The minimum PTS of the video stream.
+		hasPTS  bool
+		current = -1 // The index of the current I-frame.
+		header  = -1 // The number of the leading PAT and PMT packets.
+		psi     = -1 // The index of the first PSI packet preceding the current packet.
+		repeat  = true
+		es      []byte // The elementary stream of the first I-frame.
+	)
+
+	var programs ts.ProgramTracker
+	for i, p := range packets {
+		switch pid := p.PID(); {
+		case programs.IsPSI(pid):
+			_, pmt, err := programs.Parse(p)
+			if err != nil {
+				return nil, xmap, fmt.Errorf("packet %d: %w", i, err)
+			} else if pmt != nil && main.PID == 0 {
+				if main = pmt.MainStream(); main.PID != 0 && !ts.IsVideo(main.Type) {
+					return nil, xmap, errors.New("no video stream in the transport stream")
+				}
+			}
+			if psi < 0 {
+				psi = i
+			}
+			continue
+
+		case pid != main.PID || main.PID == 0:
+			if header < 0 && pid != ts.PIDNull {
+				header = i
+			}
+			psi = -1
+			continue
+		}
+
+		if header < 0 {
+			header = i
+		}
+
+		if !p.PayloadUnitStart() {
+			if current >= 0 {
+				iframes[current].Length = (i+1)*ts.PacketSize - iframes[current].Offset
+				if current == 0 && track.Codec == "" {
+					es = append(es, p.Payload()...)
+				}
+			}
+			psi = -1
+			continue
+		}
+
+		h, err := ts.ParsePESHeader(p.Payload())
+		if err != nil {
+			return nil, xmap, fmt.Errorf("packet %d: %w", i, err)
+		}
+
+		if h.PTS >= 0 {
+			if !hasPTS || ts.TimestampDelta(minPTS, h.PTS) < 0 {
+				minPTS, hasPTS = h.PTS, true
+			}
+		}
+
+		current = -1
+		if h.PTS >= 0 && isKeyFrame(main.Type, p, h) {
+			start := i
+			if psi >= 0 {
+				start = psi
+			} else {
+				repeat = false
+			}
+
+			current = len(iframes)
+			iframes = append(iframes, iframe{Offset: start * ts.PacketSize, Length: (i + 1 - start) * ts.PacketSize})
+			pts = append(pts, h.PTS)
+			if current == 0 && track.Codec == "" && h.Size < len(p.Payload()) {
+				es = append(es, p.Payload()[h.Size:]...)
+			}
+		}
+		psi = -1
+	}
+
+	if main.PID == 0 {
+		return nil, xmap, errors.New("no video stream in the transport stream")
+	}
+
+	for i := range iframes {
+		iframes[i].Time = float64(ts.TimestampDelta(minPTS, pts[i])) / ts.ClockRate
+	}
+
+	if track.Codec == "" && main.Type == ts.StreamTypeH264 {
+		if sps, ok := findH264SPS(es); ok {
+			track.Codec = h264Codec(sps)
+			track.Width, track.Height, _ = h264Resolution(sps)
+		}
+	}
+
+	if !repeat && header > 0 {
+		xmap = playlist.XMap{URI: seg.URI, ByteRange: playlist.XByteRange{
+			Offset: seg.ByteRange.Offset,
+			Length: uint64(header * ts.PacketSize),
+		}}
+	}
+
+	return
+}
+
+// scanIFramesFMP4 locates the sync samples of the video track in the fMP4
+// media segment. The byte range of an I-frame starts at the movie fragment
+// containing it, and ends at the end of the sample.
+func scanIFramesFMP4(data []byte, movie fmp4.Movie, track *iframeTrack) (iframes []iframe, err error) {
+	main, ok := movie.MainTrack()
+	if !ok || main.Handler != fmp4.HandlerVideo {
+		return nil, errors.New("no video track in the init section")
+	} else if main.Timescale == 0 {
+		return nil, fmt.Errorf("track %d: missing timescale", main.ID)
+	}
+
+	if track.Codec == "" {
+		track.Codec = main.Codec
+		track.Width, track.Height = int(main.Width), int(main.Height)
+	}
+
+	boxes, err := fmp4.ReadBoxes(data)
+	if err != nil {
+		return
+	}
+
+	var base uint64
+	var hasBase bool
+	start := -1
+	for _, box := range boxes {
+		switch box.Type {
+		case "styp", "prft", "emsg", "sidx":
+			if start < 0 {
+				start = box.Offset
+			}
+			continue
+
+		case "moof":
+		default:
+			continue
+		}
+
+		if start < 0 {
+			start = box.Offset
+		}
+
+		trafs, err := fmp4.ParseMovieFragment(box, movie.Trexs)
+		if err != nil {
+			return nil, fmt.Errorf("moof at %d: %w", box.Offset, err)
+		}
+
+		for _, traf := range trafs {
+			if traf.Header.TrackID != main.ID {
+				continue
+			}
+
+			if !hasBase {
+				base, hasBase = traf.BaseMediaDecodeTime, true
+			}
+
+			dts := traf.BaseMediaDecodeTime
+			for _, s := range traf.Samples {
+				if s.IsSync() && s.Offset >= start && s.Offset+int(s.Size) <= len(data) {
+					iframes = append(iframes, iframe{
+						Offset: start,
+						Length: s.Offset + int(s.Size) - start,
+						Time:   float64(int64(dts-base)) / float64(main.Timescale),
+					})
+				}
+				dts += uint64(s.Duration)
+			}
+		}
+
+		start = -1
+	}
+
+	return
+}
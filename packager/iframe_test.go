@@ -0,0 +1,242 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+type bitWriter struct {
+	data []byte
+	n    int
+}
+
+func (w *bitWriter) bits(v uint, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+func (w *bitWriter) ue(v uint) {
+	v++
+	n := 0
+	for x := v; x > 1; x >>= 1 {
+		n++
+	}
+	w.bits(0, n)
+	w.bits(v, n+1)
+}
+
+// newTestSPS returns a baseline SPS NAL unit of 1920x1080.
+func newTestSPS() []byte {
+	var w bitWriter
+	w.bits(0x67, 8) // NAL header
+	w.bits(66, 8)   // profile_idc
+	w.bits(0xC0, 8) // constraint flags
+	w.bits(40, 8)   // level_idc
+	w.ue(0)         // seq_parameter_set_id
+	w.ue(0)         // log2_max_frame_num_minus4
+	w.ue(0)         // pic_order_cnt_type
+	w.ue(0)         // log2_max_pic_order_cnt_lsb_minus4
+	w.ue(1)         // max_num_ref_frames
+	w.bits(0, 1)    // gaps_in_frame_num_value_allowed_flag
+	w.ue(119)       // pic_width_in_mbs_minus1
+	w.ue(67)        // pic_height_in_map_units_minus1
+	w.bits(1, 1)    // frame_mbs_only_flag
+	w.bits(1, 1)    // direct_8x8_inference_flag
+	w.bits(1, 1)    // frame_cropping_flag
+	w.ue(0)         // left
+	w.ue(0)         // right
+	w.ue(0)         // top
+	w.ue(4)         // bottom
+	w.bits(0, 1)    // vui_parameters_present_flag
+	w.bits(1, 1)    // rbsp_stop_one_bit
+	return w.data
+}
+
+// newTestTSSegment returns a transport stream segment of 25 fps,
+// which has a key frame per second.
+func newTestTSSegment(startPTS int64, frames int) []byte {
+	var buf bytes.Buffer
+	w := ts.NewWriter(&buf)
+	_ = w.WritePAT(ts.PAT{Programs: []ts.Program{{Number: 1, PID: 0x1000}}})
+	_ = w.WritePMT(0x1000, ts.PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: []ts.Stream{
+		{Type: ts.StreamTypeH264, PID: 0x100},
+	}})
+
+	sps := append([]byte{0, 0, 0, 1}, newTestSPS()...)
+	for i := range frames {
+		pts := startPTS + int64(i*3600)
+		es := []byte{0, 0, 0, 1, 0x09, 0xF0}
+		if i%25 == 0 {
+			es = append(es, sps...)
+			es = append(es, 0, 0, 1, 0x65)
+			es = append(es, make([]byte, 300)...)
+		} else {
+			es = append(es, 0, 0, 1, 0x41, 0x9A)
+		}
+		_ = w.WritePES(0x100, ts.AppendPES(nil, 0xE0, pts, -1, es), -1, false)
+	}
+	return buf.Bytes()
+}
+
+func TestH264Resolution(t *testing.T) {
+	sps, ok := findH264SPS(append([]byte{0, 0, 1}, newTestSPS()...))
+	if !ok {
+		t.Fatal("not found the sps")
+	}
+
+	if codec := h264Codec(sps); codec != "avc1.42c028" {
+		t.Errorf("expect codec '%s', but got '%s'", "avc1.42c028", codec)
+	}
+
+	if width, height, err := h264Resolution(sps); err != nil {
+		t.Error(err)
+	} else if width != 1920 || height != 1080 {
+		t.Errorf("expect resolution %dx%d, but got %dx%d", 1920, 1080, width, height)
+	}
+}
+
+func TestIFrameGeneratorTS(t *testing.T) {
+	files := map[string][]byte{
+		"0.ts": newTestTSSegment(90000, 50),
+		"1.ts": newTestTSSegment(90000+180000, 50),
+	}
+
+	pl := playlist.MediaPlayList{EndList: true, Segments: []playlist.MediaSegment{
+		{URI: "0.ts", Duration: 2},
+		{URI: "1.ts", Duration: 2},
+	}}
+
+	g := IFrameGenerator{Load: func(seg playlist.MediaSegment) ([]byte, error) {
+		if data, ok := files[seg.URI]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("not found '%s'", seg.URI)
+	}}
+
+	out, stream, err := g.Generate(pl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !out.IFrameOnly || !out.EndList || out.TargetDuration != 1 {
+		t.Errorf("unexpected I-frame playlist: %+v", out)
+	}
+
+	if len(out.Segments) != 4 {
+		t.Fatalf("expect %d I-frames, but got %d", 4, len(out.Segments))
+	}
+
+	for i, seg := range out.Segments {
+		if seg.Duration != 1 {
+			t.Errorf("%d: expect duration %v, but got %v", i, 1, seg.Duration)
+		}
+		if seg.URI != fmt.Sprintf("%d.ts", i/2) {
+			t.Errorf("%d: unexpected uri '%s'", i, seg.URI)
+		}
+
+		data := files[seg.URI][seg.ByteRange.Offset : seg.ByteRange.Offset+seg.ByteRange.Length]
+		if !bytes.Contains(data, []byte{0, 0, 1, 0x65}) {
+			t.Errorf("%d: the byte range does not contain the I-frame", i)
+		}
+	}
+
+	// The second I-frame of each segment is not preceded by PAT and PMT.
+	xmap := playlist.XMap{URI: "1.ts", ByteRange: playlist.XByteRange{Length: 2 * ts.PacketSize}}
+	if out.Segments[3].Map != xmap {
+		t.Errorf("expect map %+v, but got %+v", xmap, out.Segments[3].Map)
+	}
+	if out.Segments[0].ByteRange.Offset != 0 {
+		t.Errorf("expect the first I-frame to start with PAT")
+	}
+
+	if len(stream.Codecs) != 1 || stream.Codecs[0] != "avc1.42c028" {
+		t.Errorf("unexpected codecs: %v", stream.Codecs)
+	}
+	if stream.Resolution != (playlist.XResolution{Width: 1920, Height: 1080}) {
+		t.Errorf("unexpected resolution: %s", stream.Resolution)
+	}
+	if stream.Bandwidth == 0 || stream.AverageBandwidth == 0 || stream.AverageBandwidth > stream.Bandwidth {
+		t.Errorf("unexpected bandwidth: peak=%d, average=%d", stream.Bandwidth, stream.AverageBandwidth)
+	}
+
+	master := playlist.MasterPlayList{Streams: []playlist.MasterStream{
+		{Stream: playlist.XStreamInf{URI: "video.m3u8", Bandwidth: 1000000}},
+	}}
+
+	stream.URI = "iframe.m3u8"
+	if err := AddIFrameStream(&master, "video.m3u8", stream); err != nil {
+		t.Fatal(err)
+	} else if err := AddIFrameStream(&master, "video.m3u8", stream); err != nil {
+		t.Fatal(err)
+	} else if n := len(master.Streams[0].IFrameStreams); n != 1 {
+		t.Errorf("expect %d I-frame stream, but got %d", 1, n)
+	}
+
+	if err := AddIFrameStream(&master, "missing.m3u8", stream); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+
+	var buf bytes.Buffer
+	if err := out.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIFrameGeneratorFMP4(t *testing.T) {
+	files := map[string][]byte{
+		"init.mp4": newTestInit(),
+		"0.m4s":    appendTestFragment(nil, 1, 0),
+		"1.m4s":    appendTestFragment(nil, 2, 180000),
+	}
+
+	xmap := playlist.XMap{URI: "init.mp4"}
+	pl := playlist.MediaPlayList{EndList: true, Segments: []playlist.MediaSegment{
+		{URI: "0.m4s", Duration: 2, Map: xmap},
+		{URI: "1.m4s", Duration: 2, Map: xmap},
+	}}
+
+	g := IFrameGenerator{Load: func(seg playlist.MediaSegment) ([]byte, error) {
+		return files[seg.URI], nil
+	}}
+
+	out, stream, err := g.Generate(pl)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(out.Segments) != 2 {
+		t.Fatalf("expect %d I-frames, but got %d", 2, len(out.Segments))
+	}
+
+	for i, seg := range out.Segments {
+		if seg.Duration != 2 || seg.Map != xmap || seg.ByteRange.Offset != 0 ||
+			seg.ByteRange.Length != uint64(len(files[seg.URI])-100) {
+			t.Errorf("%d: unexpected I-frame: %+v", i, seg)
+		}
+	}
+
+	if len(stream.Codecs) != 1 || stream.Codecs[0] != "avc1.64001f" {
+		t.Errorf("unexpected codecs: %v", stream.Codecs)
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+// DefaultDiscontinuityThreshold is the default threshold of the PTS or PCR
+// jump to insert EXT-X-DISCONTINUITY.
+const DefaultDiscontinuityThreshold = time.Second
+
+// Segmenter is used to cut a continuous MPEG-TS stream into the media
+// segments at the key frames, and maintains the media playlist.
+type Segmenter struct {
+	// Storage is used to store the media segments, the key files
+	// and the media playlist. Required.
+	Storage Storage
+
+	// SegmentDuration is the target duration of each media segment,
+	// which is cut at the first key frame after reaching it.
+	//
+	// Default: DefaultSegmentDuration
+	SegmentDuration time.Duration
+
+	// PlayListType is the type of the media playlist, which is one of
+	// playlist.MediaPlayListTypeVOD, playlist.MediaPlayListTypeEvent,
+	// or empty for the live playlist with the sliding window.
+	//
+	// The VOD playlist is only written when the stream ends, and the others
+	// are rewritten after each media segment is written.
+	PlayListType string
+
+	// WindowSize is the maximum number of the media segments in the live
+	// playlist with the sliding window. 0 means no limit.
+	//
+	// The media segments removed from the playlist are not deleted.
+	WindowSize int
+
+	// DiscontinuityThreshold is the threshold of the PTS or PCR jump,
+	// beyond which EXT-X-DISCONTINUITY is inserted before the next media
+	// segment, which is cut at the next key frame.
+	//
+	// Default: DefaultDiscontinuityThreshold
+	DiscontinuityThreshold time.Duration
+
+	// PlayListName is the name of the media playlist in the storage.
+	//
+	// Default: "index.m3u8"
+	PlayListName string
+
+	// SegmentName returns the name of the media segment with the index,
+	// which is also used as the URI in the playlist.
+	//
+	// Default: fmt.Sprintf("seg%d.ts", index)
+	SegmentName func(index int) string
+
+	// If not nil, encrypt the media segments by AES-128
+	// with the keys rotated by it.
+	Encryptor *Encryptor
+}
+
+// Segment reads the MPEG-TS stream from r, such as a file, until EOF,
+// and cuts it into the media segments. It returns the final media playlist
+// with EXT-X-ENDLIST.
+func (s Segmenter) Segment(r io.Reader) (pl playlist.MediaPlayList, err error) {
+	if s.Storage == nil {
+		return pl, errors.New("missing the storage")
+	}
+	if s.SegmentDuration <= 0 {
+		s.SegmentDuration = DefaultSegmentDuration
+	}
+	if s.DiscontinuityThreshold <= 0 {
+		s.DiscontinuityThreshold = DefaultDiscontinuityThreshold
+	}
+	if s.PlayListName == "" {
+		s.PlayListName = "index.m3u8"
+	}
+
+	w := segmenter{Segmenter: s}
+	w.pl.PlayListType = s.PlayListType
+	w.pl.TargetDuration = uint64(math.Ceil(s.SegmentDuration.Seconds()))
+
+	p := make(ts.Packet, ts.PacketSize)
+	for index := 0; ; index++ {
+		if _, err = io.ReadFull(r, p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return w.pl, fmt.Errorf("packet %d: %w", index, err)
+		}
+
+		if err = p.Validate(); err != nil {
+			return w.pl, fmt.Errorf("packet %d: %w", index, err)
+		} else if err = w.write(p); err != nil {
+			return w.pl, fmt.Errorf("packet %d: %w", index, err)
+		}
+	}
+
+	if err = w.finish(); err != nil {
+		return w.pl, err
+	}
+	return w.pl, nil
+}
+
+type segmenter struct {
+	Segmenter
+	pl playlist.MediaPlayList
+
+	buf      bytes.Buffer // The packets of the current media segment.
+	hasData  bool         // Whether buf contains the packets besides PAT and PMT.
+	psi      []byte       // The pending PAT and PMT packets.
+	pat      []byte       // The last PAT packet.
+	pmt      []byte       // The last PMT packet.
+	programs ts.ProgramTracker
+	main     ts.Stream
+	pcr      uint16 // PCR_PID
+
+	index    int   // The index of the current media segment.
+	started  bool  // Whether the start PTS of the current media segment is set.
+	startPTS int64 // The PTS of the first frame of the current media segment.
+	maxTicks int64 // The maximum PTS relative to startPTS.
+	frames   int   // The number of the frames of the current media segment.
+	lastPTS  int64
+	hasPTS   bool
+	lastPCR  int64 // The unit is 90kHz.
+	hasPCR   bool
+
+	discontinuity bool // Whether a discontinuity is pending.
+	segmentDisc   bool // Whether the current media segment starts with a discontinuity.
+
+	key         Key
+	keys        []Key
+	keyDuration time.Duration
+}
+
+func (w *segmenter) write(p ts.Packet) (err error) {
+	pid := p.PID()
+	if w.programs.IsPSI(pid) {
+		pat, pmt, err := w.programs.Parse(p)
+		switch {
+		case err != nil:
+			return err
+
+		case pat != nil:
+			w.pat = append(w.pat[:0], p...)
+
+		case pmt != nil:
+			if w.main.PID == 0 {
+				w.main, w.pcr = pmt.MainStream(), pmt.PCRPID
+			}
+			w.pmt = append(w.pmt[:0], p...)
+		}
+
+		w.psi = append(w.psi, p...)
+		return nil
+	}
+
+	if p.DiscontinuityIndicator() && w.hasData {
+		w.discontinuity = true
+	}
+
+	if pcr, ok := p.PCR(); ok && pid == w.pcr {
+		pcr /= ts.PCRClockRate / ts.ClockRate
+		if w.hasPCR && w.jumped(w.lastPCR, pcr) {
+			w.discontinuity = true
+		}
+		w.lastPCR, w.hasPCR = pcr, true
+	}
+
+	if pid == w.main.PID && w.main.PID != 0 && p.PayloadUnitStart() {
+		if err = w.writePES(p); err != nil {
+			return
+		}
+	}
+
+	w.buf.Write(w.psi)
+	w.psi = w.psi[:0]
+	w.buf.Write(p)
+	w.hasData = true
+	return
+}
+
+func (w *segmenter) writePES(p ts.Packet) (err error) {
+	h, err := ts.ParsePESHeader(p.Payload())
+	if err != nil || h.PTS < 0 {
+		return
+	}
+
+	if w.hasPTS && w.jumped(w.lastPTS, h.PTS) {
+		w.discontinuity = true
+	}
+	w.lastPTS, w.hasPTS = h.PTS, true
+
+	if !w.started {
+		w.started, w.startPTS = true, h.PTS
+	}
+
+	if !ts.IsVideo(w.main.Type) || isKeyFrame(w.main.Type, p, h) {
+		var ticks int64
+		switch delta := ts.TimestampDelta(w.startPTS, h.PTS); {
+		case w.discontinuity:
+			ticks = w.lastFrameTicks()
+		case delta >= int64(w.SegmentDuration.Seconds()*ts.ClockRate):
+			ticks = delta
+		}
+
+		if ticks > 0 {
+			if err = w.flush(ticks); err != nil {
+				return
+			}
+
+			w.segmentDisc, w.discontinuity = w.discontinuity, false
+			w.startPTS, w.maxTicks, w.frames = h.PTS, 0, 0
+		}
+	}
+
+	// The frames after the jump belong to the next media segment.
+	if !w.discontinuity {
+		w.maxTicks = max(w.maxTicks, ts.TimestampDelta(w.startPTS, h.PTS))
+		w.frames++
+	}
+	return
+}
+
+func (w *segmenter) jumped(last, current int64) bool {
+	threshold := int64(w.DiscontinuityThreshold.Seconds() * ts.ClockRate)
+	delta := ts.TimestampDelta(last, current)
+	return delta > threshold || delta < -threshold
+}
+
+// lastFrameTicks returns the duration of the current media segment
+// to the end of the last frame.
+func (w *segmenter) lastFrameTicks() int64 {
+	ticks := w.maxTicks
+	if w.frames > 1 {
+		ticks += w.maxTicks / int64(w.frames-1)
+	}
+	return max(ticks, 1)
+}
+
+func (w *segmenter) finish() (err error) {
+	if w.hasData {
+		if err = w.flush(w.lastFrameTicks()); err != nil {
+			return
+		}
+	}
+
+	if len(w.pl.Segments) == 0 {
+		return errors.New("no media segment is generated")
+	}
+
+	w.pl.EndList = true
+	return w.writePlayList()
+}
+
+// flush writes the current media segment into the storage,
+// and starts a new one with PAT and PMT.
+func (w *segmenter) flush(ticks int64) (err error) {
+	name := fmt.Sprintf("seg%d.ts", w.index)
+	if w.SegmentName != nil {
+		name = w.SegmentName(w.index)
+	}
+
+	duration := math.Round(float64(ticks)/ts.ClockRate*1000) / 1000
+	seg := playlist.MediaSegment{
+		URI:           name,
+		Duration:      duration,
+		MediaSequence: uint64(w.index),
+		Discontinuity: w.segmentDisc,
+	}
+
+	data := w.buf.Bytes()
+	if w.Encryptor != nil {
+		if data, err = w.encrypt(&seg, data); err != nil {
+			return
+		}
+	}
+
+	err = writeFile(w.Storage, name, func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	})
+	if err != nil {
+		return
+	}
+
+	w.index++
+	w.hasData = false
+	w.buf.Reset()
+	if len(w.psi) == 0 {
+		w.buf.Write(w.pat)
+		w.buf.Write(w.pmt)
+	}
+
+	w.pl.Segments = append(w.pl.Segments, seg)
+	w.pl.TargetDuration = max(w.pl.TargetDuration, uint64(duration+0.5))
+	if w.PlayListType == "" && w.WindowSize > 0 && len(w.pl.Segments) > w.WindowSize {
+		if w.pl.Segments[0].Discontinuity {
+			w.pl.DiscontinuitySequence++
+		}
+		w.pl.Segments = w.pl.Segments[1:]
+		w.pl.MediaSequence++
+	}
+
+	if w.PlayListType != playlist.MediaPlayListTypeVOD {
+		err = w.writePlayList()
+	}
+	return
+}
+
+func (w *segmenter) encrypt(seg *playlist.MediaSegment, data []byte) ([]byte, error) {
+	e := *w.Encryptor
+	if len(w.keys) == 0 || e.rotate(w.key.Count, w.keyDuration) {
+		key, err := e.newKey(len(w.keys), w.index, w.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		w.key, w.keyDuration = key, 0
+		w.keys = append(w.keys, key)
+	}
+
+	keys, err := e.xkeys(w.key)
+	if err != nil {
+		return nil, err
+	}
+
+	seg.Keys = keys
+	iv, err := seg.IV()
+	if err != nil {
+		return nil, err
+	}
+
+	w.key.Count++
+	w.keys[len(w.keys)-1].Count++
+	w.keyDuration += time.Duration(seg.Duration * float64(time.Second))
+	return aes128.Encrypt(data, w.key.Data, iv)
+}
+
+func (w *segmenter) writePlayList() error {
+	w.pl.Version = 0
+	w.pl.Version = w.pl.MinVersion()
+	return writeFile(w.Storage, w.PlayListName, w.pl.Output)
+}
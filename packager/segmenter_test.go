@@ -0,0 +1,131 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packager
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/aes128"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/ts"
+)
+
+// newTestStream returns a transport stream of 10 seconds with 25 fps,
+// which has a key frame per second and a timestamp jump at 6 seconds.
+func newTestStream() []byte {
+	var buf bytes.Buffer
+	w := ts.NewWriter(&buf)
+	_ = w.WritePAT(ts.PAT{Programs: []ts.Program{{Number: 1, PID: 0x1000}}})
+	_ = w.WritePMT(0x1000, ts.PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: []ts.Stream{
+		{Type: ts.StreamTypeH264, PID: 0x100},
+		{Type: ts.StreamTypeAAC, PID: 0x101},
+	}})
+
+	for i := range 250 {
+		pts := int64(90000 + i*3600)
+		if i >= 150 {
+			pts += 100 * 90000
+		}
+
+		_ = w.WritePES(0x100, ts.AppendPES(nil, 0xE0, pts, -1, []byte{0, 0, 0, 1, 0x09, 0xF0}), pts*300, i%25 == 0)
+		_ = w.WritePES(0x101, ts.AppendPES(nil, 0xC0, pts, -1, []byte{0xFF, 0xF1}), -1, true)
+	}
+	return buf.Bytes()
+}
+
+func TestSegmenter(t *testing.T) {
+	var index byte
+	storage := make(memStorage)
+	s := Segmenter{
+		Storage:         storage,
+		SegmentDuration: 2 * time.Second,
+		PlayListType:    playlist.MediaPlayListTypeEvent,
+		Encryptor: &Encryptor{
+			RotateSegments: 2,
+			NewKey: func() ([]byte, error) {
+				index++
+				return bytes.Repeat([]byte{index}, 16), nil
+			},
+		},
+	}
+
+	pl, err := s.Segment(bytes.NewReader(newTestStream()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pl.Segments) != 5 {
+		t.Fatalf("expect %d segments, but got %d", 5, len(pl.Segments))
+	}
+
+	for i, seg := range pl.Segments {
+		if seg.Duration != 2 {
+			t.Errorf("%d: expect duration %v, but got %v", i, 2, seg.Duration)
+		}
+		if seg.Discontinuity != (i == 3) {
+			t.Errorf("%d: unexpected discontinuity %v", i, seg.Discontinuity)
+		}
+
+		key := bytes.Repeat([]byte{byte(i/2 + 1)}, 16)
+		if len(seg.Keys) != 1 || seg.Keys[0].URI != fmt.Sprintf("key%d.key", i/2) {
+			t.Errorf("%d: unexpected keys %+v", i, seg.Keys)
+			continue
+		}
+
+		iv, _ := seg.IV()
+		data, err := aes128.Decrypt(storage[seg.URI].Bytes(), key, iv, true)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		} else if packets, err := ts.Packets(data); err != nil {
+			t.Errorf("%d: %v", i, err)
+		} else if packets[0].PID() != ts.PIDPAT {
+			t.Errorf("%d: the segment does not start with PAT", i)
+		}
+	}
+
+	if pl.TargetDuration != 2 || !pl.EndList || pl.PlayListType != playlist.MediaPlayListTypeEvent {
+		t.Errorf("unexpected playlist: target=%d, endlist=%v, type=%s", pl.TargetDuration, pl.EndList, pl.PlayListType)
+	}
+
+	if m3u8 := storage["index.m3u8"].String(); !strings.Contains(m3u8, "#EXT-X-DISCONTINUITY\n") ||
+		!strings.Contains(m3u8, "#EXT-X-ENDLIST") {
+		t.Errorf("unexpected playlist:\n%s", m3u8)
+	}
+}
+
+func TestSegmenterSlidingWindow(t *testing.T) {
+	storage := make(memStorage)
+	s := Segmenter{Storage: storage, SegmentDuration: 2 * time.Second, WindowSize: 3}
+
+	pl, err := s.Segment(bytes.NewReader(newTestStream()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pl.Segments) != 3 || pl.MediaSequence != 2 || pl.Segments[0].URI != "seg2.ts" {
+		t.Errorf("unexpected sliding window: sequence=%d, segments=%+v", pl.MediaSequence, pl.Segments)
+	}
+	if _, ok := storage["seg0.ts"]; !ok {
+		t.Errorf("the removed segment should not be deleted")
+	}
+
+	if _, err := s.Segment(bytes.NewReader(nil)); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
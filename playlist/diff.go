@@ -0,0 +1,296 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Define the kinds of the changes between two playlists.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// Change represents a change from the old playlist to the new one.
+type Change struct {
+	Kind string // One of ChangeAdded, ChangeRemoved and ChangeModified.
+
+	// Target is the changed object, such as "header", "segment 10"
+	// by the media sequence number, "variant 720p.m3u8" by the uri, etc.
+	Target string
+
+	// Field is the changed tag or attribute of the target,
+	// such as "EXTINF" or "BANDWIDTH", which is empty when added or removed.
+	Field string
+
+	Old string // The old value, which is empty when added.
+	New string // The new value, which is empty when removed.
+}
+
+// String returns the text representation of the change, such as
+// "+ segment 13: 13.ts" for the added one, "- segment 10: 10.ts"
+// for the removed one and "~ segment 12: EXTINF: 4 -> 4.5"
+// for the modified one.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Target, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Target, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s: %s -> %s", c.Target, c.Field, noneString(c.Old), noneString(c.New))
+	}
+}
+
+func noneString(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// Changes is a set of the changes between two playlists.
+type Changes []Change
+
+// String returns the text representation of the changes, one per line.
+func (cs Changes) String() string {
+	var b strings.Builder
+	for _, c := range cs {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+type _Differ struct {
+	changes Changes
+}
+
+func (d *_Differ) add(target, value string) {
+	d.changes = append(d.changes, Change{Kind: ChangeAdded, Target: target, New: value})
+}
+
+func (d *_Differ) remove(target, value string) {
+	d.changes = append(d.changes, Change{Kind: ChangeRemoved, Target: target, Old: value})
+}
+
+func (d *_Differ) modify(target, field, old, new string) {
+	if old != new {
+		d.changes = append(d.changes, Change{Kind: ChangeModified, Target: target, Field: field, Old: old, New: new})
+	}
+}
+
+func (d *_Differ) tag(target string, tag Tag, old, new _Value) {
+	d.modify(target, strings.TrimPrefix(string(tag), "#"), encodeString(old), encodeString(new))
+}
+
+// attrs compares the attributes of the tags one by one.
+func (d *_Differ) attrs(target string, old, new _Value) {
+	olds, news := attrMap(old), attrMap(new)
+	for _, name := range attrNames(olds, news) {
+		d.modify(target, name, olds[name], news[name])
+	}
+}
+
+// attrString returns the attribute list of the tag without the uri line
+// of EXT-X-STREAM-INF, which is the target.
+func attrString(v _Value) string {
+	s, _, _ := strings.Cut(encodeString(v), "\n")
+	return s
+}
+
+func attrMap(v _Value) map[string]string {
+	attrs := make(map[string]string)
+	_ = iterAttributes(attrString(v), -1, func(name, value string) error {
+		attrs[name] = value
+		return nil
+	})
+	return attrs
+}
+
+func attrNames(attrs ...map[string]string) (names []string) {
+	for _, m := range attrs {
+		for name := range m {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return
+}
+
+func encodeString(v _Value) string {
+	if v.IsZero() {
+		return ""
+	}
+
+	var b strings.Builder
+	if err := v.encode(&b); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return b.String()
+}
+
+func encodeKeys(keys []XKey) string {
+	ss := make([]string, len(keys))
+	for i := range keys {
+		ss[i] = encodeString(keys[i])
+	}
+	return strings.Join(ss, "; ")
+}
+
+// DiffMedia compares the old and new media playlists, and returns the changes,
+// which contains the changes of the header tags, and the media segments
+// added, removed or modified by the media sequence number.
+//
+// The keys and the map of the media segments are compared by the ones
+// they inherit from the preceding media segments.
+func DiffMedia(old, new MediaPlayList) Changes {
+	const header = "header"
+
+	var d _Differ
+	d.tag(header, EXT_X_VERSION, _DecimalInteger(old.Version), _DecimalInteger(new.Version))
+	d.tag(header, EXT_X_TARGETDURATION, _DecimalInteger(old.TargetDuration), _DecimalInteger(new.TargetDuration))
+	d.tag(header, EXT_X_MEDIA_SEQUENCE, _DecimalInteger(old.MediaSequence), _DecimalInteger(new.MediaSequence))
+	d.tag(header, EXT_X_DISCONTINUITY_SEQUENCE, _DecimalInteger(old.DiscontinuitySequence), _DecimalInteger(new.DiscontinuitySequence))
+	d.tag(header, EXT_X_PLAYLIST_TYPE, _UnquotedString(old.PlayListType), _UnquotedString(new.PlayListType))
+	d.tag(header, EXT_X_INDEPENDENT_SEGMENTS, _Bool(old.IndependentSegments), _Bool(new.IndependentSegments))
+	d.tag(header, EXT_X_I_FRAMES_ONLY, _Bool(old.IFrameOnly), _Bool(new.IFrameOnly))
+	d.tag(header, EXT_X_START, old.Start, new.Start)
+	d.tag(header, EXT_X_SERVER_CONTROL, old.ServerControl, new.ServerControl)
+	d.tag(header, EXT_X_PART_INF, old.PartInf, new.PartInf)
+	d.tag(header, EXT_X_ENDLIST, _Bool(old.EndList), _Bool(new.EndList))
+
+	olds, news := diffSegments(old), diffSegments(new)
+	var i, j int
+	for i < len(olds) || j < len(news) {
+		switch {
+		case j == len(news) || (i < len(olds) && olds[i].MediaSequence < news[j].MediaSequence):
+			d.remove(segmentTarget(olds[i]), olds[i].URI)
+			i++
+
+		case i == len(olds) || news[j].MediaSequence < olds[i].MediaSequence:
+			d.add(segmentTarget(news[j]), news[j].URI)
+			j++
+
+		default:
+			diffSegment(&d, olds[i], news[j])
+			i++
+			j++
+		}
+	}
+
+	return d.changes
+}
+
+func diffSegments(pl MediaPlayList) []MediaSegment {
+	pl.Segments = slices.Clone(pl.Segments)
+	pl.update()
+	carrySegments(pl.Segments)
+	return pl.Segments
+}
+
+func segmentTarget(seg MediaSegment) string {
+	return "segment " + strconv.FormatUint(seg.MediaSequence, 10)
+}
+
+func diffSegment(d *_Differ, old, new MediaSegment) {
+	target := segmentTarget(old)
+	d.modify(target, "URI", old.URI, new.URI)
+	d.modify(target, "EXTINF", encodeString(_DecimalFloat(old.Duration)), encodeString(_DecimalFloat(new.Duration)))
+	d.modify(target, "TITLE", old.Title, new.Title)
+	d.tag(target, EXT_X_BYTERANGE, old.ByteRange, new.ByteRange)
+	d.tag(target, EXT_X_DISCONTINUITY, _Bool(old.Discontinuity), _Bool(new.Discontinuity))
+	d.modify(target, "EXT-X-KEY", encodeKeys(old.Keys), encodeKeys(new.Keys))
+	d.tag(target, EXT_X_MAP, old.Map, new.Map)
+	d.tag(target, EXT_X_PROGRAM_DATE_TIME, _Time(old.ProgramDateTime), _Time(new.ProgramDateTime))
+}
+
+// DiffMaster compares the old and new master playlists, and returns
+// the changes, which contains the changes of the header tags, and
+// the variant streams by the uri, the renditions by the type, the group id
+// and the name, the I-frame streams by the uri, the session data by
+// the data id and the language, and the session keys by the uri and
+// the key format, which are added, removed or modified attributes.
+func DiffMaster(old, new MasterPlayList) Changes {
+	const header = "header"
+
+	var d _Differ
+	d.tag(header, EXT_X_VERSION, _DecimalInteger(old.Version), _DecimalInteger(new.Version))
+	d.tag(header, EXT_X_INDEPENDENT_SEGMENTS, _Bool(old.IndependentSegments), _Bool(new.IndependentSegments))
+	d.tag(header, EXT_X_START, old.Start, new.Start)
+
+	oldm, newm := collectMasterTags(old), collectMasterTags(new)
+	for _, tag := range oldm.keys {
+		oldv := oldm.values[tag]
+		if newv, ok := newm.values[tag]; !ok {
+			d.remove(tag, attrString(oldv))
+		} else {
+			d.attrs(tag, oldv, newv)
+		}
+	}
+
+	for _, tag := range newm.keys {
+		if _, ok := oldm.values[tag]; !ok {
+			d.add(tag, attrString(newm.values[tag]))
+		}
+	}
+
+	return d.changes
+}
+
+type _MasterTags struct {
+	keys   []string
+	values map[string]_Value
+}
+
+func (m *_MasterTags) set(key string, value _Value) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func collectMasterTags(pl MasterPlayList) (m _MasterTags) {
+	m.values = make(map[string]_Value, len(pl.Streams)*2)
+	for _, s := range pl.Streams {
+		for _, x := range s.SessionKeys {
+			m.set(fmt.Sprintf("session key %s/%s", x.URI, x.Format), x)
+		}
+
+		for _, x := range s.SessionDatas {
+			m.set(fmt.Sprintf("session data %s/%s", x.DataId, x.Language), x)
+		}
+
+		for _, x := range s.Medias {
+			m.set(fmt.Sprintf("rendition %s/%s/%s", x.Type, x.GroupId, x.Name), x)
+		}
+
+		for _, x := range s.IFrameStreams {
+			m.set("iframe "+x.URI, x)
+		}
+
+		if !s.Stream.IsZero() {
+			m.set("variant "+s.Stream.URI, s.Stream)
+		}
+	}
+	return
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffMedia(t *testing.T) {
+	old := parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4,
+10.m4s
+#EXTINF:4,
+11.m4s
+#EXTINF:4,
+12.m4s
+`)
+
+	new := parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:5
+#EXT-X-MEDIA-SEQUENCE:11
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4,
+11.m4s
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXTINF:4.5,
+12.m4s
+#EXTINF:4,
+13.m4s
+#EXT-X-ENDLIST
+`)
+
+	expect := `~ header: EXT-X-TARGETDURATION: 4 -> 5
+~ header: EXT-X-MEDIA-SEQUENCE: 10 -> 11
+~ header: EXT-X-ENDLIST: (none) -> YES
+- segment 10: 10.m4s
+~ segment 12: EXTINF: 4 -> 4.5
+~ segment 12: EXT-X-KEY: (none) -> METHOD=AES-128,URI="key.bin"
++ segment 13: 13.m4s
+`
+
+	changes := DiffMedia(old, new)
+	if s := changes.String(); s != expect {
+		t.Errorf("expect changes:\n%s\nbut got:\n%s", expect, s)
+	}
+
+	if changes[3].Kind != ChangeRemoved || changes[3].Target != "segment 10" {
+		t.Errorf("unexpected change: %+v", changes[3])
+	}
+
+	if changes := DiffMedia(old, old); len(changes) != 0 {
+		t.Errorf("expect no changes, but got:\n%s", changes)
+	}
+}
+
+func TestDiffMaster(t *testing.T) {
+	var old, new MasterPlayList
+	if err := old.Parse(strings.NewReader(`#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="en",URI="en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,AUDIO="aac"
+360p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,AUDIO="aac"
+720p.m3u8
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := new.Parse(strings.NewReader(`#EXTM3U
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="en",URI="en2.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=2100000,RESOLUTION=1280x720,AUDIO="aac"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=4000000,AUDIO="aac"
+1080p.m3u8
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `~ header: EXT-X-INDEPENDENT-SEGMENTS: (none) -> YES
+~ rendition AUDIO/aac/en: URI: "en.m3u8" -> "en2.m3u8"
+- variant 360p.m3u8: BANDWIDTH=1000000,AUDIO="aac"
+~ variant 720p.m3u8: BANDWIDTH: 2000000 -> 2100000
+~ variant 720p.m3u8: RESOLUTION: (none) -> 1280x720
++ variant 1080p.m3u8: BANDWIDTH=4000000,AUDIO="aac"
+`
+
+	if s := DiffMaster(old, new).String(); s != expect {
+		t.Errorf("expect changes:\n%s\nbut got:\n%s", expect, s)
+	}
+}
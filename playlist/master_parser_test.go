@@ -98,3 +98,39 @@ func testMasterSegment(t *testing.T, value, expect MasterStream) {
 		t.Errorf("expect %+v, but got %+v", expect, value)
 	}
 }
+
+func TestMasterPlayListParserSessionData(t *testing.T) {
+	const s = `#EXTM3U
+#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="This is an example",LANGUAGE="en"
+#EXT-X-SESSION-DATA:DATA-ID="com.example.lyrics",URI="lyrics.json"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000
+low.m3u8
+`
+
+	var master MasterPlayList
+	if err := master.Parse(strings.NewReader(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	expects := []XSessionData{
+		{DataId: "com.example.title", Value: "This is an example", Language: "en"},
+		{DataId: "com.example.lyrics", URI: "lyrics.json"},
+	}
+	if len(master.Streams) != 1 {
+		t.Fatalf("expect %d master stream, but got %d", 1, len(master.Streams))
+	} else if datas := master.Streams[0].SessionDatas; !reflect.DeepEqual(datas, expects) {
+		t.Errorf("expect session datas %+v, but got %+v", expects, datas)
+	}
+
+	var b strings.Builder
+	if err := master.Output(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed MasterPlayList
+	if err := parsed.Parse(strings.NewReader(b.String())); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(parsed, master) {
+		t.Errorf("expect round-trip playlist %+v, but got %+v", master, parsed)
+	}
+}
@@ -15,6 +15,7 @@
 package playlist
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
@@ -39,6 +40,14 @@ type MediaPlayList struct {
 	IndependentSegments   bool   `json:",omitempty,omitzero"`
 	IFrameOnly            bool   `json:",omitempty,omitzero"`
 	EndList               bool   `json:",omitempty,omitzero"`
+
+	// Low-Latency HLS
+	ServerControl    XServerControl     `json:",omitzero"`
+	PartInf          XPartInf           `json:",omitzero"`
+	Skip             XSkip              `json:",omitzero"`
+	Parts            []XPart            `json:",omitempty,omitzero"` // The parts of the next incomplete media segment.
+	PreloadHints     []XPreloadHint     `json:",omitempty,omitzero"`
+	RenditionReports []XRenditionReport `json:",omitempty,omitzero"`
 }
 
 // Type returns the fixed "Media".
@@ -76,6 +85,8 @@ func (pl MediaPlayList) minVersion() (minVersion uint64) {
 		minVersion = max(minVersion, version)
 	}
 
+	setVersion(pl.Skip.minVersion())
+
 	for _, seg := range pl.Segments {
 		if !isIntegerFloat64(seg.Duration) {
 			setVersion(3)
@@ -110,10 +121,16 @@ func (pl MediaPlayList) validate(minVersion uint64) (err error) {
 		return errMissingMediaSegments
 	}
 
+	hasParts := len(pl.Parts) > 0
 	for i, seg := range pl.Segments {
 		if uint64(seg.Duration+0.5) > pl.TargetDuration {
 			return fmt.Errorf("media segment duration exceeds target duration at %d", i)
 		}
+		hasParts = hasParts || len(seg.Parts) > 0
+	}
+
+	if hasParts && pl.PartInf.IsZero() {
+		return errors.New(string(EXT_X_PART_INF) + ": must be present for " + string(EXT_X_PART))
 	}
 
 	return
@@ -122,7 +139,7 @@ func (pl MediaPlayList) validate(minVersion uint64) (err error) {
 func (pl *MediaPlayList) update() {
 	index := -1
 	lastdseq := pl.DiscontinuitySequence
-	lastmseq := pl.MediaSequence
+	lastmseq := pl.MediaSequence + pl.Skip.SkippedSegments
 	for i := range pl.Segments {
 		s := &pl.Segments[i]
 		s.MediaSequence = lastmseq
@@ -140,7 +157,7 @@ func (pl *MediaPlayList) update() {
 
 	// Recover the Media Sequence Number parsed by #EXT-X-MEDIA-SEQUENCE.
 	if len(pl.Segments) > 0 {
-		pl.MediaSequence = pl.Segments[0].MediaSequence
+		pl.MediaSequence = pl.Segments[0].MediaSequence - pl.Skip.SkippedSegments
 	}
 
 	if index > -1 {
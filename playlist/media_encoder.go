@@ -33,9 +33,12 @@ func (pl MediaPlayList) encode(w io.Writer) (err error) {
 	// Media PlayList Tags
 	err = tryWriteTag(w, err, EXT_X_PLAYLIST_TYPE, newEnum(pl.PlayListType))
 	err = tryWriteTag(w, err, EXT_X_TARGETDURATION, _DecimalInteger(pl.TargetDuration))
+	err = tryWriteTag(w, err, EXT_X_SERVER_CONTROL, pl.ServerControl)
+	err = tryWriteTag(w, err, EXT_X_PART_INF, pl.PartInf)
 	err = tryWriteTag(w, err, EXT_X_I_FRAMES_ONLY, _Bool(pl.IFrameOnly))
 	err = tryWriteTag(w, err, EXT_X_MEDIA_SEQUENCE, _DecimalInteger(pl.MediaSequence))
 	err = tryWriteTag(w, err, EXT_X_DISCONTINUITY_SEQUENCE, _DecimalInteger(pl.DiscontinuitySequence))
+	err = tryWriteTag(w, err, EXT_X_SKIP, pl.Skip)
 
 	// Media Segment Tags
 	lastkeys := make([]XKey, 0, 4)
@@ -87,12 +90,18 @@ func (pl MediaPlayList) encode(w io.Writer) (err error) {
 
 		err = tryWriteTag(w, err, EXT_X_DISCONTINUITY, _Bool(seg.Discontinuity))
 		err = tryWriteTag(w, err, EXT_X_PROGRAM_DATE_TIME, _Time(seg.ProgramDateTime))
+		err = tryWriteMasterTags(w, err, EXT_X_PART, seg.Parts)
 		err = tryWriteTag(w, err, EXT_X_BYTERANGE, seg.ByteRange)
 		err = tryWriteAny(w, err, string(EXTINF+":"), _DecimalFloat(seg.Duration), ",", _UnquotedString(seg.Title), "\n")
 		err = tryWrite(w, err, _UnquotedString(seg.URI))
 		err = tryWriteString(w, err, "\n")
 	}
 
+	// Low-Latency Tags
+	err = tryWriteMasterTags(w, err, EXT_X_PART, pl.Parts)
+	err = tryWriteMasterTags(w, err, EXT_X_PRELOAD_HINT, pl.PreloadHints)
+	err = tryWriteMasterTags(w, err, EXT_X_RENDITION_REPORT, pl.RenditionReports)
+
 	err = tryWriteTag(w, err, EXT_X_ENDLIST, _Bool(pl.EndList))
 	return
 }
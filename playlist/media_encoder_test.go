@@ -16,6 +16,7 @@ package playlist
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -123,3 +124,137 @@ http://media.example.com/fileSequence53-A.ts
 		t.Errorf("expected:\n%s\ngot:\n%s", expect[1:], s)
 	}
 }
+
+func TestMediaPlayListEncoderLowLatency(t *testing.T) {
+	const expect = `
+#EXTM3U
+#EXT-X-VERSION:9
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,CAN-SKIP-UNTIL=24,PART-HOLD-BACK=1.002
+#EXT-X-PART-INF:PART-TARGET=0.334
+#EXT-X-MEDIA-SEQUENCE:266
+#EXT-X-SKIP:SKIPPED-SEGMENTS=2
+#EXTINF:4,
+fileSequence268.mp4
+#EXT-X-PART:DURATION=0.334,URI="filePart269.0.mp4",INDEPENDENT=YES
+#EXT-X-PART:DURATION=0.334,URI="filePart269.1.mp4",BYTERANGE="1000@2000"
+#EXTINF:0.668,
+fileSequence269.mp4
+#EXT-X-PART:DURATION=0.334,URI="filePart270.0.mp4",INDEPENDENT=YES
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="filePart270.1.mp4"
+#EXT-X-RENDITION-REPORT:URI="../1M/waitForMSN.php",LAST-MSN=270,LAST-PART=0
+#EXT-X-RENDITION-REPORT:URI="../4M/waitForMSN.php",LAST-MSN=269
+`
+
+	pl := MediaPlayList{
+		Version: 9,
+
+		TargetDuration: 4,
+		MediaSequence:  266,
+
+		ServerControl: XServerControl{CanBlockReload: true, CanSkipUntil: 24, PartHoldBack: 1.002},
+		PartInf:       XPartInf{PartTarget: 0.334},
+		Skip:          XSkip{SkippedSegments: 2},
+
+		Segments: []MediaSegment{
+			{URI: "fileSequence268.mp4", Duration: 4},
+			{
+				URI:      "fileSequence269.mp4",
+				Duration: 0.668,
+				Parts: []XPart{
+					{URI: "filePart269.0.mp4", Duration: 0.334, Independent: true},
+					{URI: "filePart269.1.mp4", Duration: 0.334, ByteRange: XByteRange{Length: 1000, Offset: 2000}},
+				},
+			},
+		},
+
+		Parts:        []XPart{{URI: "filePart270.0.mp4", Duration: 0.334, Independent: true}},
+		PreloadHints: []XPreloadHint{{Type: XPreloadHintTypePart, URI: "filePart270.1.mp4"}},
+		RenditionReports: []XRenditionReport{
+			{URI: "../1M/waitForMSN.php", LastMSN: 270, LastPart: 0, HasLastPart: true},
+			{URI: "../4M/waitForMSN.php", LastMSN: 269},
+		},
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	if err := pl.Output(buf); err != nil {
+		t.Fatal(err)
+	} else if s := buf.String(); s != expect[1:] {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect[1:], s)
+	}
+}
+
+func TestMediaPlayListEncoderKeyIV(t *testing.T) {
+	const expect = `
+#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=AES-128,IV=0x000102030405060708090A0B0C0D0E0F,URI="key.bin"
+#EXTINF:4,
+1.ts
+`
+
+	pl := MediaPlayList{
+		Version:        3,
+		TargetDuration: 4,
+		Segments: []MediaSegment{
+			{
+				URI:      "1.ts",
+				Duration: 4,
+				Keys:     []XKey{{Method: XKeyMethodAES128, URI: "key.bin", IV: "0x000102030405060708090a0b0c0d0e0f"}},
+			},
+		},
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+	if err := pl.Output(buf); err != nil {
+		t.Fatal(err)
+	} else if s := buf.String(); s != expect[1:] {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect[1:], s)
+	}
+
+	var parsed MediaPlayList
+	if err := parsed.Parse(buf); err != nil {
+		t.Fatal(err)
+	} else if iv := parsed.Segments[0].Keys[0].IV; iv != pl.Segments[0].Keys[0].IV {
+		t.Errorf("expect IV '%s', but got '%s'", pl.Segments[0].Keys[0].IV, iv)
+	}
+}
+
+func TestMediaPlayListEncoderStartZero(t *testing.T) {
+	const s = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-START:TIME-OFFSET=0,PRECISE=YES
+#EXT-X-TARGETDURATION:4
+#EXTINF:4,
+1.ts
+`
+
+	var pl MediaPlayList
+	if err := pl.Parse(strings.NewReader(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+	if err := pl.Output(buf); err != nil {
+		t.Fatal(err)
+	} else if out := buf.String(); out != s {
+		t.Errorf("expected:\n%s\ngot:\n%s", s, out)
+	}
+
+	var parsed MediaPlayList
+	if err := parsed.Parse(buf); err != nil {
+		t.Fatal(err)
+	} else if parsed.Start != pl.Start {
+		t.Errorf("expect start %+v, but got %+v", pl.Start, parsed.Start)
+	}
+
+	// The zero value is omitted.
+	buf.Reset()
+	pl.Start = XStart{}
+	if err := pl.Output(buf); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(buf.String(), "#EXT-X-START") {
+		t.Errorf("unexpected EXT-X-START:\n%s", buf.String())
+	}
+}
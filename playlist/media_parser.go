@@ -56,6 +56,10 @@ func (p *_MediaPlayList) PlayList() MediaPlayList {
 	p.media.IndependentSegments = p.parser.independentSegments
 	p.media.Version = p.parser.version
 	p.media.Start = p.parser.start
+	if p.curseg != nil && len(p.curseg.Parts) > 0 {
+		// The parts of the incomplete media segment after the last one.
+		p.media.Parts = p.curseg.Parts
+	}
 	p.media.update()
 	return p.media
 }
@@ -114,7 +118,15 @@ func (p *_Parser) parseTagForMedia(tag Tag, attr string) (ok bool, err error) {
 		EXT_X_DISCONTINUITY_SEQUENCE,
 		EXT_X_PLAYLIST_TYPE,
 		EXT_X_I_FRAMES_ONLY,
-		EXT_X_ENDLIST:
+		EXT_X_ENDLIST,
+
+		////// Low-Latency Tags
+		EXT_X_SERVER_CONTROL,
+		EXT_X_PART_INF,
+		EXT_X_PART,
+		EXT_X_SKIP,
+		EXT_X_PRELOAD_HINT,
+		EXT_X_RENDITION_REPORT:
 
 	default:
 		return
@@ -279,6 +291,57 @@ func (p *_MediaPlayList) parseTag(tag Tag, attr string) (err error) {
 		} else {
 			p.media.IFrameOnly = true
 		}
+
+	////// Low-Latency Tags
+	case EXT_X_SERVER_CONTROL:
+		// RFC 8216bis, 4.4.3.8:
+		// It applies to the entire Playlist.
+		if !p.media.ServerControl.IsZero() && parser.strict {
+			err = errDuplicatedTag
+		} else {
+			err = p.media.ServerControl.decode(attr)
+		}
+
+	case EXT_X_PART_INF:
+		// RFC 8216bis, 4.4.3.7:
+		// It applies to the entire Playlist.
+		if !p.media.PartInf.IsZero() && parser.strict {
+			err = errDuplicatedTag
+		} else {
+			err = p.media.PartInf.decode(attr)
+		}
+
+	case EXT_X_PART:
+		// RFC 8216bis, 4.4.4.9:
+		// It applies to the Parent Segment that follows it in the Playlist.
+		p.initCurrentMediaSegment()
+		var part XPart
+		if err = part.decode(attr); err == nil {
+			p.curseg.Parts = append(p.curseg.Parts, part)
+		}
+
+	case EXT_X_SKIP:
+		// RFC 8216bis, 4.4.5.2:
+		// It MUST appear before the first Media Segment in the Playlist.
+		if len(p.media.Segments) > 0 || p.curseg != nil {
+			err = errNotBeforeMediaSegment
+		} else {
+			err = p.media.Skip.decode(attr)
+		}
+
+	case EXT_X_PRELOAD_HINT:
+		// RFC 8216bis, 4.4.5.3:
+		var hint XPreloadHint
+		if err = hint.decode(attr); err == nil {
+			p.media.PreloadHints = append(p.media.PreloadHints, hint)
+		}
+
+	case EXT_X_RENDITION_REPORT:
+		// RFC 8216bis, 4.4.5.4:
+		var report XRenditionReport
+		if err = report.decode(attr); err == nil {
+			p.media.RenditionReports = append(p.media.RenditionReports, report)
+		}
 	}
 
 	return
@@ -106,3 +106,110 @@ http://media.example.com/third.ts
 		}
 	}
 }
+
+func TestMediaPlayListParserLowLatency(t *testing.T) {
+	const s = `
+#EXTM3U
+#EXT-X-VERSION:9
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,CAN-SKIP-UNTIL=24,PART-HOLD-BACK=1.002
+#EXT-X-PART-INF:PART-TARGET=0.334
+#EXT-X-MEDIA-SEQUENCE:266
+#EXT-X-SKIP:SKIPPED-SEGMENTS=2
+#EXTINF:4.00008,
+fileSequence268.mp4
+#EXT-X-PART:DURATION=0.334,INDEPENDENT=YES,URI="filePart269.0.mp4"
+#EXT-X-PART:DURATION=0.334,URI="filePart269.1.mp4",BYTERANGE="1000@2000"
+#EXTINF:0.668,
+fileSequence269.mp4
+#EXT-X-PART:DURATION=0.334,INDEPENDENT=YES,URI="filePart270.0.mp4"
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="filePart270.1.mp4"
+#EXT-X-RENDITION-REPORT:URI="../1M/waitForMSN.php",LAST-MSN=270,LAST-PART=0
+#EXT-X-RENDITION-REPORT:URI="../4M/waitForMSN.php",LAST-MSN=269
+`
+
+	var media MediaPlayList
+	if err := media.Parse(strings.NewReader(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := XServerControl{CanBlockReload: true, CanSkipUntil: 24, PartHoldBack: 1.002}
+	if media.ServerControl != expect {
+		t.Errorf("expect server control %+v, but got %+v", expect, media.ServerControl)
+	}
+	if media.PartInf.PartTarget != 0.334 {
+		t.Errorf("expect part target %v, but got %v", 0.334, media.PartInf.PartTarget)
+	}
+	if media.MediaSequence != 266 {
+		t.Errorf("expect media sequence %d, but got %d", 266, media.MediaSequence)
+	}
+
+	if len(media.Segments) != 2 {
+		t.Fatalf("expect %d media segments, but got %d", 2, len(media.Segments))
+	}
+	if seq := media.Segments[0].MediaSequence; seq != 268 {
+		t.Errorf("expect media sequence %d, but got %d", 268, seq)
+	}
+	if index := media.GetSegmentIndexByMediaSequence(269); index != 1 {
+		t.Errorf("expect segment index %d, but got %d", 1, index)
+	}
+
+	parts := []XPart{
+		{URI: "filePart269.0.mp4", Duration: 0.334, Independent: true},
+		{URI: "filePart269.1.mp4", Duration: 0.334, ByteRange: XByteRange{Length: 1000, Offset: 2000}},
+	}
+	if !reflect.DeepEqual(media.Segments[1].Parts, parts) {
+		t.Errorf("expect parts %+v, but got %+v", parts, media.Segments[1].Parts)
+	}
+
+	parts = []XPart{{URI: "filePart270.0.mp4", Duration: 0.334, Independent: true}}
+	if !reflect.DeepEqual(media.Parts, parts) {
+		t.Errorf("expect parts %+v, but got %+v", parts, media.Parts)
+	}
+
+	hints := []XPreloadHint{{Type: XPreloadHintTypePart, URI: "filePart270.1.mp4"}}
+	if !reflect.DeepEqual(media.PreloadHints, hints) {
+		t.Errorf("expect preload hints %+v, but got %+v", hints, media.PreloadHints)
+	}
+
+	reports := []XRenditionReport{
+		{URI: "../1M/waitForMSN.php", LastMSN: 270, LastPart: 0, HasLastPart: true},
+		{URI: "../4M/waitForMSN.php", LastMSN: 269},
+	}
+	if !reflect.DeepEqual(media.RenditionReports, reports) {
+		t.Errorf("expect rendition reports %+v, but got %+v", reports, media.RenditionReports)
+	}
+}
+
+func TestMediaPlayListParserKeyIV(t *testing.T) {
+	const s = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x000102030405060708090A0B0C0D0E0F
+#EXTINF:4,
+1.ts
+`
+
+	var pl MediaPlayList
+	if err := pl.Parse(strings.NewReader(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "0x000102030405060708090a0b0c0d0e0f"
+	if iv := pl.Segments[0].Keys[0].IV; iv != expect {
+		t.Errorf("expect IV '%s', but got '%s'", expect, iv)
+	}
+
+	if iv, err := pl.Segments[0].IV(); err != nil {
+		t.Error(err)
+	} else if len(iv) != 16 || iv[15] != 15 {
+		t.Errorf("unexpected IV %x", iv)
+	}
+
+	var b strings.Builder
+	if err := pl.Output(&b); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(b.String(), "IV=0x000102030405060708090A0B0C0D0E0F") {
+		t.Errorf("expect the IV is kept, but got:\n%s", b.String())
+	}
+}
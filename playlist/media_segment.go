@@ -37,6 +37,8 @@ type MediaSegment struct {
 
 	ProgramDateTime time.Time `json:",omitempty,omitzero"`
 
+	Parts []XPart `json:",omitempty,omitzero"` // Low-Latency HLS
+
 	MediaSequence         uint64 `json:",omitempty,omitzero"` // Cannot be encoded
 	DiscontinuitySequence uint64 `json:",omitempty,omitzero"` // Cannot be encoded
 
@@ -75,7 +77,7 @@ func (s MediaSegment) AES128Decrypt(encryptedData, key []byte, removePadding boo
 //
 // NOTE: It is only valid for the same media playlist.
 func (pl MediaPlayList) GetSegmentIndexByMediaSequence(seq uint64) (index int) {
-	index = int(seq - pl.MediaSequence)
+	index = int(seq - pl.MediaSequence - pl.Skip.SkippedSegments)
 	if index < 0 || index >= len(pl.Segments) {
 		return -1
 	}
@@ -117,3 +119,26 @@ func (pl MediaPlayList) GetSegmentIndexByDuration(durationSecond float64) (index
 	}
 	return -1
 }
+
+// GetSegmentIndexByProgramDateTime returns the index of the media segment
+// whose the time range based on EXT-X-PROGRAM-DATE-TIME contains the given t.
+//
+// Return -1 if not found.
+func (pl MediaPlayList) GetSegmentIndexByProgramDateTime(t time.Time) (index int) {
+	var start time.Time
+	for i := range pl.Segments {
+		seg := &pl.Segments[i]
+		if !seg.ProgramDateTime.IsZero() {
+			start = seg.ProgramDateTime
+		} else if start.IsZero() {
+			continue
+		}
+
+		end := start.Add(float64ToDuration(seg.Duration))
+		if !t.Before(start) && t.Before(end) {
+			return i
+		}
+		start = end
+	}
+	return -1
+}
@@ -47,6 +47,14 @@ const (
 	// Media or Master Playlist Tags
 	EXT_X_INDEPENDENT_SEGMENTS Tag = "#EXT-X-INDEPENDENT-SEGMENTS" // RFC 8216, 4.3.5.1
 	EXT_X_START                Tag = "#EXT-X-START"                // RFC 8216, 4.3.5.2
+
+	// Low-Latency Tags
+	EXT_X_SERVER_CONTROL   Tag = "#EXT-X-SERVER-CONTROL"   // RFC 8216bis, 4.4.3.8
+	EXT_X_PART_INF         Tag = "#EXT-X-PART-INF"         // RFC 8216bis, 4.4.3.7
+	EXT_X_PART             Tag = "#EXT-X-PART"             // RFC 8216bis, 4.4.4.9
+	EXT_X_SKIP             Tag = "#EXT-X-SKIP"             // RFC 8216bis, 4.4.5.2
+	EXT_X_PRELOAD_HINT     Tag = "#EXT-X-PRELOAD-HINT"     // RFC 8216bis, 4.4.5.3
+	EXT_X_RENDITION_REPORT Tag = "#EXT-X-RENDITION-REPORT" // RFC 8216bis, 4.4.5.4
 )
 
 // Tag is used to define a playlist tag.
@@ -146,9 +146,10 @@ func (v *XResolution) decode(s string) (err error) {
 /// ----------------------------------------------------------------------- ///
 
 const (
-	XKeyMethodNone      = "NONE"
-	XKeyMethodAES128    = "AES-128"
-	XKeyMethodSampleAES = "SAMPLE-AES"
+	XKeyMethodNone         = "NONE"
+	XKeyMethodAES128       = "AES-128"
+	XKeyMethodSampleAES    = "SAMPLE-AES"
+	XKeyMethodSampleAESCTR = "SAMPLE-AES-CTR" // RFC 8216bis
 )
 
 // FormatIV formats the 16-octet bytes to a hexadecimal-sequence string
@@ -628,6 +629,10 @@ type XStreamInf struct {
 	Video          string `json:",omitempty,omitzero"`
 	Subtitles      string `json:",omitempty,omitzero"`
 	ClosedCaptions string `json:",omitempty,omitzero"`
+
+	// Content Steering and Redundant Streams
+	PathwayId       string `json:",omitempty,omitzero"`
+	StableVariantId string `json:",omitempty,omitzero"`
 }
 
 func (x XStreamInf) IsZero() bool {
@@ -656,6 +661,9 @@ func (x XStreamInf) encode(w io.Writer) (err error) {
 		_NewAttr("VIDEO", _QuotedString(x.Video)),
 		_NewAttr("SUBTITLES", _QuotedString(x.Subtitles)),
 		_NewAttr("CLOSED-CAPTIONS", closedCaptions),
+
+		_NewAttr("PATHWAY-ID", _QuotedString(x.PathwayId)),
+		_NewAttr("STABLE-VARIANT-ID", _QuotedString(x.StableVariantId)),
 	)
 
 	err = tryWriteAny(w, err, "\n", _UnquotedString(x.URI))
@@ -728,6 +736,18 @@ func (x *XStreamInf) decode(s string) (err error) {
 					x.ClosedCaptions = s.get()
 				}
 			}
+
+		case "PATHWAY-ID":
+			var s _QuotedString
+			if err = s.decode(value); err == nil {
+				x.PathwayId = s.get()
+			}
+
+		case "STABLE-VARIANT-ID":
+			var s _QuotedString
+			if err = s.decode(value); err == nil {
+				x.StableVariantId = s.get()
+			}
 		}
 		return
 	})
@@ -761,6 +781,10 @@ type XIFrameStreamInf struct {
 	Resolution XResolution `json:",omitzero"`
 
 	Video string `json:",omitempty,omitzero"`
+
+	// Content Steering and Redundant Streams
+	PathwayId       string `json:",omitempty,omitzero"`
+	StableVariantId string `json:",omitempty,omitzero"`
 }
 
 func (x XIFrameStreamInf) IsZero() bool {
@@ -779,6 +803,8 @@ func (x XIFrameStreamInf) encode(w io.Writer) (err error) {
 		_NewAttr("HDCP-LEVEL", newEnum(x.HdcpLevel)),
 		_NewAttr("RESOLUTION", x.Resolution),
 		_NewAttr("VIDEO", _QuotedString(x.Video)),
+		_NewAttr("PATHWAY-ID", _QuotedString(x.PathwayId)),
+		_NewAttr("STABLE-VARIANT-ID", _QuotedString(x.StableVariantId)),
 		_NewAttr("URI", _QuotedString(x.URI)),
 	)
 }
@@ -827,6 +853,18 @@ func (x *XIFrameStreamInf) decode(s string) (err error) {
 			if err = s.decode(value); err == nil {
 				x.Video = s.get()
 			}
+
+		case "PATHWAY-ID":
+			var s _QuotedString
+			if err = s.decode(value); err == nil {
+				x.PathwayId = s.get()
+			}
+
+		case "STABLE-VARIANT-ID":
+			var s _QuotedString
+			if err = s.decode(value); err == nil {
+				x.StableVariantId = s.get()
+			}
 		}
 		return
 	})
@@ -878,25 +916,25 @@ func (x *XSessionData) decode(s string) (err error) {
 		switch name {
 		case "DATA-ID":
 			var v _QuotedString
-			if err = v.decode(s); err == nil {
+			if err = v.decode(value); err == nil {
 				x.DataId = v.get()
 			}
 
 		case "VALUE":
 			var v _QuotedString
-			if err = v.decode(s); err == nil {
+			if err = v.decode(value); err == nil {
 				x.Value = v.get()
 			}
 
 		case "LANGUAGE":
 			var v _QuotedString
-			if err = v.decode(s); err == nil {
+			if err = v.decode(value); err == nil {
 				x.Language = v.get()
 			}
 
 		case "URI":
 			var v _QuotedString
-			if err = v.decode(s); err == nil {
+			if err = v.decode(value); err == nil {
 				x.URI = v.get()
 			}
 		}
@@ -918,16 +956,26 @@ func (x XSessionData) check() (err error) {
 
 /// ----------------------------------------------------------------------- ///
 
+// XStart represents the tag EXT-X-START.
+//
+// The tag with the zero TimeOffset is omitted as the zero value, unless
+// it is parsed from TIME-OFFSET=0 or set by MediaPlayList.StartOver.
 type XStart struct {
 	TimeOffset float64 `json:",omitempty,omitzero"` // Required. Unit: Second
 	Precise    bool    `json:",omitempty,omitzero"`
+
+	explicit bool // Whether TIME-OFFSET=0 is present.
 }
 
-func (x XStart) IsZero() bool { return x.TimeOffset == 0 }
+func (x XStart) IsZero() bool { return x.TimeOffset == 0 && !x.explicit }
 
 func (x XStart) encode(w io.Writer) (err error) {
-	return tryWriteAttrs(w, nil, true,
-		_NewAttr("TIME-OFFSET", _SignDecimalFloat(x.TimeOffset)),
+	// Write TIME-OFFSET even if it is 0, because it is required.
+	err = tryWriteString(w, err, "TIME-OFFSET=")
+	if err == nil {
+		err = _SignDecimalFloat(x.TimeOffset).encode(w)
+	}
+	return tryWriteAttrs(w, err, false,
 		_NewAttr("PRECISE", _Bool(x.Precise)),
 	)
 }
@@ -946,7 +994,7 @@ func (x *XStart) decode(s string) (err error) {
 			if err = offset.decode(value); err != nil {
 				err = fmt.Errorf("invalid TIME-OFFSET: %w", err)
 			} else {
-				x.TimeOffset = offset.get()
+				x.TimeOffset, x.explicit = offset.get(), true
 			}
 
 		case "PRECISE":
@@ -968,7 +1016,7 @@ func (x *XStart) decode(s string) (err error) {
 }
 
 func (x *XStart) check() (err error) {
-	if x.TimeOffset == 0 {
+	if x.TimeOffset == 0 && !x.explicit {
 		return errors.New("missing TIME-OFFSET")
 	}
 
@@ -190,6 +190,7 @@ func (v _HexSequence) String() string {
 
 	var b strings.Builder
 	b.Grow(hex.EncodedLen(len(v)) + 2)
+	b.WriteString("0x")
 	_, _ = hex.NewEncoder(&b).Write(v)
 	return b.String()
 }
@@ -0,0 +1,397 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Define the query parameters of the Low-Latency HLS.
+//
+// See [[RFC 8216bis, 6.2.5]].
+//
+// [RFC 8216bis, 6.2.5]: https://datatracker.ietf.org/doc/html/draft-pantos-hls-rfc8216bis#section-6.2.5
+const (
+	QueryHLSMsn  = "_HLS_msn"
+	QueryHLSPart = "_HLS_part"
+	QueryHLSSkip = "_HLS_skip"
+)
+
+/// ----------------------------------------------------------------------- ///
+
+// XServerControl represents the server control of the Low-Latency HLS.
+type XServerControl struct {
+	CanSkipUntil      float64 `json:",omitempty,omitzero"` // Unit: second
+	CanSkipDateRanges bool    `json:",omitempty,omitzero"`
+	HoldBack          float64 `json:",omitempty,omitzero"` // Unit: second
+	PartHoldBack      float64 `json:",omitempty,omitzero"` // Unit: second
+	CanBlockReload    bool    `json:",omitempty,omitzero"`
+}
+
+func (x XServerControl) IsZero() bool { return x == XServerControl{} }
+
+func (x XServerControl) encode(w io.Writer) (err error) {
+	return tryWriteAttrs(w, nil, true,
+		_NewAttr("CAN-BLOCK-RELOAD", _Bool(x.CanBlockReload)),
+		_NewAttr("CAN-SKIP-UNTIL", _DecimalFloat(x.CanSkipUntil)),
+		_NewAttr("CAN-SKIP-DATERANGES", _Bool(x.CanSkipDateRanges)),
+		_NewAttr("HOLD-BACK", _DecimalFloat(x.HoldBack)),
+		_NewAttr("PART-HOLD-BACK", _DecimalFloat(x.PartHoldBack)),
+	)
+}
+
+func (x *XServerControl) decode(s string) (err error) {
+	return iterAttributes(s, -1, func(name, value string) (err error) {
+		switch name {
+		case "CAN-SKIP-UNTIL":
+			var v _DecimalFloat
+			if err = v.decode(value); err == nil {
+				x.CanSkipUntil = v.get()
+			}
+
+		case "CAN-SKIP-DATERANGES":
+			var v _Bool
+			if err = v.decode(value); err == nil {
+				x.CanSkipDateRanges = v.get()
+			}
+
+		case "HOLD-BACK":
+			var v _DecimalFloat
+			if err = v.decode(value); err == nil {
+				x.HoldBack = v.get()
+			}
+
+		case "PART-HOLD-BACK":
+			var v _DecimalFloat
+			if err = v.decode(value); err == nil {
+				x.PartHoldBack = v.get()
+			}
+
+		case "CAN-BLOCK-RELOAD":
+			var v _Bool
+			if err = v.decode(value); err == nil {
+				x.CanBlockReload = v.get()
+			}
+		}
+		return
+	})
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// XPartInf represents the information about the partial segments.
+type XPartInf struct {
+	PartTarget float64 `json:",omitempty,omitzero"` // Required. Unit: second
+}
+
+func (x XPartInf) IsZero() bool { return x.PartTarget == 0 }
+
+func (x XPartInf) encode(w io.Writer) (err error) {
+	return tryWriteAttrs(w, nil, true, _NewAttr("PART-TARGET", _DecimalFloat(x.PartTarget)))
+}
+
+func (x *XPartInf) decode(s string) (err error) {
+	err = iterAttributes(s, -1, func(name, value string) (err error) {
+		if name == "PART-TARGET" {
+			var v _DecimalFloat
+			if err = v.decode(value); err == nil {
+				x.PartTarget = v.get()
+			}
+		}
+		return
+	})
+
+	if err == nil && x.PartTarget <= 0 {
+		err = errors.New("missing PART-TARGET")
+	}
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// XPart represents a partial segment of a media segment.
+type XPart struct {
+	URI       string     `json:",omitempty,omitzero"` // Required
+	Duration  float64    `json:",omitempty,omitzero"` // Required. Unit: second
+	ByteRange XByteRange `json:",omitzero"`
+
+	Independent bool `json:",omitempty,omitzero"`
+	Gap         bool `json:",omitempty,omitzero"`
+}
+
+func (x XPart) IsZero() bool { return x.URI == "" }
+
+func (x XPart) encode(w io.Writer) (err error) {
+	if err = x.check(); err != nil {
+		return
+	}
+
+	var byterange _QuotedString
+	if x.ByteRange.valid() {
+		var buf strings.Builder
+		_ = x.ByteRange.encode(&buf)
+		byterange = _QuotedString(buf.String())
+	}
+
+	return tryWriteAttrs(w, nil, true,
+		_NewAttr("DURATION", _DecimalFloat(x.Duration)),
+		_NewAttr("URI", _QuotedString(x.URI)),
+		_NewAttr("BYTERANGE", byterange),
+		_NewAttr("INDEPENDENT", _Bool(x.Independent)),
+		_NewAttr("GAP", _Bool(x.Gap)),
+	)
+}
+
+func (x *XPart) decode(s string) (err error) {
+	err = iterAttributes(s, -1, func(name, value string) (err error) {
+		switch name {
+		case "URI":
+			var v _QuotedString
+			if err = v.decode(value); err == nil {
+				x.URI = v.get()
+			}
+
+		case "DURATION":
+			var v _DecimalFloat
+			if err = v.decode(value); err == nil {
+				x.Duration = v.get()
+			}
+
+		case "BYTERANGE":
+			var v _QuotedString
+			if err = v.decode(value); err == nil {
+				err = x.ByteRange.decode(v.get())
+			}
+
+		case "INDEPENDENT":
+			var v _Bool
+			if err = v.decode(value); err == nil {
+				x.Independent = v.get()
+			}
+
+		case "GAP":
+			var v _Bool
+			if err = v.decode(value); err == nil {
+				x.Gap = v.get()
+			}
+		}
+		return
+	})
+
+	if err == nil {
+		err = x.check()
+	}
+	return
+}
+
+func (x XPart) check() (err error) {
+	switch {
+	case x.URI == "":
+		return errors.New("missing URI")
+	case x.Duration <= 0:
+		return errors.New("missing DURATION")
+	}
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+const (
+	XPreloadHintTypePart = "PART"
+	XPreloadHintTypeMap  = "MAP"
+)
+
+// XPreloadHint represents a hint that the resource will be required
+// to play the media playlist.
+type XPreloadHint struct {
+	Type string `json:",omitempty,omitzero"` // Required
+	URI  string `json:",omitempty,omitzero"` // Required
+
+	ByteRangeStart  uint64 `json:",omitempty,omitzero"`
+	ByteRangeLength uint64 `json:",omitempty,omitzero"` // 0 means the end of the resource
+}
+
+func (x XPreloadHint) IsZero() bool { return x.URI == "" }
+
+func (x XPreloadHint) encode(w io.Writer) (err error) {
+	if err = x.check(); err != nil {
+		return
+	}
+
+	return tryWriteAttrs(w, nil, true,
+		_NewAttr("TYPE", newEnum(x.Type)),
+		_NewAttr("URI", _QuotedString(x.URI)),
+		_NewAttr("BYTERANGE-START", _DecimalInteger(x.ByteRangeStart)),
+		_NewAttr("BYTERANGE-LENGTH", _DecimalInteger(x.ByteRangeLength)),
+	)
+}
+
+func (x *XPreloadHint) decode(s string) (err error) {
+	err = iterAttributes(s, -1, func(name, value string) (err error) {
+		switch name {
+		case "TYPE":
+			var v _Enum
+			if err = v.decode(value); err == nil {
+				x.Type = v.get()
+			}
+
+		case "URI":
+			var v _QuotedString
+			if err = v.decode(value); err == nil {
+				x.URI = v.get()
+			}
+
+		case "BYTERANGE-START":
+			var v _DecimalInteger
+			if err = v.decode(value, 0); err == nil {
+				x.ByteRangeStart = v.get()
+			}
+
+		case "BYTERANGE-LENGTH":
+			var v _DecimalInteger
+			if err = v.decode(value, 0); err == nil {
+				x.ByteRangeLength = v.get()
+			}
+		}
+		return
+	})
+
+	if err == nil {
+		err = x.check()
+	}
+	return
+}
+
+func (x XPreloadHint) check() (err error) {
+	switch {
+	case x.Type == "":
+		return errors.New("missing TYPE")
+	case x.URI == "":
+		return errors.New("missing URI")
+	}
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// XRenditionReport represents a report about the media playlist
+// of another rendition.
+type XRenditionReport struct {
+	URI     string `json:",omitempty,omitzero"` // Required
+	LastMSN uint64 `json:",omitempty,omitzero"` // Required
+
+	// LastPart is the index of the last partial segment, which is encoded
+	// as LAST-PART only if HasLastPart is true. So the zero value means
+	// that the rendition has no partial segments, not the first one.
+	LastPart    int64 `json:",omitempty,omitzero"`
+	HasLastPart bool  `json:",omitempty,omitzero"`
+}
+
+func (x XRenditionReport) IsZero() bool { return x.URI == "" }
+
+func (x XRenditionReport) encode(w io.Writer) (err error) {
+	if x.URI == "" {
+		return errInvalidURI
+	}
+
+	err = tryWriteAttrs(w, nil, true, _NewAttr("URI", _QuotedString(x.URI)))
+	err = tryWriteString(w, err, fmt.Sprintf(",LAST-MSN=%d", x.LastMSN))
+	if x.HasLastPart {
+		err = tryWriteString(w, err, fmt.Sprintf(",LAST-PART=%d", x.LastPart))
+	}
+	return
+}
+
+func (x *XRenditionReport) decode(s string) (err error) {
+	err = iterAttributes(s, -1, func(name, value string) (err error) {
+		switch name {
+		case "URI":
+			var v _QuotedString
+			if err = v.decode(value); err == nil {
+				x.URI = v.get()
+			}
+
+		case "LAST-MSN":
+			var v _DecimalInteger
+			if err = v.decode(value, 0); err == nil {
+				x.LastMSN = v.get()
+			}
+
+		case "LAST-PART":
+			var v _DecimalInteger
+			if err = v.decode(value, 0); err == nil {
+				x.LastPart, x.HasLastPart = int64(v.get()), true
+			}
+		}
+		return
+	})
+
+	if err == nil && x.URI == "" {
+		err = errors.New("missing URI")
+	}
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// XSkip represents the media segments skipped by the delta update.
+type XSkip struct {
+	SkippedSegments uint64 `json:",omitempty,omitzero"` // Required
+
+	RecentlyRemovedDateRanges []string `json:",omitempty,omitzero"`
+}
+
+func (x XSkip) IsZero() bool { return x.SkippedSegments == 0 }
+
+func (x XSkip) minVersion() uint64 {
+	switch {
+	case len(x.RecentlyRemovedDateRanges) > 0:
+		return 10
+	case x.SkippedSegments > 0:
+		return 9
+	default:
+		return 1
+	}
+}
+
+func (x XSkip) encode(w io.Writer) (err error) {
+	return tryWriteAttrs(w, nil, true,
+		_NewAttr("SKIPPED-SEGMENTS", _DecimalInteger(x.SkippedSegments)),
+		_NewAttr("RECENTLY-REMOVED-DATERANGES", _QuotedString(strings.Join(x.RecentlyRemovedDateRanges, "\t"))),
+	)
+}
+
+func (x *XSkip) decode(s string) (err error) {
+	err = iterAttributes(s, -1, func(name, value string) (err error) {
+		switch name {
+		case "SKIPPED-SEGMENTS":
+			var v _DecimalInteger
+			if err = v.decode(value, 0); err == nil {
+				x.SkippedSegments = v.get()
+			}
+
+		case "RECENTLY-REMOVED-DATERANGES":
+			var v _QuotedString
+			if err = v.decode(value); err == nil {
+				x.RecentlyRemovedDateRanges = strings.Split(v.get(), "\t")
+			}
+		}
+		return
+	})
+	return
+}
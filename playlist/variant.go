@@ -0,0 +1,97 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Variant is a logical variant stream of the master playlist,
+// which contains the redundant streams with the same content
+// as the primary and the backups.
+type Variant struct {
+	// Streams is the redundant streams, the first of which is the primary
+	// and the others are the backups in order.
+	Streams []MasterStream
+}
+
+// Primary returns the primary stream of the variant.
+func (v Variant) Primary() MasterStream {
+	return v.Streams[0]
+}
+
+// URIs returns the uris of all the redundant streams in order.
+func (v Variant) URIs() []string {
+	uris := make([]string, len(v.Streams))
+	for i := range v.Streams {
+		uris[i] = v.Streams[i].Stream.URI
+	}
+	return uris
+}
+
+// Variants groups the redundant streams into the logical variant streams
+// in order of their first appearance.
+//
+// The streams with the same STABLE-VARIANT-ID are the same variant.
+// For the streams without STABLE-VARIANT-ID, they are the same variant
+// if all the attributes of EXT-X-STREAM-INF are equal except the uri,
+// PATHWAY-ID and the group ids of the renditions, because the redundant
+// streams may reference the different rendition groups.
+//
+// The backups in a variant are ordered by their appearance. If pathways
+// is given, the streams in the pathway with the higher priority come first,
+// and the streams not in pathways come last.
+func (pl MasterPlayList) Variants(pathways ...string) []Variant {
+	var variants []Variant
+	indexes := make(map[string]int, len(pl.Streams))
+	for _, stream := range pl.Streams {
+		key := variantKey(stream.Stream)
+		if index, ok := indexes[key]; ok {
+			variants[index].Streams = append(variants[index].Streams, stream)
+		} else {
+			indexes[key] = len(variants)
+			variants = append(variants, Variant{Streams: []MasterStream{stream}})
+		}
+	}
+
+	if len(pathways) > 0 {
+		priority := func(s MasterStream) int {
+			if index := slices.Index(pathways, s.Stream.PathwayId); index >= 0 {
+				return index
+			}
+			return len(pathways)
+		}
+
+		for _, variant := range variants {
+			slices.SortStableFunc(variant.Streams, func(a, b MasterStream) int {
+				return priority(a) - priority(b)
+			})
+		}
+	}
+
+	return variants
+}
+
+func variantKey(x XStreamInf) string {
+	if x.StableVariantId != "" {
+		return "id:" + x.StableVariantId
+	}
+
+	return fmt.Sprintf("attr:%d,%d,%s,%s,%g,%dx%d", x.Bandwidth, x.AverageBandwidth,
+		strings.Join(x.Codecs, ","), x.HdcpLevel, x.FrameRate,
+		x.Resolution.Width, x.Resolution.Height)
+}
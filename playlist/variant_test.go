@@ -0,0 +1,77 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMasterPlayListVariants(t *testing.T) {
+	var pl MasterPlayList
+	err := pl.Parse(strings.NewReader(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1280x720,AUDIO="aac-a"
+http://a.example.com/720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=640x360,AUDIO="aac-a"
+http://a.example.com/360p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1280x720,AUDIO="aac-b"
+http://b.example.com/720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=640x360,AUDIO="aac-b"
+http://b.example.com/360p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,PATHWAY-ID="CDN-A",STABLE-VARIANT-ID="1080p"
+http://a.example.com/1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3100000,PATHWAY-ID="CDN-B",STABLE-VARIANT-ID="1080p"
+http://b.example.com/1080p.m3u8
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := pl.Streams[4].Stream; s.PathwayId != "CDN-A" || s.StableVariantId != "1080p" {
+		t.Errorf("unexpected stream: %+v", s)
+	}
+
+	var buf strings.Builder
+	if err := pl.Output(&buf); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), `PATHWAY-ID="CDN-B",STABLE-VARIANT-ID="1080p"`) {
+		t.Errorf("missing PATHWAY-ID and STABLE-VARIANT-ID:\n%s", buf.String())
+	}
+
+	variants := pl.Variants()
+	expects := [][]string{
+		{"http://a.example.com/720p.m3u8", "http://b.example.com/720p.m3u8"},
+		{"http://a.example.com/360p.m3u8", "http://b.example.com/360p.m3u8"},
+		{"http://a.example.com/1080p.m3u8", "http://b.example.com/1080p.m3u8"},
+	}
+
+	if len(variants) != len(expects) {
+		t.Fatalf("expect %d variants, but got %d", len(expects), len(variants))
+	}
+	for i, expect := range expects {
+		if uris := variants[i].URIs(); !slices.Equal(uris, expect) {
+			t.Errorf("%d: expect uris %v, but got %v", i, expect, uris)
+		}
+	}
+
+	variants = pl.Variants("CDN-B")
+	if uri := variants[2].Primary().Stream.URI; uri != "http://b.example.com/1080p.m3u8" {
+		t.Errorf("expect primary uri '%s', but got '%s'", "http://b.example.com/1080p.m3u8", uri)
+	}
+	if uri := variants[0].Primary().Stream.URI; uri != "http://a.example.com/720p.m3u8" {
+		t.Errorf("expect primary uri '%s', but got '%s'", "http://a.example.com/720p.m3u8", uri)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// LiveToVOD merges the snapshots of a live media playlist, which are
+// reloaded from the same url at the different time, into a VOD playlist
+// with EXT-X-PLAYLIST-TYPE:VOD and EXT-X-ENDLIST when the live event ends.
+//
+// The media segments are matched and de-duplicated by the media sequence
+// number, and the keys, the map, the discontinuities and the program date
+// time of them are preserved. If some media segments are lost between
+// two snapshots, the next media segment is marked as the discontinuity,
+// and its implicit IV derived from the media sequence number is set
+// explicitly, because it will be moved forward.
+//
+// Each rendition is converted on its own. So the media sequence and the
+// discontinuity sequence numbers of the renditions in a master playlist
+// stay aligned only if the snapshots of them lose the same media segments,
+// such as the ones reloaded at the same time. Or, the same media segment
+// may have the different numbers in the renditions.
+//
+// If rewrite is not nil, it is used to rewrite the uris of the media segments,
+// the maps and the keys, such as the paths of the archive storage.
+func LiveToVOD(snapshots []MediaPlayList, rewrite func(uri string) string) (vod MediaPlayList, err error) {
+	var segments []MediaSegment
+	independent := len(snapshots) > 0
+	for i := range snapshots {
+		snapshot := snapshots[i]
+		snapshot.Segments = slices.Clone(snapshot.Segments)
+		snapshot.update()
+		carrySegments(snapshot.Segments)
+
+		if segments, err = mergeSegments(segments, snapshot.Segments); err != nil {
+			return
+		}
+
+		independent = independent && snapshot.IndependentSegments
+		vod.IFrameOnly = vod.IFrameOnly || snapshot.IFrameOnly
+		vod.Version = max(vod.Version, snapshot.Version)
+		vod.TargetDuration = max(vod.TargetDuration, snapshot.TargetDuration)
+	}
+
+	if len(segments) == 0 {
+		return MediaPlayList{}, errors.New("no media segments in the snapshots")
+	}
+
+	for i := range segments {
+		seg := &segments[i]
+		seg.Parts = nil
+		if i > 0 && (seg.MediaSequence != segments[i-1].MediaSequence+1 ||
+			seg.DiscontinuitySequence != segments[i-1].DiscontinuitySequence) {
+			seg.Discontinuity = true
+		}
+
+		if rewrite != nil {
+			seg.URI = rewrite(seg.URI)
+			if !seg.Map.IsZero() {
+				seg.Map.URI = rewrite(seg.Map.URI)
+			}
+			for j := range seg.Keys {
+				if seg.Keys[j].URI != "" {
+					seg.Keys[j].URI = rewrite(seg.Keys[j].URI)
+				}
+			}
+		}
+
+		if seq := segments[0].MediaSequence + uint64(i); seg.MediaSequence != seq {
+			setExplicitIV(seg)
+		}
+
+		vod.TargetDuration = max(vod.TargetDuration, uint64(math.Round(seg.Duration)))
+	}
+
+	vod.Segments = segments
+	vod.MediaSequence = segments[0].MediaSequence
+	vod.DiscontinuitySequence = segments[0].DiscontinuitySequence
+	if segments[0].Discontinuity {
+		vod.DiscontinuitySequence--
+	}
+
+	vod.PlayListType = MediaPlayListTypeVOD
+	vod.IndependentSegments = independent
+	vod.EndList = true
+	vod.Version = max(vod.Version, vod.minVersion())
+
+	vod.update()
+	return
+}
+
+// carrySegments makes each media segment carry the keys and the map
+// that it inherits from the preceding ones.
+func carrySegments(segments []MediaSegment) {
+	for i := 1; i < len(segments); i++ {
+		seg, prev := &segments[i], &segments[i-1]
+		if len(seg.Keys) == 0 {
+			seg.Keys = prev.Keys
+		}
+		if seg.Map.IsZero() {
+			seg.Map = prev.Map
+		}
+	}
+}
+
+func mergeSegments(merged, segments []MediaSegment) ([]MediaSegment, error) {
+	for _, seg := range segments {
+		index, found := slices.BinarySearchFunc(merged, seg.MediaSequence,
+			func(s MediaSegment, seq uint64) int {
+				switch {
+				case s.MediaSequence < seq:
+					return -1
+				case s.MediaSequence > seq:
+					return 1
+				default:
+					return 0
+				}
+			})
+
+		if !found {
+			seg.Keys = slices.Clone(seg.Keys)
+			merged = slices.Insert(merged, index, seg)
+		} else if merged[index].URI != seg.URI {
+			return nil, fmt.Errorf("media segment %d has the different uris '%s' and '%s'",
+				seg.MediaSequence, merged[index].URI, seg.URI)
+		}
+	}
+	return merged, nil
+}
+
+func setExplicitIV(seg *MediaSegment) {
+	for i := range seg.Keys {
+		key := &seg.Keys[i]
+		if key.Method != "" && key.Method != XKeyMethodNone && key.IV == "" {
+			key.IV = fmt.Sprintf("0x%032x", seg.MediaSequence)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseTestMediaPlayList(t *testing.T, s string) (pl MediaPlayList) {
+	t.Helper()
+	if err := pl.Parse(strings.NewReader(s)); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestLiveToVOD(t *testing.T) {
+	snapshot1 := parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PROGRAM-DATE-TIME:2025-01-01T00:00:00Z
+#EXTINF:4,
+10.m4s
+#EXTINF:4,
+11.m4s
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+12.m4s
+`)
+
+	snapshot2 := parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:11
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4,
+11.m4s
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+12.m4s
+#EXTINF:4,
+13.m4s
+`)
+
+	// The media segments 14 and 15 are lost.
+	snapshot3 := parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:5
+#EXT-X-MEDIA-SEQUENCE:16
+#EXT-X-DISCONTINUITY-SEQUENCE:1
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4.5,
+16.m4s
+`)
+
+	vod, err := LiveToVOD([]MediaPlayList{snapshot2, snapshot1, snapshot3},
+		func(uri string) string { return "archive/" + uri })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !vod.EndList || vod.PlayListType != MediaPlayListTypeVOD {
+		t.Errorf("expect a VOD playlist, but got %+v", vod)
+	}
+	if vod.MediaSequence != 10 || vod.DiscontinuitySequence != 0 || vod.TargetDuration != 5 {
+		t.Errorf("unexpected media playlist: %+v", vod)
+	}
+
+	expects := []struct {
+		URI           string
+		Discontinuity bool
+		IV            string
+	}{
+		{URI: "archive/10.m4s"},
+		{URI: "archive/11.m4s"},
+		{URI: "archive/12.m4s", Discontinuity: true},
+		{URI: "archive/13.m4s"},
+		{URI: "archive/16.m4s", Discontinuity: true, IV: "0x00000000000000000000000000000010"},
+	}
+
+	if len(vod.Segments) != len(expects) {
+		t.Fatalf("expect %d segments, but got %d", len(expects), len(vod.Segments))
+	}
+
+	for i, expect := range expects {
+		seg := vod.Segments[i]
+		if seg.URI != expect.URI {
+			t.Errorf("%d: expect uri '%s', but got '%s'", i, expect.URI, seg.URI)
+		}
+		if seg.Discontinuity != expect.Discontinuity {
+			t.Errorf("%d: expect discontinuity %v, but got %v", i, expect.Discontinuity, seg.Discontinuity)
+		}
+		if len(seg.Keys) != 1 || seg.Keys[0].URI != "archive/key.bin" || seg.Keys[0].IV != expect.IV {
+			t.Errorf("%d: unexpected keys %+v", i, seg.Keys)
+		}
+		if seg.Map.URI != "archive/init.mp4" {
+			t.Errorf("%d: expect map '%s', but got '%s'", i, "archive/init.mp4", seg.Map.URI)
+		}
+	}
+
+	if pdt := vod.Segments[3].ProgramDateTime.Format("15:04:05"); pdt != "00:00:12" {
+		t.Errorf("expect program date time '%s', but got '%s'", "00:00:12", pdt)
+	}
+
+	var buf strings.Builder
+	if err := vod.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LiveToVOD([]MediaPlayList{snapshot1, {MediaSequence: 10,
+		Segments: []MediaSegment{{URI: "other.m4s", Duration: 4}}}}, nil); err == nil {
+		t.Errorf("expect an error for the conflict media segment, but got nil")
+	}
+
+	if _, err := LiveToVOD(nil, nil); err == nil {
+		t.Errorf("expect an error for no media segments, but got nil")
+	}
+}
+
+func TestLiveToVODRenditions(t *testing.T) {
+	// The snapshots of both the renditions are reloaded at the same time,
+	// so both of them lose the media segments 12 and 13.
+	snapshots := func(rendition string) []MediaPlayList {
+		return []MediaPlayList{
+			parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:10
+#EXTINF:4,
+`+rendition+`/10.ts
+#EXTINF:4,
+`+rendition+`/11.ts
+`),
+			parseTestMediaPlayList(t, `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:14
+#EXT-X-DISCONTINUITY-SEQUENCE:1
+#EXTINF:4,
+`+rendition+`/14.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+`+rendition+`/15.ts
+`),
+		}
+	}
+
+	video, err := LiveToVOD(snapshots("video"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audio, err := LiveToVOD(snapshots("audio"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(video.Segments) != 4 || len(audio.Segments) != len(video.Segments) {
+		t.Fatalf("expect %d segments, but got %d and %d", 4, len(video.Segments), len(audio.Segments))
+	}
+
+	for i := range video.Segments {
+		v, a := video.Segments[i], audio.Segments[i]
+		if strings.TrimPrefix(v.URI, "video/") != strings.TrimPrefix(a.URI, "audio/") {
+			t.Errorf("%d: expect the same media segment, but got '%s' and '%s'", i, v.URI, a.URI)
+		}
+		if v.MediaSequence != a.MediaSequence || v.DiscontinuitySequence != a.DiscontinuitySequence {
+			t.Errorf("%d: expect the same sequence numbers, but got %d/%d and %d/%d", i,
+				v.MediaSequence, v.DiscontinuitySequence, a.MediaSequence, a.DiscontinuitySequence)
+		}
+	}
+
+	if seg := video.Segments[2]; !seg.Discontinuity || seg.URI != "video/14.ts" {
+		t.Errorf("expect the discontinuity at '%s', but got %+v", "video/14.ts", seg)
+	}
+	if seg := video.Segments[3]; seg.DiscontinuitySequence != 2 {
+		t.Errorf("expect discontinuity sequence %d, but got %d", 2, seg.DiscontinuitySequence)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"slices"
+	"time"
+)
+
+// Slice returns a new media playlist only containing the media segments
+// in the range [start, end), which is used to build a view of the playlist,
+// such as the sliding window of a recorded EVENT playlist.
+//
+// The media sequence number and the discontinuity sequence number are
+// recalculated, and the keys, the map and the program date time that
+// the first media segment inherits from the preceding ones are carried.
+//
+// If the range does not start from the first media segment, the playlist
+// type EVENT is removed, because the media segments cannot be removed from
+// an EVENT playlist. If the range does not reach the last media segment,
+// EXT-X-ENDLIST and the Low-Latency tags of the live edge are removed.
+func (pl MediaPlayList) Slice(start, end int) MediaPlayList {
+	segments := slices.Clone(pl.Segments[start:end])
+	preceding := pl.Segments[:start]
+
+	view := pl
+	view.Segments = segments
+	view.Skip = XSkip{}
+	view.MediaSequence = pl.MediaSequence + pl.Skip.SkippedSegments + uint64(start)
+	view.DiscontinuitySequence = pl.DiscontinuitySequence
+	for i := range preceding {
+		if preceding[i].Discontinuity {
+			view.DiscontinuitySequence++
+		}
+	}
+
+	if start > 0 && pl.PlayListType == MediaPlayListTypeEvent {
+		view.PlayListType = ""
+	}
+
+	if end < len(pl.Segments) {
+		view.EndList = false
+		view.Parts = nil
+		view.PreloadHints = nil
+		view.RenditionReports = nil
+	}
+
+	if len(segments) > 0 {
+		carrySegment(&segments[0], preceding)
+	}
+
+	view.update()
+	return view
+}
+
+func carrySegment(first *MediaSegment, preceding []MediaSegment) {
+	var duration float64
+	for i := len(preceding) - 1; i >= 0; i-- {
+		seg := &preceding[i]
+		duration += seg.Duration
+
+		if len(first.Keys) == 0 && len(seg.Keys) > 0 {
+			first.Keys = slices.Clone(seg.Keys)
+		}
+		if first.Map.IsZero() && !seg.Map.IsZero() {
+			first.Map = seg.Map
+		}
+		if first.ProgramDateTime.IsZero() && !seg.ProgramDateTime.IsZero() {
+			first.ProgramDateTime = seg.nextProgramDateTime(duration)
+		}
+
+		if len(first.Keys) > 0 && !first.Map.IsZero() && !first.ProgramDateTime.IsZero() {
+			break
+		}
+	}
+}
+
+// LiveWindow returns a live view of the playlist, which only contains
+// the last media segments whose total duration reaches the window seconds
+// like a sliding window. If window is not positive, contain all.
+//
+// The playlist type is always removed, so that it keeps unchanged
+// when the view slides on the growing playlist.
+func (pl MediaPlayList) LiveWindow(window float64) MediaPlayList {
+	start := len(pl.Segments)
+	if window <= 0 {
+		start = 0
+	}
+
+	for total := 0.0; start > 0 && total < window; {
+		start--
+		total += pl.Segments[start].Duration
+	}
+
+	view := pl.Slice(start, len(pl.Segments))
+	view.PlayListType = ""
+	return view
+}
+
+// LiveWindowAt is the same as LiveWindow, but the view starts from
+// the media segment containing the absolute time t by EXT-X-PROGRAM-DATE-TIME
+// to the live edge, which is used to time-shift to a point in the past.
+//
+// If t is before the first media segment, start from the first one.
+// Return false if not found, for example, t is after the last media segment
+// or there is no EXT-X-PROGRAM-DATE-TIME.
+func (pl MediaPlayList) LiveWindowAt(t time.Time) (view MediaPlayList, ok bool) {
+	start := pl.GetSegmentIndexByProgramDateTime(t)
+	if start < 0 {
+		if len(pl.Segments) == 0 || pl.Segments[0].ProgramDateTime.IsZero() ||
+			!t.Before(pl.Segments[0].ProgramDateTime) {
+			return
+		}
+		start = 0
+	}
+
+	view = pl.Slice(start, len(pl.Segments))
+	view.PlayListType = ""
+	return view, true
+}
+
+// StartOver returns a "start-over" view of the playlist, which contains
+// all the media segments and the tag EXT-X-START with the time offset
+// in seconds, so the client starts to play from the offset, not the live edge.
+// A negative offset indicates a time offset from the end of the playlist.
+func (pl MediaPlayList) StartOver(offset float64, precise bool) MediaPlayList {
+	view := pl.Slice(0, len(pl.Segments))
+	view.Start = XStart{TimeOffset: offset, Precise: precise, explicit: true}
+	return view
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package playlist
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEventPlayList() MediaPlayList {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := XKey{Method: XKeyMethodAES128, URI: "key1.bin"}
+	return MediaPlayList{
+		TargetDuration: 4,
+		MediaSequence:  10,
+		PlayListType:   MediaPlayListTypeEvent,
+		Segments: []MediaSegment{
+			{URI: "seg10.m4s", Duration: 4, Keys: []XKey{key}, Map: XMap{URI: "init1.mp4"}, ProgramDateTime: start},
+			{URI: "seg11.m4s", Duration: 4},
+			{URI: "seg12.m4s", Duration: 4, Discontinuity: true, Map: XMap{URI: "init2.mp4"}},
+			{URI: "seg13.m4s", Duration: 4},
+			{URI: "seg14.m4s", Duration: 4},
+		},
+	}
+}
+
+func TestMediaPlayListLiveWindow(t *testing.T) {
+	pl := newTestEventPlayList()
+	pl.Version = pl.MinVersion()
+	pl.update()
+
+	view := pl.LiveWindow(6)
+	if len(view.Segments) != 2 || view.Segments[0].URI != "seg13.m4s" {
+		t.Fatalf("unexpected segments: %+v", view.Segments)
+	}
+
+	if view.MediaSequence != 13 {
+		t.Errorf("expect media sequence %d, but got %d", 13, view.MediaSequence)
+	}
+	if view.DiscontinuitySequence != 1 {
+		t.Errorf("expect discontinuity sequence %d, but got %d", 1, view.DiscontinuitySequence)
+	}
+	if view.PlayListType != "" {
+		t.Errorf("expect no playlist type, but got '%s'", view.PlayListType)
+	}
+
+	first := view.Segments[0]
+	if len(first.Keys) != 1 || first.Keys[0].URI != "key1.bin" {
+		t.Errorf("unexpected keys: %+v", first.Keys)
+	}
+	if first.Map.URI != "init2.mp4" {
+		t.Errorf("expect map '%s', but got '%s'", "init2.mp4", first.Map.URI)
+	}
+	if expect := pl.Segments[0].ProgramDateTime.Add(12 * time.Second); !first.ProgramDateTime.Equal(expect) {
+		t.Errorf("expect program date time '%s', but got '%s'", expect, first.ProgramDateTime)
+	}
+
+	// The original playlist is unchanged.
+	if len(pl.Segments[3].Keys) != 0 || !pl.Segments[3].Map.IsZero() {
+		t.Errorf("unexpected the original segment: %+v", pl.Segments[3])
+	}
+
+	var buf strings.Builder
+	if err := view.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed MediaPlayList
+	if err := parsed.Parse(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err)
+	} else if parsed.MediaSequence != 13 || parsed.DiscontinuitySequence != 1 ||
+		parsed.Segments[0].Map.URI != "init2.mp4" || len(parsed.Segments[0].Keys) != 1 {
+		t.Errorf("unexpected output playlist:\n%s", buf.String())
+	}
+
+	if view := pl.LiveWindow(0); len(view.Segments) != 5 || view.MediaSequence != 10 {
+		t.Errorf("expect all the segments, but got %+v", view.Segments)
+	}
+}
+
+func TestMediaPlayListLiveWindowAt(t *testing.T) {
+	pl := newTestEventPlayList()
+	pl.Version = pl.MinVersion()
+	start := pl.Segments[0].ProgramDateTime
+
+	view, ok := pl.LiveWindowAt(start.Add(9 * time.Second))
+	if !ok {
+		t.Fatal("expect the time is found")
+	}
+
+	// The discontinuity tag of the first segment is kept.
+	if view.MediaSequence != 12 || view.DiscontinuitySequence != 0 || !view.Segments[0].Discontinuity {
+		t.Errorf("unexpected view: %+v", view)
+	}
+	if view.Segments[2].DiscontinuitySequence != 1 {
+		t.Errorf("expect segment discontinuity sequence %d, but got %d", 1, view.Segments[2].DiscontinuitySequence)
+	}
+
+	if view, ok := pl.LiveWindowAt(start.Add(-time.Hour)); !ok || len(view.Segments) != 5 {
+		t.Errorf("expect all the segments, but got %v", ok)
+	}
+	if _, ok := pl.LiveWindowAt(start.Add(time.Hour)); ok {
+		t.Errorf("expect the time is not found")
+	}
+}
+
+func TestMediaPlayListStartOver(t *testing.T) {
+	pl := newTestEventPlayList()
+	pl.Version = pl.MinVersion()
+
+	view := pl.StartOver(8, true)
+	if len(view.Segments) != 5 || view.PlayListType != MediaPlayListTypeEvent {
+		t.Errorf("unexpected view: %+v", view)
+	}
+
+	var buf strings.Builder
+	if err := view.Output(&buf); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), "#EXT-X-START:TIME-OFFSET=8,PRECISE=YES\n") {
+		t.Errorf("missing EXT-X-START:\n%s", buf.String())
+	}
+
+	view = pl.StartOver(0, false)
+	if view.Start.TimeOffset != 0 {
+		t.Errorf("expect time offset %v, but got %v", 0, view.Start.TimeOffset)
+	}
+
+	buf.Reset()
+	if err := view.Output(&buf); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), "#EXT-X-START:TIME-OFFSET=0\n") {
+		t.Errorf("missing EXT-X-START:\n%s", buf.String())
+	}
+
+	if parsed, err := Parse(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err)
+	} else if start := parsed.(MediaPlayList).Start; start != view.Start {
+		t.Errorf("expect start %+v, but got %+v", view.Start, start)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// MasterHook is used to rewrite the master playlist before its URIs
+// are rewritten, that's, the URIs are still the original ones.
+type MasterHook interface {
+	HookMaster(r *http.Request, pl *playlist.MasterPlayList) error
+}
+
+// MasterHookFunc is a function to rewrite the master playlist.
+type MasterHookFunc func(r *http.Request, pl *playlist.MasterPlayList) error
+
+// HookMaster implements the interface MasterHook.
+func (f MasterHookFunc) HookMaster(r *http.Request, pl *playlist.MasterPlayList) error {
+	return f(r, pl)
+}
+
+// MediaHook is used to rewrite the media playlist before its URIs
+// are rewritten, that's, the URIs are still the original ones.
+type MediaHook interface {
+	HookMedia(r *http.Request, pl *playlist.MediaPlayList) error
+}
+
+// MediaHookFunc is a function to rewrite the media playlist.
+type MediaHookFunc func(r *http.Request, pl *playlist.MediaPlayList) error
+
+// HookMedia implements the interface MediaHook.
+func (f MediaHookFunc) HookMedia(r *http.Request, pl *playlist.MediaPlayList) error {
+	return f(r, pl)
+}
+
+// FilterVariants returns a master hook to only keep the variant streams
+// which keep returns true for.
+//
+// The renditions, the I-frame streams and the session tags declared
+// with the removed variant streams are moved to the next kept one.
+func FilterVariants(keep func(playlist.XStreamInf) bool) MasterHook {
+	return MasterHookFunc(func(_ *http.Request, pl *playlist.MasterPlayList) error {
+		streams := make([]playlist.MasterStream, 0, len(pl.Streams))
+		var orphan playlist.MasterStream
+		for _, s := range pl.Streams {
+			if s.Stream.URI != "" && !keep(s.Stream) {
+				orphan = mergeMasterStream(orphan, playlist.MasterStream{
+					Medias:        s.Medias,
+					IFrameStreams: s.IFrameStreams,
+					SessionDatas:  s.SessionDatas,
+					SessionKeys:   s.SessionKeys,
+				})
+				continue
+			}
+
+			s = mergeMasterStream(orphan, s)
+			orphan = playlist.MasterStream{}
+			streams = append(streams, s)
+		}
+
+		if len(streams) == 0 {
+			return errors.New("no variant stream is kept")
+		}
+
+		last := len(streams) - 1
+		streams[last] = mergeMasterStream(orphan, streams[last])
+		pl.Streams = streams
+		return nil
+	})
+}
+
+// mergeMasterStream prepends the tags except the variant stream of from
+// into into, and returns it.
+func mergeMasterStream(from, into playlist.MasterStream) playlist.MasterStream {
+	into.Medias = append(slices.Clip(from.Medias), into.Medias...)
+	into.IFrameStreams = append(slices.Clip(from.IFrameStreams), into.IFrameStreams...)
+	into.SessionDatas = append(slices.Clip(from.SessionDatas), into.SessionDatas...)
+	into.SessionKeys = append(slices.Clip(from.SessionKeys), into.SessionKeys...)
+	return into
+}
+
+// MaxHeight returns a master hook to remove the variant streams
+// and the I-frame streams, the resolution height of which is greater
+// than height, such as 1080.
+func MaxHeight(height uint64) MasterHook {
+	filter := FilterVariants(func(s playlist.XStreamInf) bool {
+		return s.Resolution.Height <= height
+	})
+
+	return MasterHookFunc(func(r *http.Request, pl *playlist.MasterPlayList) error {
+		if err := filter.HookMaster(r, pl); err != nil {
+			return err
+		}
+
+		for i := range pl.Streams {
+			iframes := pl.Streams[i].IFrameStreams[:0]
+			for _, s := range pl.Streams[i].IFrameStreams {
+				if s.Resolution.Height <= height {
+					iframes = append(iframes, s)
+				}
+			}
+			pl.Streams[i].IFrameStreams = iframes
+		}
+		return nil
+	})
+}
+
+// InjectSessionData returns a master hook to add the EXT-X-SESSION-DATA
+// tags, which replaces the ones with the same DATA-ID and LANGUAGE.
+func InjectSessionData(datas ...playlist.XSessionData) MasterHook {
+	return MasterHookFunc(func(_ *http.Request, pl *playlist.MasterPlayList) error {
+		if len(pl.Streams) == 0 {
+			return errors.New("no variant stream in the master playlist")
+		}
+
+	loop:
+		for _, data := range datas {
+			for i := range pl.Streams {
+				for j, sd := range pl.Streams[i].SessionDatas {
+					if sd.DataId == data.DataId && sd.Language == data.Language {
+						pl.Streams[i].SessionDatas[j] = data
+						continue loop
+					}
+				}
+			}
+			pl.Streams[0].SessionDatas = append(pl.Streams[0].SessionDatas, data)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,331 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/xgfone/go-hls/client"
+	"github.com/xgfone/go-hls/playlist"
+	"github.com/xgfone/go-hls/server"
+)
+
+// Define the query parameters of the rewritten URI.
+const (
+	QueryURL   = "url"
+	QueryToken = "token"
+)
+
+// The headers of the origin response passed through to the client.
+var passHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Range",
+	"Accept-Ranges",
+	"Cache-Control",
+	"Last-Modified",
+	"Etag",
+	"Expires",
+}
+
+// Proxy is a http handler as the reverse proxy of HLS.
+//
+// The request URI is like "PREFIX?url=ORIGIN_URL&token=TOKEN". The playlist
+// fetched from the origin url is parsed, rewritten by the hooks, and all
+// the URIs in it are rewritten to route through the proxy. The others,
+// such as the media segments and the keys, are passed through with the
+// "Range" header.
+//
+// To avoid being an open proxy, the origin url must be signed by Signer
+// or allowed by AllowURL. Or, the request is rejected with 403.
+type Proxy struct {
+	// Prefix is the path of the proxy in the rewritten URIs, such as "/hls".
+	Prefix string
+
+	// Signer is used to sign the origin urls in the rewritten URIs
+	// and verify them in the requests.
+	Signer Signer
+
+	// AllowURL reports whether the origin url is allowed to be fetched,
+	// such as AllowOrigins, which is checked together with Signer if set.
+	AllowURL func(origin string) bool
+
+	// Client is used to fetch the resources from the origins.
+	//
+	// Default: client.DefaultClient
+	Client *client.Client
+
+	// Handler is used to render the playlists, and its CORS settings
+	// are also applied to the passed-through resources.
+	//
+	// Default: server.NewHandler(nil)
+	Handler *server.Handler
+
+	MasterHooks []MasterHook
+	MediaHooks  []MediaHook
+}
+
+// New returns a new proxy with the prefix and the signer.
+//
+// If signer is nil, AllowURL must be set to allow the origin urls.
+func New(prefix string, signer Signer) *Proxy {
+	return &Proxy{Prefix: prefix, Signer: signer, Handler: server.NewHandler(nil)}
+}
+
+// AllowOrigins returns a function used by Proxy.AllowURL to only allow
+// the origin urls with the given origins, such as "https://cdn.example.com".
+func AllowOrigins(origins ...string) func(string) bool {
+	return func(origin string) bool {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" {
+			return false
+		}
+
+		origin = strings.ToLower(u.Scheme + "://" + u.Host)
+		for _, o := range origins {
+			if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// URL returns the URI of the proxy to fetch the origin url.
+func (p *Proxy) URL(origin string) string {
+	query := url.Values{QueryURL: []string{origin}}
+	if p.Signer != nil {
+		query.Set(QueryToken, p.Signer.Sign(origin))
+	}
+	return p.Prefix + "?" + query.Encode()
+}
+
+func (p *Proxy) client() *client.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return client.DefaultClient
+}
+
+// ServeHTTP implements the interface http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler := p.handler()
+	if !handler.HandleMethod(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	origin := query.Get(QueryURL)
+	switch {
+	case origin == "":
+		http.Error(w, "missing the origin url", http.StatusBadRequest)
+		return
+
+	case p.Signer == nil && p.AllowURL == nil:
+		http.Error(w, "no origin is allowed", http.StatusForbidden)
+		return
+
+	case p.Signer != nil && !p.Signer.Verify(origin, query.Get(QueryToken)):
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+
+	case p.AllowURL != nil && !p.AllowURL(origin):
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	// Forward the other query parameters, such as "_HLS_msn", to the origin.
+	options := make([]client.Option, 0, len(query))
+	for key, values := range query {
+		if key == QueryURL || key == QueryToken {
+			continue
+		}
+		for _, value := range values {
+			options = append(options, client.Query(key, value))
+		}
+	}
+
+	if rng := r.Header.Get("Range"); rng != "" {
+		options = append(options, func(req *http.Request) *http.Request {
+			req.Header.Set("Range", rng)
+			return req
+		})
+	}
+
+	var written bool
+	err := p.client().Get(r.Context(), origin, func(resp *http.Response) error {
+		if !isPlayList(origin, resp.Header.Get("Content-Type")) {
+			written = true
+			return passThrough(w, r, resp)
+		}
+
+		pl, err := playlist.Parse(resp.Body)
+		if err != nil {
+			return fmt.Errorf("invalid playlist: %w", err)
+		}
+
+		if pl, err = p.rewrite(r, origin, pl); err != nil {
+			return err
+		}
+
+		written = true
+		handler.ServePlayList(w, r, pl, time.Time{})
+		return nil
+	}, options...)
+
+	if err != nil && !written {
+		// Copy through the 4xx status code of the origin, such as 404,
+		// and respond with 502 for the others, such as the transport error.
+		code := http.StatusBadGateway
+		if se := (*client.StatusError)(nil); errors.As(err, &se) && se.StatusCode >= 400 && se.StatusCode < 500 {
+			code = se.StatusCode
+		}
+		http.Error(w, fmt.Sprintf("fail to fetch '%s': %s", origin, err), code)
+	}
+}
+
+func (p *Proxy) handler() *server.Handler {
+	if p.Handler != nil {
+		return p.Handler
+	}
+	return server.NewHandler(nil)
+}
+
+func (p *Proxy) rewrite(r *http.Request, origin string, pl playlist.PlayList) (playlist.PlayList, error) {
+	switch v := pl.(type) {
+	case playlist.MasterPlayList:
+		for _, hook := range p.MasterHooks {
+			if err := hook.HookMaster(r, &v); err != nil {
+				return nil, err
+			}
+		}
+		return v, p.rewriteMaster(origin, &v)
+
+	case playlist.MediaPlayList:
+		for _, hook := range p.MediaHooks {
+			if err := hook.HookMedia(r, &v); err != nil {
+				return nil, err
+			}
+		}
+		return v, p.rewriteMedia(origin, &v)
+
+	default:
+		return nil, fmt.Errorf("unsupported playlist type %T", pl)
+	}
+}
+
+func (p *Proxy) rewriteMaster(base string, pl *playlist.MasterPlayList) (err error) {
+	for i := range pl.Streams {
+		s := &pl.Streams[i]
+		rewriteURI(&err, p, base, &s.Stream.URI)
+		for j := range s.Medias {
+			rewriteURI(&err, p, base, &s.Medias[j].URI)
+		}
+		for j := range s.IFrameStreams {
+			rewriteURI(&err, p, base, &s.IFrameStreams[j].URI)
+		}
+		for j := range s.SessionDatas {
+			rewriteURI(&err, p, base, &s.SessionDatas[j].URI)
+		}
+		for j := range s.SessionKeys {
+			rewriteURI(&err, p, base, &s.SessionKeys[j].URI)
+		}
+	}
+	return
+}
+
+func (p *Proxy) rewriteMedia(base string, pl *playlist.MediaPlayList) (err error) {
+	for i := range pl.Segments {
+		seg := &pl.Segments[i]
+		rewriteURI(&err, p, base, &seg.URI)
+		rewriteURI(&err, p, base, &seg.Map.URI)
+		for j := range seg.Keys {
+			rewriteURI(&err, p, base, &seg.Keys[j].URI)
+		}
+		for j := range seg.Parts {
+			rewriteURI(&err, p, base, &seg.Parts[j].URI)
+		}
+	}
+
+	for i := range pl.Parts {
+		rewriteURI(&err, p, base, &pl.Parts[i].URI)
+	}
+	for i := range pl.PreloadHints {
+		rewriteURI(&err, p, base, &pl.PreloadHints[i].URI)
+	}
+	for i := range pl.RenditionReports {
+		rewriteURI(&err, p, base, &pl.RenditionReports[i].URI)
+	}
+	return
+}
+
+// rewriteURI rewrites the http uri to the proxy URI, and keeps the others,
+// such as "skd://" and "data:", which only records the first error.
+func rewriteURI(err *error, p *Proxy, base string, uri *string) {
+	if *err != nil || *uri == "" {
+		return
+	}
+
+	abs, _err := client.ResolveURL(base, *uri)
+	if _err != nil {
+		*err = fmt.Errorf("invalid uri '%s': %w", *uri, _err)
+		return
+	}
+
+	if strings.HasPrefix(abs, "http://") || strings.HasPrefix(abs, "https://") {
+		*uri = p.URL(abs)
+	}
+}
+
+func passThrough(w http.ResponseWriter, r *http.Request, resp *http.Response) error {
+	header := w.Header()
+	for _, key := range passHeaders {
+		if values := resp.Header.Values(key); len(values) > 0 {
+			header[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err := io.Copy(w, resp.Body)
+	if err != nil && !errors.Is(err, r.Context().Err()) {
+		return err
+	}
+	return nil
+}
+
+func isPlayList(origin, contentType string) bool {
+	if mediatype, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch strings.ToLower(mediatype) {
+		case server.MIMEPlayList, "application/x-mpegurl", "audio/mpegurl", "audio/x-mpegurl":
+			return true
+		}
+	}
+
+	if u, err := url.Parse(origin); err == nil {
+		return server.IsPlayList(u.Path)
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+const (
+	testMaster = `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="en",URI="audio.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=8000000,RESOLUTION=3840x2160,AUDIO="aac"
+2160p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1280x720,AUDIO="aac"
+720p.m3u8
+`
+
+	testMedia = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4,
+0.m4s
+#EXT-X-ENDLIST
+`
+)
+
+func newTestOrigin() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			_, _ = io.WriteString(w, testMaster)
+
+		case "/720p.m3u8":
+			if r.URL.Query().Get("_HLS_msn") != "1" {
+				w.WriteHeader(400)
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			_, _ = io.WriteString(w, testMedia)
+
+		case "/0.m4s":
+			http.ServeContent(w, r, "0.m4s", time.Time{}, strings.NewReader("0123456789"))
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func fetch(t *testing.T, h http.Handler, uri string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, uri, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProxy(t *testing.T) {
+	origin := newTestOrigin()
+	defer origin.Close()
+
+	p := New("/hls", NewHMACSigner([]byte("secret"), time.Minute))
+	p.Handler.DisableGzip = true
+	p.MasterHooks = []MasterHook{
+		MaxHeight(1080),
+		InjectSessionData(playlist.XSessionData{DataId: "com.example.title", Value: "Test"}),
+	}
+
+	rec := fetch(t, p, p.URL(origin.URL+"/master.m3u8"))
+	if rec.Code != 200 {
+		t.Fatalf("expect status code %d, but got %d: %s", 200, rec.Code, rec.Body.String())
+	}
+
+	var master playlist.MasterPlayList
+	if err := master.Parse(rec.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(master.Streams) != 1 || master.Streams[0].Stream.Resolution.Height != 720 {
+		t.Fatalf("unexpected variant streams: %+v", master.Streams)
+	} else if len(master.Streams[0].Medias) != 1 || len(master.Streams[0].SessionDatas) != 1 {
+		t.Errorf("unexpected master stream: %+v", master.Streams[0])
+	}
+
+	if uri := master.Streams[0].Medias[0].URI; uri != p.URL(origin.URL+"/audio.m3u8") {
+		t.Errorf("unexpected rendition uri '%s'", uri)
+	}
+
+	// Forward the LL-HLS query parameters to the origin.
+	rec = fetch(t, p, master.Streams[0].Stream.URI+"&_HLS_msn=1")
+	if rec.Code != 200 {
+		t.Fatalf("expect status code %d, but got %d: %s", 200, rec.Code, rec.Body.String())
+	}
+
+	var media playlist.MediaPlayList
+	if err := media.Parse(rec.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	seg := media.Segments[0]
+	if seg.Keys[0].URI != p.URL(origin.URL+"/key.bin") || seg.Map.URI != p.URL(origin.URL+"/init.mp4") {
+		t.Errorf("unexpected segment: %+v", seg)
+	}
+
+	if v := rec.Header().Get("Access-Control-Allow-Origin"); v != "*" {
+		t.Errorf("expect the playlist allow origin '%s', but got '%s'", "*", v)
+	}
+
+	rec = fetch(t, p, seg.URI, "Range", "bytes=2-5")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Errorf("unexpected segment response: %d %s", rec.Code, rec.Body.String())
+	} else if cr := rec.Header().Get("Content-Range"); cr != "bytes 2-5/10" {
+		t.Errorf("expect content range '%s', but got '%s'", "bytes 2-5/10", cr)
+	} else if v := rec.Header().Get("Access-Control-Allow-Origin"); v != "*" {
+		t.Errorf("expect the segment allow origin '%s', but got '%s'", "*", v)
+	}
+
+	// CORS preflight
+	req := httptest.NewRequest(http.MethodOptions, seg.URI, nil)
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expect status code %d, but got %d", http.StatusNoContent, rec.Code)
+	} else if v := rec.Header().Get("Access-Control-Allow-Headers"); v != "Range" {
+		t.Errorf("expect allow headers '%s', but got '%s'", "Range", v)
+	}
+
+	// Invalid token
+	query := url.Values{QueryURL: []string{origin.URL + "/0.m4s"}, QueryToken: []string{"0.invalid"}}
+	if rec := fetch(t, p, "/hls?"+query.Encode()); rec.Code != http.StatusForbidden {
+		t.Errorf("expect status code %d, but got %d", http.StatusForbidden, rec.Code)
+	}
+
+	// The 4xx status code of the origin is copied through.
+	if rec := fetch(t, p, p.URL(origin.URL+"/missing.ts")); rec.Code != http.StatusNotFound {
+		t.Errorf("expect status code %d, but got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// The transport error is responded with 502.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if rec := fetch(t, p, p.URL(closed.URL+"/0.ts")); rec.Code != http.StatusBadGateway {
+		t.Errorf("expect status code %d, but got %d", http.StatusBadGateway, rec.Code)
+	}
+}
+
+func TestProxyAllowURL(t *testing.T) {
+	origin := newTestOrigin()
+	defer origin.Close()
+
+	// Reject all the origins without the signer and AllowURL.
+	p := New("/hls", nil)
+	if rec := fetch(t, p, p.URL(origin.URL+"/0.m4s")); rec.Code != http.StatusForbidden {
+		t.Errorf("expect status code %d, but got %d", http.StatusForbidden, rec.Code)
+	}
+
+	p.AllowURL = AllowOrigins(origin.URL)
+	if rec := fetch(t, p, p.URL(origin.URL+"/0.m4s")); rec.Code != 200 || rec.Body.String() != "0123456789" {
+		t.Errorf("unexpected segment response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, uri := range []string{"http://169.254.169.254/latest/meta-data", "file:///etc/passwd"} {
+		if rec := fetch(t, p, p.URL(uri)); rec.Code != http.StatusForbidden {
+			t.Errorf("%s: expect status code %d, but got %d", uri, http.StatusForbidden, rec.Code)
+		}
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewHMACSigner([]byte("secret"), time.Minute)
+	s.Now = func() time.Time { return now }
+
+	token := s.Sign("http://example.com/a.ts")
+	if !s.Verify("http://example.com/a.ts", token) {
+		t.Errorf("expect the token is valid")
+	}
+	if s.Verify("http://example.com/b.ts", token) {
+		t.Errorf("expect the token is invalid for another url")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if s.Verify("http://example.com/a.ts", token) {
+		t.Errorf("expect the token is expired")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer is used to sign the origin url in the rewritten URI,
+// and verify it when the request comes back.
+type Signer interface {
+	Sign(url string) (token string)
+	Verify(url, token string) bool
+}
+
+// HMACSigner is a signer based on HMAC-SHA256,
+// the token of which is "EXPIRES.SIGNATURE".
+type HMACSigner struct {
+	Key []byte
+
+	// TTL is the lifetime of the token. 0 means no expiration.
+	TTL time.Duration
+
+	// Now returns the current time.
+	//
+	// Default: time.Now
+	Now func() time.Time
+}
+
+// NewHMACSigner returns a new HMAC signer.
+func NewHMACSigner(key []byte, ttl time.Duration) *HMACSigner {
+	return &HMACSigner{Key: key, TTL: ttl}
+}
+
+func (s *HMACSigner) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Sign implements the interface Signer.
+func (s *HMACSigner) Sign(url string) string {
+	var expires int64
+	if s.TTL > 0 {
+		expires = s.now().Add(s.TTL).Unix()
+	}
+
+	expstr := strconv.FormatInt(expires, 10)
+	return expstr + "." + s.sign(url, expstr)
+}
+
+// Verify implements the interface Signer.
+func (s *HMACSigner) Verify(url, token string) bool {
+	expstr, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(expstr, 10, 64)
+	if err != nil || (expires > 0 && s.now().Unix() > expires) {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(s.sign(url, expstr)))
+}
+
+func (s *HMACSigner) sign(url, expires string) string {
+	h := hmac.New(sha256.New, s.Key)
+	h.Write([]byte(expires))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(url))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampleaes
+
+import "crypto/aes"
+
+// audioClearLeaderSize is the size of the unencrypted_leader of an audio frame.
+const audioClearLeaderSize = 16
+
+// The bitrates of AC-3 in kbps, which is indexed by frmsizecod/2.
+var ac3Bitrates = [...]int{
+	32, 40, 48, 56, 64, 80, 96, 112, 128, 160,
+	192, 224, 256, 320, 384, 448, 512, 576, 640,
+}
+
+// adtsFrame returns the header size and the frame size of the ADTS frame.
+func adtsFrame(data []byte) (header, size int, ok bool) {
+	if len(data) < 7 || data[0] != 0xFF || data[1]&0xF6 != 0xF0 {
+		return
+	}
+
+	header = 7
+	if data[1]&0x01 == 0 { // protection_absent=0, with CRC
+		header = 9
+	}
+
+	size = int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5]>>5)
+	return header, size, size >= header
+}
+
+// ac3Frame returns the frame size of the AC-3 frame.
+func ac3Frame(data []byte) (header, size int, ok bool) {
+	if len(data) < 5 || data[0] != 0x0B || data[1] != 0x77 {
+		return
+	}
+
+	fscod, frmsizecod := data[4]>>6, int(data[4]&0x3F)
+	if frmsizecod >= len(ac3Bitrates)*2 {
+		return
+	}
+
+	bitrate := ac3Bitrates[frmsizecod/2]
+	switch fscod {
+	case 0: // 48kHz
+		size = bitrate * 4
+	case 1: // 44.1kHz
+		size = (bitrate*96000/44100 + frmsizecod%2) * 2
+	case 2: // 32kHz
+		size = bitrate * 6
+	default:
+		return
+	}
+
+	return 0, size, true
+}
+
+// eac3Frame returns the frame size of the Enhanced AC-3 frame.
+func eac3Frame(data []byte) (header, size int, ok bool) {
+	if len(data) < 4 || data[0] != 0x0B || data[1] != 0x77 {
+		return
+	}
+
+	frmsiz := int(data[2]&0x07)<<8 | int(data[3])
+	return 0, (frmsiz + 1) * 2, true
+}
+
+// decryptADTS decrypts the AAC frames in place, which are:
+//
+//	Encrypted_AAC_Frame () {
+//	    ADTS_Header                        // 7 or 9 bytes
+//	    unencrypted_leader                 // 16 bytes
+//	    while (bytes_remaining() >= 16) {
+//	        protected_block                // 16 bytes
+//	    }
+//	    unencrypted_trailer                // 0-15 bytes
+//	}
+func (d *decryptor) decryptADTS(data []byte) []byte {
+	return d.decryptAudio(data, adtsFrame)
+}
+
+// decryptAC3 decrypts the AC-3 frames in place, which are:
+//
+//	Encrypted_AC3_Frame () {
+//	    unencrypted_leader                 // 16 bytes
+//	    while (bytes_remaining() >= 16) {
+//	        protected_block                // 16 bytes
+//	    }
+//	    unencrypted_trailer                // 0-15 bytes
+//	}
+func (d *decryptor) decryptAC3(data []byte) []byte {
+	return d.decryptAudio(data, ac3Frame)
+}
+
+// decryptEAC3 decrypts the Enhanced AC-3 frames in place,
+// which is the same as AC-3.
+func (d *decryptor) decryptEAC3(data []byte) []byte {
+	return d.decryptAudio(data, eac3Frame)
+}
+
+// decryptAudio decrypts the audio frames in place, and stops
+// at the first invalid or incomplete frame.
+func (d *decryptor) decryptAudio(data []byte, frame func([]byte) (int, int, bool)) []byte {
+	for remaining := data; ; {
+		header, size, ok := frame(remaining)
+		if !ok || size > len(remaining) {
+			break
+		}
+
+		if body := remaining[header:size]; len(body) > audioClearLeaderSize {
+			body = body[audioClearLeaderSize:]
+			d.reset()
+			d.decrypt(body[:len(body)&^(aes.BlockSize-1)])
+		}
+		remaining = remaining[size:]
+	}
+	return data
+}
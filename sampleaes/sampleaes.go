@@ -0,0 +1,195 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sampleaes provides some functions to decrypt the MPEG-2 transport
+// stream encrypted by SAMPLE-AES, which is defined by Apple's
+// MPEG-2 Stream Encryption Format for HTTP Live Streaming.
+//
+// The encrypted elementary streams include H.264 video, and AAC (ADTS),
+// AC-3 and Enhanced AC-3 audio. For each NAL unit or audio frame,
+// the cipher block chaining is reset with the original IV.
+package sampleaes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+
+	"github.com/xgfone/go-hls/ts"
+)
+
+var (
+	errInvalidKey = errors.New("invalid SAMPLE-AES key")
+	errInvalidIV  = errors.New("invalid SAMPLE-AES iv")
+)
+
+// decryptor is used to decrypt the protected blocks in AES-128 CBC mode.
+type decryptor struct {
+	block cipher.Block
+	iv    []byte
+	prev  [aes.BlockSize]byte
+	buf   [aes.BlockSize]byte
+}
+
+func newDecryptor(key, iv []byte) (*decryptor, error) {
+	switch {
+	case len(key) != aes.BlockSize:
+		return nil, errInvalidKey
+
+	case len(iv) != aes.BlockSize:
+		return nil, errInvalidIV
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptor{block: block, iv: iv}, nil
+}
+
+// reset resets the cipher block chaining with the original IV.
+func (d *decryptor) reset() { copy(d.prev[:], d.iv) }
+
+// decrypt decrypts the blocks in place, which continues the cipher
+// block chaining from the last decrypted block.
+func (d *decryptor) decrypt(blocks []byte) {
+	for ; len(blocks) >= aes.BlockSize; blocks = blocks[aes.BlockSize:] {
+		block := blocks[:aes.BlockSize]
+		d.block.Decrypt(d.buf[:], block)
+		for i := range d.buf {
+			d.buf[i] ^= d.prev[i]
+		}
+
+		copy(d.prev[:], block)
+		copy(block, d.buf[:])
+	}
+}
+
+// DecryptTS decrypts the MPEG-2 transport stream segment encrypted
+// by SAMPLE-AES with the key and iv, and returns the clear one.
+//
+// The encrypted stream types in PMT are replaced with the clear ones,
+// and the private data indicator descriptors and the audio setup
+// information are removed.
+func DecryptTS(data, key, iv []byte) ([]byte, error) {
+	d, err := newDecryptor(key, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	rewriter := ts.Rewriter{PMT: rewritePMT, PES: func(stream ts.Stream, pes []byte) ([]byte, error) {
+		var decrypt func([]byte) []byte
+		switch stream.Type {
+		case ts.StreamTypeH264SampleAES:
+			decrypt = d.decryptH264
+		case ts.StreamTypeAACSampleAES:
+			decrypt = d.decryptADTS
+		case ts.StreamTypeAC3SampleAES:
+			decrypt = d.decryptAC3
+		case ts.StreamTypeEAC3SampleAES:
+			decrypt = d.decryptEAC3
+		default:
+			return pes, nil
+		}
+
+		h, err := ts.ParsePESHeader(pes)
+		if err != nil {
+			return nil, err
+		}
+
+		// pes must not be returned even if decrypted in place,
+		// because the original packets would be kept.
+		return append(pes[:h.Size:h.Size], decrypt(pes[h.Size:])...), nil
+	}}
+
+	return rewriter.Rewrite(data)
+}
+
+// DecryptH264 decrypts the H.264 elementary stream in the Annex B format,
+// such as the payload of a PES packet, and returns the clear one.
+func DecryptH264(data, key, iv []byte) ([]byte, error) {
+	d, err := newDecryptor(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptH264(data), nil
+}
+
+// DecryptADTS decrypts the AAC audio frames with the ADTS headers in place.
+func DecryptADTS(data, key, iv []byte) error {
+	d, err := newDecryptor(key, iv)
+	if err == nil {
+		d.decryptADTS(data)
+	}
+	return err
+}
+
+// DecryptAC3 decrypts the AC-3 audio frames in place.
+func DecryptAC3(data, key, iv []byte) error {
+	d, err := newDecryptor(key, iv)
+	if err == nil {
+		d.decryptAC3(data)
+	}
+	return err
+}
+
+// DecryptEAC3 decrypts the Enhanced AC-3 audio frames in place.
+func DecryptEAC3(data, key, iv []byte) error {
+	d, err := newDecryptor(key, iv)
+	if err == nil {
+		d.decryptEAC3(data)
+	}
+	return err
+}
+
+// The descriptors used by SAMPLE-AES in PMT.
+const (
+	descriptorRegistration         = 0x05
+	descriptorPrivateDataIndicator = 0x0F
+
+	// The format identifier of the registration descriptor
+	// carrying the audio setup information.
+	formatAudioSetup = "apad"
+)
+
+var clearStreamTypes = map[uint8]uint8{
+	ts.StreamTypeH264SampleAES: ts.StreamTypeH264,
+	ts.StreamTypeAACSampleAES:  ts.StreamTypeAAC,
+	ts.StreamTypeAC3SampleAES:  ts.StreamTypeAC3,
+	ts.StreamTypeEAC3SampleAES: ts.StreamTypeEAC3,
+}
+
+func rewritePMT(pmt *ts.PMT) error {
+	for i, s := range pmt.Streams {
+		_type, ok := clearStreamTypes[s.Type]
+		if !ok {
+			continue
+		}
+
+		descriptors := make([]ts.Descriptor, 0, len(s.Descriptors))
+		for _, d := range s.Descriptors {
+			switch {
+			case d.Tag == descriptorPrivateDataIndicator:
+			case d.Tag == descriptorRegistration && len(d.Data) >= 4 &&
+				string(d.Data[:4]) == formatAudioSetup:
+			default:
+				descriptors = append(descriptors, d)
+			}
+		}
+
+		pmt.Streams[i].Type = _type
+		pmt.Streams[i].Descriptors = descriptors
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampleaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/xgfone/go-hls/ts"
+)
+
+var (
+	testKey = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	testIV  = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+)
+
+/// ----------------------------------------------------------------------- ///
+// The encryptor to generate the test fixtures.
+
+type encryptor struct {
+	block cipher.Block
+	prev  []byte
+}
+
+func newEncryptor() *encryptor {
+	block, _ := aes.NewCipher(testKey)
+	return &encryptor{block: block}
+}
+
+func (e *encryptor) reset() { e.prev = append(e.prev[:0], testIV...) }
+
+func (e *encryptor) encrypt(blocks []byte) {
+	for ; len(blocks) >= 16; blocks = blocks[16:] {
+		for i := range 16 {
+			blocks[i] ^= e.prev[i]
+		}
+		e.block.Encrypt(blocks[:16], blocks[:16])
+		copy(e.prev, blocks[:16])
+	}
+}
+
+func (e *encryptor) encryptNAL(nal []byte) []byte {
+	nal = bytes.Clone(nal)
+	if len(nal) > nalMinEncryptedSize && (nal[0]&0x1F == nalTypeSlice || nal[0]&0x1F == nalTypeSliceIDR) {
+		e.reset()
+		for data := nal[nalClearLeaderSize:]; len(data) > 0; {
+			if len(data) > 16 {
+				e.encrypt(data[:16])
+				data = data[16:]
+			}
+			data = data[min(nalClearBlockSize, len(data)):]
+		}
+		nal = addEmulationPrevention(nal)
+	}
+	return nal
+}
+
+func (e *encryptor) encryptFrame(frame []byte, header int) []byte {
+	frame = bytes.Clone(frame)
+	if body := frame[header:]; len(body) > audioClearLeaderSize {
+		body = body[audioClearLeaderSize:]
+		e.reset()
+		e.encrypt(body[:len(body)&^15])
+	}
+	return frame
+}
+
+func addEmulationPrevention(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/32)
+	var zeros int
+	for _, b := range data {
+		if zeros >= 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func newData(size int, seed byte) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)*seed + seed
+		if i%50 == 10 { // Make some emulation prevention bytes.
+			data[i], data[i-1], data[i-2] = 1, 0, 0
+		}
+	}
+	return data
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// newFixtures returns the clear and encrypted elementary streams.
+func newFixtures() (clear, encrypted map[uint8][]byte) {
+	e := newEncryptor()
+	clear = make(map[uint8][]byte, 4)
+	encrypted = make(map[uint8][]byte, 4)
+
+	// H.264: SPS, IDR slice, short non-IDR slice, long non-IDR slice.
+	for i, nal := range [][]byte{
+		append([]byte{0x67}, newData(60, 3)...),
+		append([]byte{0x65}, addEmulationPrevention(newData(1000, 5))...),
+		append([]byte{0x41}, newData(40, 7)...),
+		append([]byte{0x41}, addEmulationPrevention(newData(333, 9))...),
+	} {
+		prefix := startCode
+		if i == 0 {
+			prefix = []byte{0, 0, 0, 1}
+		}
+
+		clear[ts.StreamTypeH264] = append(append(clear[ts.StreamTypeH264], prefix...), nal...)
+		encrypted[ts.StreamTypeH264] = append(append(encrypted[ts.StreamTypeH264], prefix...), e.encryptNAL(nal)...)
+	}
+
+	// AAC: the frames without and with CRC.
+	for _, size := range []int{7 + 200, 9 + 333} {
+		frame := newData(size, byte(size))
+		frame[0], frame[1] = 0xFF, 0xF1
+		if size%2 == 0 {
+			frame[1] = 0xF0
+		}
+		frame[3] = frame[3]&^0x03 | byte(size>>11)
+		frame[4] = byte(size >> 3)
+		frame[5] = frame[5]&0x1F | byte(size<<5)
+
+		header, _, _ := adtsFrame(frame)
+		clear[ts.StreamTypeAAC] = append(clear[ts.StreamTypeAAC], frame...)
+		encrypted[ts.StreamTypeAAC] = append(encrypted[ts.StreamTypeAAC], e.encryptFrame(frame, header)...)
+	}
+
+	// AC-3: 48kHz, 128kbps, 512 bytes.
+	frame := newData(512, 11)
+	frame[0], frame[1], frame[4] = 0x0B, 0x77, 16
+	clear[ts.StreamTypeAC3] = append(bytes.Clone(frame), frame...)
+	encrypted[ts.StreamTypeAC3] = append(e.encryptFrame(frame, 0), e.encryptFrame(frame, 0)...)
+
+	// E-AC-3: 600 bytes.
+	frame = newData(600, 13)
+	frame[0], frame[1], frame[2], frame[3] = 0x0B, 0x77, 0x01, 0x2B
+	clear[ts.StreamTypeEAC3] = frame
+	encrypted[ts.StreamTypeEAC3] = e.encryptFrame(frame, 0)
+
+	return
+}
+
+func TestDecryptElementaryStreams(t *testing.T) {
+	clear, encrypted := newFixtures()
+	for _type, data := range encrypted {
+		if bytes.Equal(data, clear[_type]) {
+			t.Fatalf("stream type 0x%02x: the fixture is not encrypted", _type)
+		}
+	}
+
+	if data, err := DecryptH264(encrypted[ts.StreamTypeH264], testKey, testIV); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(data, clear[ts.StreamTypeH264]) {
+		t.Errorf("the decrypted H.264 stream is not equal to the original")
+	}
+
+	for _type, decrypt := range map[uint8]func(data, key, iv []byte) error{
+		ts.StreamTypeAAC:  DecryptADTS,
+		ts.StreamTypeAC3:  DecryptAC3,
+		ts.StreamTypeEAC3: DecryptEAC3,
+	} {
+		data := bytes.Clone(encrypted[_type])
+		if err := decrypt(data, testKey, testIV); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(data, clear[_type]) {
+			t.Errorf("stream type 0x%02x: the decrypted data is not equal to the original", _type)
+		}
+	}
+
+	if _, err := DecryptH264(nil, testKey[:8], testIV); err == nil {
+		t.Errorf("expect an error, but got nil")
+	}
+}
+
+func TestDecryptTS(t *testing.T) {
+	const pmtpid = 0x1000
+	streams := []ts.Stream{
+		{Type: ts.StreamTypeH264SampleAES, PID: 0x100, Descriptors: []ts.Descriptor{
+			{Tag: descriptorPrivateDataIndicator, Data: []byte("zavc")},
+		}},
+		{Type: ts.StreamTypeAACSampleAES, PID: 0x101, Descriptors: []ts.Descriptor{
+			{Tag: descriptorPrivateDataIndicator, Data: []byte("aacd")},
+			{Tag: descriptorRegistration, Data: []byte("apad\x00\x00\x00\x00")},
+			{Tag: 0x0A, Data: []byte("eng\x00")}, // ISO_639_language_descriptor
+		}},
+		{Type: ts.StreamTypeAC3SampleAES, PID: 0x102},
+		{Type: ts.StreamTypeEAC3SampleAES, PID: 0x103},
+		{Type: ts.StreamTypeMetadata, PID: 0x104},
+	}
+
+	clear, encrypted := newFixtures()
+	clear[ts.StreamTypeMetadata] = newData(100, 17)
+	encrypted[ts.StreamTypeMetadata] = clear[ts.StreamTypeMetadata]
+
+	var buf bytes.Buffer
+	w := ts.NewWriter(&buf)
+	_ = w.WritePAT(ts.PAT{Programs: []ts.Program{{Number: 1, PID: pmtpid}}})
+	_ = w.WritePMT(pmtpid, ts.PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: streams})
+	for i := range 2 {
+		for _, s := range streams {
+			_type := clearStreamTypes[s.Type]
+			if _type == 0 {
+				_type = s.Type
+			}
+			pes := ts.AppendPES(nil, 0xE0, int64(i)*3000, -1, encrypted[_type])
+			_ = w.WritePES(s.PID, pes, -1, false)
+		}
+	}
+
+	data, err := DecryptTS(buf.Bytes(), testKey, testIV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets, err := ts.Packets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section, err := ts.Section(packets[1].Payload())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pmt, err := ts.ParsePMT(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range pmt.Streams {
+		if _type := clearStreamTypes[streams[i].Type]; _type != 0 && s.Type != _type {
+			t.Errorf("expect stream type 0x%02x, but got 0x%02x", _type, s.Type)
+		}
+
+		if s.Type == ts.StreamTypeAAC {
+			if len(s.Descriptors) != 1 || s.Descriptors[0].Tag != 0x0A {
+				t.Errorf("unexpected descriptors: %+v", s.Descriptors)
+			}
+		} else if len(s.Descriptors) != 0 {
+			t.Errorf("stream type 0x%02x: unexpected descriptors: %+v", s.Type, s.Descriptors)
+		}
+
+		var pess [][]byte
+		for _, p := range packets {
+			if p.PID() == s.PID && p.HasPayload() {
+				if p.PayloadUnitStart() {
+					pess = append(pess, nil)
+				}
+				pess[len(pess)-1] = append(pess[len(pess)-1], p.Payload()...)
+			}
+		}
+
+		if len(pess) != 2 {
+			t.Errorf("stream type 0x%02x: expect %d PES packets, but got %d", s.Type, 2, len(pess))
+			continue
+		}
+
+		for _, pes := range pess {
+			h, err := ts.ParsePESHeader(pes)
+			if err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(pes[h.Size:], clear[s.Type]) {
+				t.Errorf("stream type 0x%02x: the decrypted data is not equal to the original", s.Type)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampleaes
+
+import "bytes"
+
+const (
+	// The NAL unit types of the coded slices, which may be encrypted.
+	nalTypeSlice    = 1
+	nalTypeSliceIDR = 5
+
+	nalMinEncryptedSize = 48
+	nalClearLeaderSize  = 32  // nal_unit_type_byte + unencrypted_leader
+	nalClearBlockSize   = 144 // The size of the unencrypted_block.
+)
+
+var startCode = []byte{0, 0, 1}
+
+// decryptH264 decrypts the NAL units in the Annex B format.
+//
+// Only the coded slices longer than 48 bytes are encrypted, which are:
+//
+//	Encrypted_nal_unit () {
+//	    nal_unit_type_byte                // 1 byte
+//	    unencrypted_leader                // 31 bytes
+//	    while (bytes_remaining() > 0) {
+//	        if (bytes_remaining() > 16) {
+//	            encrypted_block           // 16 bytes
+//	        }
+//	        unencrypted_block             // MIN(144, bytes_remaining()) bytes
+//	    }
+//	}
+//
+// And the emulation prevention bytes are inserted after encryption,
+// so they are removed before decryption.
+func (d *decryptor) decryptH264(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		start := bytes.Index(data, startCode)
+		if start < 0 {
+			break
+		}
+
+		nal := data[start+3:]
+		end := bytes.Index(nal, startCode)
+		if end < 0 {
+			end = len(nal)
+		} else if end > 0 && nal[end-1] == 0 { // 4-byte start code
+			end--
+		}
+
+		out = append(out, data[:start+3]...)
+		nal, data = nal[:end], nal[end:]
+		if len(nal) <= nalMinEncryptedSize {
+			out = append(out, nal...)
+			continue
+		}
+
+		switch nal[0] & 0x1F {
+		case nalTypeSlice, nalTypeSliceIDR:
+			out = append(out, d.decryptNAL(nal)...)
+		default:
+			out = append(out, nal...)
+		}
+	}
+
+	return append(out, data...)
+}
+
+func (d *decryptor) decryptNAL(nal []byte) []byte {
+	nal = removeEmulationPrevention(nal)
+
+	d.reset()
+	for data := nal[nalClearLeaderSize:]; len(data) > 0; {
+		if len(data) > 16 {
+			d.decrypt(data[:16])
+			data = data[16:]
+		}
+		data = data[min(nalClearBlockSize, len(data)):]
+	}
+
+	return nal
+}
+
+// removeEmulationPrevention returns a copy of the NAL unit
+// without the emulation prevention bytes, that's, 0x03 in 0x000003.
+func removeEmulationPrevention(nal []byte) []byte {
+	out := make([]byte, 0, len(nal))
+	var zeros int
+	for _, b := range nal {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// Define the query parameters of the DVR view of a media playlist.
+const (
+	// QueryDVRStart is the absolute start time to time-shift to,
+	// which is the RFC 3339 time or the unix timestamp in seconds,
+	// and is matched with EXT-X-PROGRAM-DATE-TIME.
+	QueryDVRStart = "start"
+
+	// QueryDVROffset is the time offset in seconds of EXT-X-START
+	// to start over the whole media playlist.
+	QueryDVROffset = "offset"
+)
+
+// DVRHandler is the same as Handler, but serves the growing media playlists,
+// such as the EVENT playlist of a recorder, as a view for each request.
+//
+// By default, a media playlist is served as the live sliding window
+// of the last Window duration. If the query "start" is given, it is served
+// from the media segment at the absolute time to the live edge. If the query
+// "offset" is given, it is served as the start-over view with EXT-X-START.
+//
+// The master playlists and the other files are served as they are.
+type DVRHandler struct {
+	*Handler
+
+	// Window is the duration of the live sliding window.
+	// If zero, contain all the media segments.
+	Window time.Duration
+}
+
+// NewDVRHandler returns a new DVR handler to serve the HLS content in fsys
+// with the live sliding window.
+func NewDVRHandler(fsys fs.FS, window time.Duration) *DVRHandler {
+	return &DVRHandler{Handler: NewHandler(fsys), Window: window}
+}
+
+// ServeHTTP implements the interface http.Handler.
+func (h *DVRHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, h.view)
+}
+
+func (h *DVRHandler) view(r *http.Request, pl playlist.PlayList) (playlist.PlayList, error) {
+	media, ok := pl.(playlist.MediaPlayList)
+	if !ok {
+		return pl, nil
+	}
+
+	query := r.URL.Query()
+	if value := query.Get(QueryDVROffset); value != "" {
+		offset, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errInvalidQuery(QueryDVROffset, value)
+		}
+		return media.StartOver(offset, false), nil
+	}
+
+	if value := query.Get(QueryDVRStart); value != "" {
+		start, err := parseDVRTime(value)
+		if err != nil {
+			return nil, errInvalidQuery(QueryDVRStart, value)
+		}
+
+		view, ok := media.LiveWindowAt(start)
+		if !ok {
+			return nil, fmt.Errorf("no media segment at the start time '%s'", value)
+		}
+		return view, nil
+	}
+
+	return media.LiveWindow(h.Window.Seconds()), nil
+}
+
+func parseDVRTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
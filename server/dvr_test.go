@@ -0,0 +1,88 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+const testEvent = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:4
+#EXT-X-PLAYLIST-TYPE:EVENT
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PROGRAM-DATE-TIME:2025-01-01T00:00:00Z
+#EXTINF:4,
+0.m4s
+#EXTINF:4,
+1.m4s
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+2.m4s
+#EXTINF:4,
+3.m4s
+`
+
+func TestDVRHandler(t *testing.T) {
+	h := NewDVRHandler(fstest.MapFS{"event.m3u8": {Data: []byte(testEvent)}}, 8*time.Second)
+	h.DisableGzip = true
+
+	parse := func(rec *httptest.ResponseRecorder) (pl playlist.MediaPlayList) {
+		t.Helper()
+		resp := rec.Result()
+		if resp.StatusCode != 200 {
+			t.Fatalf("expect status code %d, but got %d", 200, resp.StatusCode)
+		}
+		if err := pl.Parse(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	pl := parse(serve(h, http.MethodGet, "/event.m3u8"))
+	if len(pl.Segments) != 2 || pl.MediaSequence != 2 || pl.DiscontinuitySequence != 0 ||
+		pl.PlayListType != "" || pl.Segments[0].Map.URI != "init.mp4" {
+		t.Errorf("unexpected sliding window: %+v", pl)
+	}
+
+	pl = parse(serve(h, http.MethodGet, "/event.m3u8?start=2025-01-01T00:00:05Z"))
+	if len(pl.Segments) != 3 || pl.MediaSequence != 1 || pl.Segments[0].URI != "1.m4s" {
+		t.Errorf("unexpected time-shift window: %+v", pl)
+	}
+	if expect := time.Date(2025, 1, 1, 0, 0, 4, 0, time.UTC); !pl.Segments[0].ProgramDateTime.Equal(expect) {
+		t.Errorf("expect program date time '%s', but got '%s'", expect, pl.Segments[0].ProgramDateTime)
+	}
+
+	pl = parse(serve(h, http.MethodGet, "/event.m3u8?offset=-8"))
+	if len(pl.Segments) != 4 || pl.PlayListType != playlist.MediaPlayListTypeEvent || pl.Start.TimeOffset != -8 {
+		t.Errorf("unexpected start-over view: %+v", pl)
+	}
+
+	for _, query := range []string{"start=abc", "start=2030-01-01T00:00:00Z", "offset=abc"} {
+		rec := serve(h, http.MethodGet, "/event.m3u8?"+query)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expect status code %d, but got %d", query, http.StatusBadRequest, rec.Code)
+		} else if !strings.Contains(rec.Body.String(), "start") && !strings.Contains(rec.Body.String(), "offset") {
+			t.Errorf("%s: unexpected error '%s'", query, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+var errMissingMsn = errors.New("_HLS_part requires _HLS_msn")
+
+func errInvalidQuery(key, value string) error {
+	return fmt.Errorf("invalid %s '%s'", key, value)
+}
+
+// LowLatencyHandler is a http handler as the origin of a live Low-Latency
+// HLS media playlist, which holds the media playlist and the data
+// of the media segments and the parts in memory.
+//
+// It supports the blocking playlist reload by "_HLS_msn" and "_HLS_part",
+// the delta update by "_HLS_skip", and holds the request of the preload
+// hint until the data of the part is added.
+type LowLatencyHandler struct {
+	// Handler is used to render the media playlist, and serve the files
+	// not in memory if its FS is not nil.
+	*Handler
+
+	// Name is the name of the media playlist, such as "live.m3u8".
+	Name string
+
+	// WindowSize is the maximum number of the media segments
+	// in the media playlist. 0 means no limit.
+	WindowSize int
+
+	// Timeout is the maximum duration to hold the blocking request.
+	//
+	// Default: 3 times the target duration
+	Timeout time.Duration
+
+	lock   sync.RWMutex
+	pl     playlist.MediaPlayList
+	data   map[string][]byte
+	notify chan struct{}
+}
+
+// NewLowLatencyHandler returns a new low-latency handler with the name
+// and the initial live media playlist, which should contain TargetDuration,
+// PartInf and MediaSequence.
+//
+// CAN-BLOCK-RELOAD is enabled, and PART-HOLD-BACK defaults to 3 times
+// the part target duration.
+func NewLowLatencyHandler(name string, pl playlist.MediaPlayList) *LowLatencyHandler {
+	pl.ServerControl.CanBlockReload = true
+	if pl.ServerControl.PartHoldBack == 0 {
+		pl.ServerControl.PartHoldBack = pl.PartInf.PartTarget * 3
+	}
+
+	return &LowLatencyHandler{
+		Handler: NewHandler(nil),
+		Name:    name,
+		pl:      pl,
+		data:    make(map[string][]byte, 32),
+		notify:  make(chan struct{}),
+	}
+}
+
+// PlayList returns the snapshot of the current media playlist.
+func (h *LowLatencyHandler) PlayList() playlist.MediaPlayList {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.snapshot()
+}
+
+func (h *LowLatencyHandler) snapshot() playlist.MediaPlayList {
+	pl := h.pl
+	pl.Segments = append([]playlist.MediaSegment(nil), pl.Segments...)
+	pl.Parts = append([]playlist.XPart(nil), pl.Parts...)
+	pl.PreloadHints = append([]playlist.XPreloadHint(nil), pl.PreloadHints...)
+	pl.Version = 0
+	pl.Version = pl.MinVersion()
+	return pl
+}
+
+// update updates the media playlist and wakes up the blocking requests.
+func (h *LowLatencyHandler) update(f func()) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	f()
+	close(h.notify)
+	h.notify = make(chan struct{})
+}
+
+// AddPart adds the part of the next incomplete media segment with its data,
+// which is stored by the part URI.
+//
+// If the part has the byte range, the data is appended to the existing data
+// of the URI, that's, the parts must be added in order.
+func (h *LowLatencyHandler) AddPart(part playlist.XPart, data []byte) {
+	h.update(func() {
+		if part.ByteRange.IsZero() {
+			h.data[part.URI] = data
+		} else {
+			h.data[part.URI] = append(h.data[part.URI], data...)
+		}
+		h.pl.Parts = append(h.pl.Parts, part)
+	})
+}
+
+// AddSegment completes the next media segment with the parts added before,
+// and stores its data by the segment URI. If data is nil, the data
+// accumulated by the byte-range parts is used.
+//
+// The media segments out of the window are removed with their data,
+// and the parts of the media segments older than 3 target durations
+// are removed from the media playlist.
+func (h *LowLatencyHandler) AddSegment(seg playlist.MediaSegment, data []byte) {
+	h.update(func() {
+		seg.Parts = h.pl.Parts
+		seg.MediaSequence = h.pl.MediaSequence + uint64(len(h.pl.Segments))
+		if data != nil {
+			h.data[seg.URI] = data
+		}
+
+		h.pl.Parts = nil
+		h.pl.Segments = append(h.pl.Segments, seg)
+		h.pl.TargetDuration = max(h.pl.TargetDuration, uint64(seg.Duration+0.5))
+
+		for h.WindowSize > 0 && len(h.pl.Segments) > h.WindowSize {
+			h.removeData(h.pl.Segments[0])
+			if h.pl.Segments[0].Discontinuity {
+				h.pl.DiscontinuitySequence++
+			}
+			h.pl.Segments = h.pl.Segments[1:]
+			h.pl.MediaSequence++
+		}
+
+		var duration float64
+		limit := float64(h.pl.TargetDuration * 3)
+		for i := len(h.pl.Segments) - 1; i >= 0; i-- {
+			if duration += h.pl.Segments[i].Duration; duration > limit && len(h.pl.Segments[i].Parts) > 0 {
+				h.removeParts(h.pl.Segments[i])
+				h.pl.Segments[i].Parts = nil
+			}
+		}
+	})
+}
+
+func (h *LowLatencyHandler) removeData(seg playlist.MediaSegment) {
+	h.removeParts(seg)
+	delete(h.data, seg.URI)
+}
+
+func (h *LowLatencyHandler) removeParts(seg playlist.MediaSegment) {
+	for _, part := range seg.Parts {
+		if part.URI != seg.URI {
+			delete(h.data, part.URI)
+		}
+	}
+}
+
+// SetPreloadHints replaces the preload hints of the media playlist.
+func (h *LowLatencyHandler) SetPreloadHints(hints ...playlist.XPreloadHint) {
+	h.update(func() { h.pl.PreloadHints = hints })
+}
+
+// End appends EXT-X-ENDLIST into the media playlist, and removes
+// the preload hints.
+func (h *LowLatencyHandler) End() {
+	h.update(func() {
+		h.pl.EndList = true
+		h.pl.PreloadHints = nil
+	})
+}
+
+// ServeHTTP implements the interface http.Handler.
+func (h *LowLatencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.HandleMethod(w, r) {
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == h.Name {
+		h.servePlayList(w, r)
+		return
+	}
+
+	data, ok := h.waitData(w, r, name)
+	switch {
+	case ok:
+		w.Header().Set("Content-Type", ContentType(name))
+		setMaxAge(w.Header(), defaultDuration(h.SegmentMaxAge, DefaultVODMaxAge))
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+
+	case data != nil: // The response has been written.
+
+	case h.FS != nil:
+		h.Handler.ServeHTTP(w, r)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// waitData returns the data of the file. If it is the URI of a preload hint,
+// wait until its data is added.
+//
+// If failing to wait, it writes the error response and returns a non-nil
+// empty data and false.
+func (h *LowLatencyHandler) waitData(w http.ResponseWriter, r *http.Request, name string) ([]byte, bool) {
+	var timer *time.Timer
+	for {
+		h.lock.RLock()
+		data, ok := h.data[name]
+		hinted := h.isPreloadHint(name)
+		notify, timeout := h.notify, h.timeout()
+		h.lock.RUnlock()
+
+		if ok || !hinted {
+			return data, ok
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(timeout)
+			defer timer.Stop()
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			http.Error(w, "preload hint is not available", http.StatusServiceUnavailable)
+			return []byte{}, false
+		case <-r.Context().Done():
+			return []byte{}, false
+		}
+	}
+}
+
+func (h *LowLatencyHandler) isPreloadHint(name string) bool {
+	for _, hint := range h.pl.PreloadHints {
+		if strings.TrimPrefix(path.Clean("/"+hint.URI), "/") == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *LowLatencyHandler) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return time.Duration(h.pl.TargetDuration) * time.Second * 3
+}
+
+func (h *LowLatencyHandler) servePlayList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	msn, part, hasMsn, hasPart, err := parseBlockingQuery(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var timer *time.Timer
+	for {
+		h.lock.RLock()
+		pl, notify, timeout := h.pl, h.notify, h.timeout()
+		next := pl.MediaSequence + uint64(len(pl.Segments)) // The next incomplete media segment.
+		available := !hasMsn || pl.EndList || msn < next ||
+			(msn == next && hasPart && part < uint64(len(pl.Parts)))
+		if available {
+			pl = h.snapshot()
+		}
+		h.lock.RUnlock()
+
+		if available {
+			if skip := query.Get(playlist.QueryHLSSkip); skip == "YES" || skip == "v2" {
+				pl = deltaUpdate(pl)
+			}
+			h.ServePlayList(w, r, pl, time.Time{})
+			return
+		}
+
+		// The server should reject the request more than 2 media segments
+		// in the future, that's, after the last one plus 2, or next plus 1.
+		if msn > next+1 {
+			http.Error(w, "the requested media sequence number is too far in the future", http.StatusBadRequest)
+			return
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(timeout)
+			defer timer.Stop()
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			http.Error(w, "the requested media segment or part is not available", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func parseBlockingQuery(query url.Values) (msn, part uint64, hasMsn, hasPart bool, err error) {
+	if v := query.Get(playlist.QueryHLSMsn); v != "" {
+		if msn, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return 0, 0, false, false, errInvalidQuery(playlist.QueryHLSMsn, v)
+		}
+		hasMsn = true
+	}
+
+	if v := query.Get(playlist.QueryHLSPart); v != "" {
+		if !hasMsn {
+			return 0, 0, false, false, errMissingMsn
+		} else if part, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return 0, 0, false, false, errInvalidQuery(playlist.QueryHLSPart, v)
+		}
+		hasPart = true
+	}
+
+	return
+}
+
+// deltaUpdate returns the delta update of the media playlist, which skips
+// the media segments older than CAN-SKIP-UNTIL from the end.
+func deltaUpdate(pl playlist.MediaPlayList) playlist.MediaPlayList {
+	if pl.ServerControl.CanSkipUntil <= 0 {
+		return pl
+	}
+
+	remaining := pl.TotalDuration()
+	var skipped int
+	for _, seg := range pl.Segments {
+		if remaining -= seg.Duration; remaining < pl.ServerControl.CanSkipUntil {
+			break
+		}
+		skipped++
+	}
+
+	if skipped > 0 {
+		pl.Skip = playlist.XSkip{SkippedSegments: uint64(skipped)}
+		pl.Segments = pl.Segments[skipped:]
+		pl.Version = 0
+		pl.Version = pl.MinVersion()
+	}
+	return pl
+}
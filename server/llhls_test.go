@@ -0,0 +1,165 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+func addTestSegment(h *LowLatencyHandler, msn int) {
+	for i := range 4 {
+		uri := fmt.Sprintf("%d.%d.m4s", msn, i)
+		h.AddPart(playlist.XPart{URI: uri, Duration: 1, Independent: i == 0}, []byte(uri))
+	}
+
+	uri := fmt.Sprintf("%d.m4s", msn)
+	h.AddSegment(playlist.MediaSegment{URI: uri, Duration: 4}, []byte(uri))
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestLowLatencyHandler(t *testing.T) {
+	h := NewLowLatencyHandler("live.m3u8", playlist.MediaPlayList{
+		TargetDuration: 4,
+		MediaSequence:  1,
+		PartInf:        playlist.XPartInf{PartTarget: 1},
+		ServerControl:  playlist.XServerControl{CanSkipUntil: 8},
+	})
+	h.DisableGzip = true
+	for msn := 1; msn <= 4; msn++ {
+		addTestSegment(h, msn)
+	}
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	if code, body := get(t, server.URL+"/live.m3u8"); code != 200 {
+		t.Errorf("expect status code %d, but got %d", 200, code)
+	} else if !strings.Contains(body, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES") ||
+		!strings.Contains(body, "4.m4s") || strings.Contains(body, "1.0.m4s") {
+		t.Errorf("unexpected playlist:\n%s", body)
+	}
+
+	// The parts of the segments older than 3 target durations are removed.
+	if code, _ := get(t, server.URL+"/1.0.m4s"); code != 404 {
+		t.Errorf("expect status code %d, but got %d", 404, code)
+	}
+	if code, body := get(t, server.URL+"/4.3.m4s"); code != 200 || body != "4.3.m4s" {
+		t.Errorf("unexpected part: %d %s", code, body)
+	}
+
+	// Blocking playlist reload
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		h.AddPart(playlist.XPart{URI: "5.0.m4s", Duration: 1, Independent: true}, []byte("5.0.m4s"))
+	}()
+	if code, body := get(t, server.URL+"/live.m3u8?_HLS_msn=5&_HLS_part=0"); code != 200 {
+		t.Errorf("expect status code %d, but got %d", 200, code)
+	} else if !strings.Contains(body, "5.0.m4s") {
+		t.Errorf("missing the blocking part:\n%s", body)
+	}
+
+	for _, query := range []string{"_HLS_part=0", "_HLS_msn=100", "_HLS_msn=x"} {
+		if code, _ := get(t, server.URL+"/live.m3u8?"+query); code != 400 {
+			t.Errorf("%s: expect status code %d, but got %d", query, 400, code)
+		}
+	}
+
+	h.Timeout = 50 * time.Millisecond
+	if code, _ := get(t, server.URL+"/live.m3u8?_HLS_msn=6"); code != 503 {
+		t.Errorf("expect status code %d, but got %d", 503, code)
+	}
+
+	// Delta update
+	if code, body := get(t, server.URL+"/live.m3u8?_HLS_skip=YES"); code != 200 {
+		t.Errorf("expect status code %d, but got %d", 200, code)
+	} else if !strings.Contains(body, "#EXT-X-SKIP:SKIPPED-SEGMENTS=2") || strings.Contains(body, "\n2.m4s") {
+		t.Errorf("unexpected delta update:\n%s", body)
+	}
+
+	// Preload hint
+	h.Timeout = 0
+	h.SetPreloadHints(playlist.XPreloadHint{Type: playlist.XPreloadHintTypePart, URI: "5.1.m4s"})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		h.AddPart(playlist.XPart{URI: "5.1.m4s", Duration: 1}, []byte("5.1.m4s"))
+	}()
+	if code, body := get(t, server.URL+"/5.1.m4s"); code != 200 || body != "5.1.m4s" {
+		t.Errorf("unexpected preload hint response: %d %s", code, body)
+	}
+
+	h.End()
+	if code, body := get(t, server.URL+"/live.m3u8?_HLS_msn=6"); code != 200 || !strings.Contains(body, "#EXT-X-ENDLIST") {
+		t.Errorf("unexpected ended playlist: %d\n%s", code, body)
+	}
+}
+
+func TestLowLatencyHandlerFutureMsn(t *testing.T) {
+	h := NewLowLatencyHandler("live.m3u8", playlist.MediaPlayList{
+		TargetDuration: 4,
+		MediaSequence:  1,
+		PartInf:        playlist.XPartInf{PartTarget: 1},
+	})
+	h.DisableGzip = true
+	for msn := 1; msn <= 4; msn++ {
+		addTestSegment(h, msn)
+	}
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	// The last media segment is 4, so 6 is accepted and blocks.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		addTestSegment(h, 5)
+		addTestSegment(h, 6)
+	}()
+	if code, body := get(t, server.URL+"/live.m3u8?_HLS_msn=6"); code != 200 {
+		t.Errorf("expect status code %d, but got %d", 200, code)
+	} else if !strings.Contains(body, "\n6.m4s") {
+		t.Errorf("missing the blocking segment:\n%s", body)
+	}
+
+	// The last media segment is 6 now.
+	if code, _ := get(t, server.URL+"/live.m3u8?_HLS_msn=9"); code != 400 {
+		t.Errorf("expect status code %d, but got %d", 400, code)
+	}
+
+	h.Timeout = 50 * time.Millisecond
+	if code, _ := get(t, server.URL+"/live.m3u8?_HLS_msn=8"); code != 503 {
+		t.Errorf("expect status code %d, but got %d", 503, code)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// DefaultVODMaxAge is the default max-age of the VOD playlists
+// and the media segments.
+const DefaultVODMaxAge = 24 * time.Hour
+
+// Some MIME types of the HLS content.
+const (
+	MIMEPlayList    = "application/vnd.apple.mpegurl"
+	MIMEMPEG2TS     = "video/mp2t"
+	MIMEVideoMP4    = "video/mp4"
+	MIMEAudioMP4    = "audio/mp4"
+	MIMEAAC         = "audio/aac"
+	MIMEMP3         = "audio/mpeg"
+	MIMEAC3         = "audio/ac3"
+	MIMEEAC3        = "audio/eac3"
+	MIMEWebVTT      = "text/vtt"
+	MIMEOctetStream = "application/octet-stream"
+)
+
+var contentTypes = map[string]string{
+	".m3u8":   MIMEPlayList,
+	".m3u":    MIMEPlayList,
+	".ts":     MIMEMPEG2TS,
+	".mp4":    MIMEVideoMP4,
+	".m4s":    MIMEVideoMP4,
+	".m4v":    MIMEVideoMP4,
+	".cmfv":   MIMEVideoMP4,
+	".m4a":    MIMEAudioMP4,
+	".cmfa":   MIMEAudioMP4,
+	".aac":    MIMEAAC,
+	".mp3":    MIMEMP3,
+	".ac3":    MIMEAC3,
+	".ec3":    MIMEEAC3,
+	".vtt":    MIMEWebVTT,
+	".webvtt": MIMEWebVTT,
+}
+
+// ContentType returns the MIME type of the HLS content by the extension
+// of the name, which is "application/octet-stream" for the unknown one.
+func ContentType(name string) string {
+	if ct, ok := contentTypes[strings.ToLower(path.Ext(name))]; ok {
+		return ct
+	}
+	return MIMEOctetStream
+}
+
+// IsPlayList reports whether the name is a playlist by its extension.
+func IsPlayList(name string) bool {
+	return ContentType(name) == MIMEPlayList
+}
+
+// Handler is a http handler to serve the HLS content in a file system,
+// such as os.DirFS for a directory.
+//
+// The playlists are parsed and rendered by the Output method of
+// playlist.MasterPlayList or playlist.MediaPlayList, and the other files,
+// such as the media segments, support the HTTP Range request
+// for EXT-X-BYTERANGE.
+type Handler struct {
+	FS fs.FS
+
+	// AllowOrigin is the value of the header "Access-Control-Allow-Origin".
+	// If empty, disable CORS.
+	AllowOrigin string
+
+	// LiveMaxAge is the max-age of the live media playlists without
+	// EXT-X-ENDLIST or EXT-X-PLAYLIST-TYPE:VOD.
+	//
+	// Default: a half of the target duration
+	LiveMaxAge time.Duration
+
+	// VODMaxAge is the max-age of the VOD media playlists
+	// and the master playlists.
+	//
+	// Default: DefaultVODMaxAge
+	VODMaxAge time.Duration
+
+	// SegmentMaxAge is the max-age of the files except the playlists,
+	// such as the media segments and the keys.
+	//
+	// Default: DefaultVODMaxAge
+	SegmentMaxAge time.Duration
+
+	// If true, do not compress the playlists by gzip.
+	DisableGzip bool
+}
+
+// NewHandler returns a new handler to serve the HLS content in fsys,
+// which allows CORS from any origin.
+func NewHandler(fsys fs.FS) *Handler {
+	return &Handler{FS: fsys, AllowOrigin: "*"}
+}
+
+// ServeHTTP implements the interface http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, nil)
+}
+
+// serve serves the file in the file system, and view is used to replace
+// the parsed playlist for the request if not nil.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request,
+	view func(*http.Request, playlist.PlayList) (playlist.PlayList, error)) {
+	if !h.HandleMethod(w, r) {
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if h.FS == nil || name == "" || !fs.ValidPath(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := h.FS.Open(name)
+	if err != nil {
+		h.serveError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		h.serveError(w, r, err)
+		return
+	} else if fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if IsPlayList(name) {
+		pl, err := playlist.Parse(f)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid playlist '%s': %s", name, err), http.StatusInternalServerError)
+			return
+		}
+
+		if view != nil {
+			if pl, err = view(r, pl); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		h.ServePlayList(w, r, pl, fi.ModTime())
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			h.serveError(w, r, err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	w.Header().Set("Content-Type", ContentType(name))
+	setMaxAge(w.Header(), defaultDuration(h.SegmentMaxAge, DefaultVODMaxAge))
+	http.ServeContent(w, r, name, fi.ModTime(), content)
+}
+
+// HandleMethod sets the CORS headers by AllowOrigin, and handles the CORS
+// preflight request and the unsupported methods.
+//
+// Return true if the request should be served, such as GET and HEAD.
+// It may be used by the other handlers serving the HLS content.
+func (h *Handler) HandleMethod(w http.ResponseWriter, r *http.Request) bool {
+	if h.AllowOrigin != "" {
+		header := w.Header()
+		header.Set("Access-Control-Allow-Origin", h.AllowOrigin)
+		header.Set("Access-Control-Expose-Headers", "Content-Length, Content-Range")
+		if r.Method == http.MethodOptions {
+			header.Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			header.Set("Access-Control-Allow-Headers", "Range")
+			header.Set("Access-Control-Max-Age", "86400")
+			w.WriteHeader(http.StatusNoContent)
+			return false
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	default:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+}
+
+func (h *Handler) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ServePlayList renders the playlist as the response, which sets
+// the cache headers by the playlist and compresses it by gzip
+// if the client accepts it.
+//
+// modtime is used to handle the conditional request, which may be ZERO.
+func (h *Handler) ServePlayList(w http.ResponseWriter, r *http.Request, pl playlist.PlayList, modtime time.Time) {
+	var buf bytes.Buffer
+	var err error
+	switch v := pl.(type) {
+	case playlist.MediaPlayList:
+		err = v.Output(&buf)
+	case *playlist.MediaPlayList:
+		err = v.Output(&buf)
+	case playlist.MasterPlayList:
+		err = v.Output(&buf)
+	case *playlist.MasterPlayList:
+		err = v.Output(&buf)
+	default:
+		err = fmt.Errorf("unsupported playlist type %T", pl)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", MIMEPlayList)
+	setMaxAge(header, h.playListMaxAge(pl))
+
+	body := buf.Bytes()
+	if !h.DisableGzip {
+		header.Add("Vary", "Accept-Encoding")
+		if acceptGzip(r) {
+			var gzbuf bytes.Buffer
+			gw := gzip.NewWriter(&gzbuf)
+			_, _ = gw.Write(body)
+			_ = gw.Close()
+
+			// ServeContent handles the conditional and range requests
+			// against the gzipped representation.
+			header.Set("Content-Encoding", "gzip")
+			body = gzbuf.Bytes()
+		}
+	}
+
+	http.ServeContent(w, r, "", modtime, bytes.NewReader(body))
+}
+
+func (h *Handler) playListMaxAge(pl playlist.PlayList) time.Duration {
+	var media playlist.MediaPlayList
+	switch v := pl.(type) {
+	case playlist.MediaPlayList:
+		media = v
+	case *playlist.MediaPlayList:
+		media = *v
+	default:
+		return defaultDuration(h.VODMaxAge, DefaultVODMaxAge)
+	}
+
+	if media.EndList || media.PlayListType == playlist.MediaPlayListTypeVOD {
+		return defaultDuration(h.VODMaxAge, DefaultVODMaxAge)
+	}
+
+	if h.LiveMaxAge > 0 {
+		return h.LiveMaxAge
+	}
+	return time.Duration(media.TargetDuration) * time.Second / 2
+}
+
+func defaultDuration(value, _default time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return _default
+}
+
+func setMaxAge(header http.Header, maxAge time.Duration) {
+	if seconds := int64(maxAge / time.Second); seconds > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("max-age=%d", seconds))
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+}
+
+func acceptGzip(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(v), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		params = strings.ReplaceAll(params, " ", "")
+		return params != "q=0" && params != "q=0.0" && params != "q=0.00" && params != "q=0.000"
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const (
+	testMaster = "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000\nlive.m3u8\n"
+	testLive   = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:4,\n1.ts\n"
+	testVOD    = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:4\n#EXTINF:4,\n0.ts\n#EXT-X-ENDLIST\n"
+)
+
+func newTestHandler() *Handler {
+	return NewHandler(fstest.MapFS{
+		"master.m3u8":  {Data: []byte(testMaster)},
+		"live.m3u8":    {Data: []byte(testLive)},
+		"vod/vod.m3u8": {Data: []byte(testVOD), ModTime: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"vod/0.ts":     {Data: []byte("0123456789")},
+		"bad.m3u8":     {Data: []byte("invalid")},
+	})
+}
+
+func serve(h http.Handler, method, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerPlayList(t *testing.T) {
+	h := newTestHandler()
+
+	tests := []struct {
+		Path   string
+		MaxAge string
+	}{
+		{Path: "/master.m3u8", MaxAge: "max-age=86400"},
+		{Path: "/live.m3u8", MaxAge: "max-age=2"},
+		{Path: "/vod/vod.m3u8", MaxAge: "max-age=86400"},
+	}
+
+	for _, test := range tests {
+		rec := serve(h, http.MethodGet, test.Path)
+		if rec.Code != 200 {
+			t.Errorf("%s: expect status code %d, but got %d", test.Path, 200, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != MIMEPlayList {
+			t.Errorf("%s: expect content type '%s', but got '%s'", test.Path, MIMEPlayList, ct)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != test.MaxAge {
+			t.Errorf("%s: expect cache control '%s', but got '%s'", test.Path, test.MaxAge, cc)
+		}
+		if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "*" {
+			t.Errorf("%s: expect allow origin '%s', but got '%s'", test.Path, "*", origin)
+		}
+	}
+
+	rec := serve(h, http.MethodGet, "/vod/vod.m3u8", "Accept-Encoding", "br, gzip")
+	if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("expect content encoding '%s', but got '%s'", "gzip", ce)
+	}
+
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	} else if data, err := io.ReadAll(gr); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), "#EXT-X-ENDLIST") {
+		t.Errorf("unexpected playlist:\n%s", data)
+	}
+
+	if lm := rec.Header().Get("Last-Modified"); lm == "" {
+		t.Errorf("expect the header Last-Modified, but got nothing")
+	} else if rec := serve(h, http.MethodGet, "/vod/vod.m3u8",
+		"Accept-Encoding", "gzip", "If-Modified-Since", lm); rec.Code != http.StatusNotModified {
+		t.Errorf("expect status code %d, but got %d", http.StatusNotModified, rec.Code)
+	}
+
+	if rec := serve(h, http.MethodGet, "/vod/vod.m3u8", "Accept-Encoding", "gzip;q=0"); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expect no content encoding")
+	}
+
+	if rec := serve(h, http.MethodGet, "/bad.m3u8"); rec.Code != 500 {
+		t.Errorf("expect status code %d, but got %d", 500, rec.Code)
+	}
+}
+
+func TestHandlerSegment(t *testing.T) {
+	h := newTestHandler()
+
+	rec := serve(h, http.MethodGet, "/vod/0.ts", "Range", "bytes=2-5")
+	if rec.Code != http.StatusPartialContent {
+		t.Errorf("expect status code %d, but got %d", http.StatusPartialContent, rec.Code)
+	} else if body := rec.Body.String(); body != "2345" {
+		t.Errorf("expect body '%s', but got '%s'", "2345", body)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != MIMEMPEG2TS {
+		t.Errorf("expect content type '%s', but got '%s'", MIMEMPEG2TS, ct)
+	}
+
+	for path, code := range map[string]int{"/missing.ts": 404, "/vod": 404, "/": 404} {
+		if rec := serve(h, http.MethodGet, path); rec.Code != code {
+			t.Errorf("%s: expect status code %d, but got %d", path, code, rec.Code)
+		}
+	}
+
+	if rec := serve(h, http.MethodPost, "/vod/0.ts"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status code %d, but got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+
+	rec = serve(h, http.MethodOptions, "/vod/0.ts")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Headers") != "Range" {
+		t.Errorf("unexpected preflight response: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestContentType(t *testing.T) {
+	for name, expect := range map[string]string{
+		"a.M3U8":  MIMEPlayList,
+		"a.m4s":   MIMEVideoMP4,
+		"a.aac":   MIMEAAC,
+		"a.vtt":   MIMEWebVTT,
+		"a.key":   MIMEOctetStream,
+		"a/b.mp4": MIMEVideoMP4,
+	} {
+		if ct := ContentType(name); ct != expect {
+			t.Errorf("%s: expect '%s', but got '%s'", name, expect, ct)
+		}
+	}
+}
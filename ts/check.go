@@ -0,0 +1,94 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// SegmentReport is the check report of a media segment.
+type SegmentReport struct {
+	Index    int
+	Segment  playlist.MediaSegment
+	Info     Info
+	Problems []string
+}
+
+// OK reports whether the media segment has no problem.
+func (r SegmentReport) OK() bool { return len(r.Problems) == 0 }
+
+// CheckMediaPlayList loads the transport stream media segments
+// of the media playlist by load, and checks whether they match
+// what the media playlist claims, which reports the problems, such as
+//   - the actual duration mismatches EXTINF over the tolerance in seconds.
+//   - the timestamps are discontinuous without EXT-X-DISCONTINUITY.
+//   - the continuity counter errors.
+//
+// It only returns an error when failing to load or parse a media segment.
+func CheckMediaPlayList(pl playlist.MediaPlayList, tolerance float64,
+	load func(playlist.MediaSegment) ([]byte, error)) (reports []SegmentReport, err error) {
+	reports = make([]SegmentReport, 0, len(pl.Segments))
+	var last SegmentReport
+	for i, seg := range pl.Segments {
+		data, err := load(seg)
+		if err != nil {
+			return reports, fmt.Errorf("fail to load segment %d '%s': %w", i, seg.URI, err)
+		}
+
+		report := SegmentReport{Index: i, Segment: seg}
+		if report.Info, err = Inspect(data); err != nil {
+			return reports, fmt.Errorf("fail to parse segment %d '%s': %w", i, seg.URI, err)
+		}
+
+		report.check(last, tolerance)
+		reports = append(reports, report)
+		last = report
+	}
+	return
+}
+
+func (r *SegmentReport) check(last SegmentReport, tolerance float64) {
+	start := r.Info.StartPTS()
+	if start < 0 {
+		r.Problems = append(r.Problems, "no PTS")
+		return
+	}
+
+	duration := r.Info.Duration().Seconds()
+	if math.Abs(duration-r.Segment.Duration) > tolerance {
+		r.Problems = append(r.Problems, fmt.Sprintf("the actual duration %.3fs mismatches EXTINF %.3fs",
+			duration, r.Segment.Duration))
+	}
+
+	if n := len(r.Info.ContinuityErrors); n > 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("%d continuity counter errors", n))
+	}
+
+	if last.Info.Packets == 0 || r.Segment.Discontinuity {
+		return
+	}
+
+	if laststart := last.Info.StartPTS(); laststart >= 0 {
+		expect := laststart + int64(last.Info.Duration().Seconds()*ClockRate)
+		gap := float64(TimestampDelta(expect, start)) / ClockRate
+		if math.Abs(gap) > tolerance {
+			r.Problems = append(r.Problems, fmt.Sprintf(
+				"the timestamp jumps %.3fs without EXT-X-DISCONTINUITY", gap))
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// ClockRate is the clock rate of PTS and DTS, that's, 90kHz.
+	ClockRate = 90000
+
+	// PCRClockRate is the clock rate of PCR, that's, 27MHz.
+	PCRClockRate = 27000000
+
+	// The wraparound period of the 33-bit PTS and DTS.
+	timestampPeriod = 1 << 33
+)
+
+// IsVideo reports whether the stream type is video.
+func IsVideo(streamType uint8) bool {
+	switch streamType {
+	case StreamTypeMPEG1Video, StreamTypeMPEG2Video, StreamTypeH264,
+		StreamTypeH265, StreamTypeH264SampleAES, 0x10: // 0x10: MPEG-4 Visual
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAudio reports whether the stream type is audio.
+func IsAudio(streamType uint8) bool {
+	switch streamType {
+	case StreamTypeMPEG1Audio, StreamTypeMPEG2Audio, StreamTypeAAC,
+		StreamTypeAC3, StreamTypeEAC3, StreamTypeAACSampleAES,
+		StreamTypeAC3SampleAES, StreamTypeEAC3SampleAES, 0x11: // 0x11: LATM AAC
+		return true
+	default:
+		return false
+	}
+}
+
+// ContinuityError represents a continuity counter error.
+type ContinuityError struct {
+	Packet   int // The index of the packet.
+	PID      uint16
+	Expected uint8
+	Got      uint8
+}
+
+func (e ContinuityError) String() string {
+	return fmt.Sprintf("packet %d: pid %d expects continuity counter %d, but got %d",
+		e.Packet, e.PID, e.Expected, e.Got)
+}
+
+// StreamInfo is the information of an elementary stream in a segment.
+type StreamInfo struct {
+	Stream
+
+	Packets   int // The number of the packets.
+	Frames    int // The number of the PES packets.
+	KeyFrames int // The number of the PES packets with the random access indicator.
+
+	// The PTS and DTS of the first PES packet, which are -1 if absent.
+	FirstPTS int64
+	FirstDTS int64
+
+	// The minimum and maximum PTS, which are unwrapped relative to FirstPTS,
+	// so they may be out of [0, 2^33). They are valid only if FirstPTS>=0.
+	MinPTS int64
+	MaxPTS int64
+
+	lastPTS  int64 // The last unwrapped PTS.
+	lastCC   uint8
+	hasCC    bool
+	ptsCount int
+}
+
+// Duration returns the duration of the elementary stream, which is
+// the PTS range plus the average frame duration for the last frame.
+func (s StreamInfo) Duration() time.Duration {
+	if s.FirstPTS < 0 {
+		return 0
+	}
+
+	ticks := s.MaxPTS - s.MinPTS
+	if s.ptsCount > 1 {
+		ticks += ticks / int64(s.ptsCount-1)
+	}
+	return ticksToDuration(ticks)
+}
+
+// Info is the information of a transport stream segment.
+type Info struct {
+	Packets  int // The number of all the packets.
+	Programs []Program
+	Streams  []StreamInfo // In the order of PMT.
+
+	// The first and last PCR, which are -1 if absent.
+	FirstPCR int64
+	LastPCR  int64
+
+	ContinuityErrors []ContinuityError
+}
+
+// Stream returns the information of the elementary stream by the pid.
+func (i Info) Stream(pid uint16) (StreamInfo, bool) {
+	for _, s := range i.Streams {
+		if s.PID == pid {
+			return s, true
+		}
+	}
+	return StreamInfo{}, false
+}
+
+// StartPTS returns the start PTS of the segment, which is the minimum
+// PTS of the video streams, or all the streams if no video.
+//
+// Return -1 if no PTS.
+func (i Info) StartPTS() int64 {
+	pts, minPTS := int64(-1), int64(0)
+	i.eachMainStream(func(s StreamInfo) {
+		if s.FirstPTS >= 0 && (pts < 0 || s.MinPTS < minPTS) {
+			pts, minPTS = (s.MinPTS%timestampPeriod+timestampPeriod)%timestampPeriod, s.MinPTS
+		}
+	})
+	return pts
+}
+
+// Duration returns the actual duration of the segment, which is
+// the maximum duration of the video streams, or all the streams if no video.
+func (i Info) Duration() (duration time.Duration) {
+	i.eachMainStream(func(s StreamInfo) { duration = max(duration, s.Duration()) })
+	return
+}
+
+func (i Info) eachMainStream(f func(StreamInfo)) {
+	var video bool
+	for _, s := range i.Streams {
+		if IsVideo(s.Type) && s.FirstPTS >= 0 {
+			video = true
+			f(s)
+		}
+	}
+
+	if !video {
+		for _, s := range i.Streams {
+			f(s)
+		}
+	}
+}
+
+// Inspect parses the transport stream segment and returns its information.
+func Inspect(data []byte) (Info, error) {
+	packets, err := Packets(data)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var i inspector
+	i.init()
+	for _, p := range packets {
+		if err = i.inspect(p); err != nil {
+			return i.info, err
+		}
+	}
+	return i.info, nil
+}
+
+// InspectReader is the same as Inspect, but reads the transport stream
+// from r packet by packet.
+func InspectReader(r io.Reader) (Info, error) {
+	var i inspector
+	i.init()
+
+	p := make(Packet, PacketSize)
+	for {
+		if _, err := io.ReadFull(r, p); err != nil {
+			if errors.Is(err, io.EOF) {
+				return i.info, nil
+			}
+			return i.info, fmt.Errorf("packet %d: %w", i.info.Packets, err)
+		}
+
+		if err := p.Validate(); err != nil {
+			return i.info, fmt.Errorf("packet %d: %w", i.info.Packets, err)
+		} else if err = i.inspect(p); err != nil {
+			return i.info, err
+		}
+	}
+}
+
+type inspector struct {
+	info    Info
+	psi     ProgramTracker
+	streams map[uint16]int // The index of the stream in info.Streams.
+	ccs     map[uint16]uint8
+}
+
+func (i *inspector) init() {
+	i.info.FirstPCR, i.info.LastPCR = -1, -1
+	i.streams = make(map[uint16]int, 4)
+	i.ccs = make(map[uint16]uint8, 8)
+}
+
+func (i *inspector) inspect(p Packet) (err error) {
+	index := i.info.Packets
+	i.info.Packets++
+
+	pid := p.PID()
+	if pid == PIDNull {
+		return
+	}
+
+	i.checkContinuity(index, p)
+	if pcr, ok := p.PCR(); ok {
+		if i.info.FirstPCR < 0 {
+			i.info.FirstPCR = pcr
+		}
+		i.info.LastPCR = pcr
+	}
+
+	if i.psi.IsPSI(pid) {
+		var pat *PAT
+		var pmt *PMT
+		switch pat, pmt, err = i.psi.Parse(p); {
+		case pat != nil:
+			i.info.Programs = pat.Programs
+		case pmt != nil:
+			i.addStreams(*pmt)
+		}
+	} else if k, ok := i.streams[pid]; ok {
+		err = i.inspectPES(&i.info.Streams[k], p)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("packet %d: %w", index, err)
+	}
+	return
+}
+
+func (i *inspector) checkContinuity(index int, p Packet) {
+	if !p.HasPayload() {
+		return
+	}
+
+	pid, cc := p.PID(), p.ContinuityCounter()
+	last, ok := i.ccs[pid]
+	i.ccs[pid] = cc
+
+	// The duplicate packet has the same continuity counter.
+	if ok && cc != last && cc != (last+1)&0x0F && !p.DiscontinuityIndicator() {
+		i.info.ContinuityErrors = append(i.info.ContinuityErrors, ContinuityError{
+			Packet:   index,
+			PID:      pid,
+			Expected: (last + 1) & 0x0F,
+			Got:      cc,
+		})
+	}
+}
+
+func (i *inspector) addStreams(pmt PMT) {
+	for _, s := range pmt.Streams {
+		if k, ok := i.streams[s.PID]; ok {
+			i.info.Streams[k].Stream = s
+			continue
+		}
+
+		i.streams[s.PID] = len(i.info.Streams)
+		i.info.Streams = append(i.info.Streams, StreamInfo{
+			Stream:   s,
+			FirstPTS: -1,
+			FirstDTS: -1,
+		})
+	}
+}
+
+func (i *inspector) inspectPES(s *StreamInfo, p Packet) error {
+	s.Packets++
+	if !p.PayloadUnitStart() {
+		return nil
+	}
+
+	h, err := ParsePESHeader(p.Payload())
+	if err != nil {
+		return err
+	}
+
+	s.Frames++
+	if p.RandomAccessIndicator() {
+		s.KeyFrames++
+	}
+
+	if h.PTS < 0 {
+		return nil
+	}
+
+	if s.FirstPTS < 0 {
+		s.FirstPTS, s.FirstDTS = h.PTS, h.DTS
+		s.MinPTS, s.MaxPTS, s.lastPTS = h.PTS, h.PTS, h.PTS
+	} else {
+		s.lastPTS = UnwrapTimestamp(s.lastPTS, h.PTS)
+		s.MinPTS = min(s.MinPTS, s.lastPTS)
+		s.MaxPTS = max(s.MaxPTS, s.lastPTS)
+	}
+
+	s.ptsCount++
+	return nil
+}
+
+func ticksToDuration(ticks int64) time.Duration {
+	return time.Duration(float64(ticks) / ClockRate * float64(time.Second))
+}
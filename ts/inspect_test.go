@@ -0,0 +1,136 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xgfone/go-hls/playlist"
+)
+
+// newSegment returns a transport stream with the video stream of 25 fps
+// and the audio stream, which starts with the pts.
+func newSegment(pts int64, frames int) []byte {
+	const pmtpid, vpid, apid = 0x1000, 0x100, 0x101
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	_ = w.WritePAT(PAT{TransportStreamID: 1, Programs: []Program{{Number: 1, PID: pmtpid}}})
+	_ = w.WritePMT(pmtpid, PMT{ProgramNumber: 1, PCRPID: vpid, Streams: []Stream{
+		{Type: StreamTypeH264, PID: vpid},
+		{Type: StreamTypeAAC, PID: apid},
+	}})
+
+	for i := range frames {
+		ts := (pts + int64(i)*3600) % timestampPeriod
+		_ = w.WritePES(vpid, AppendPES(nil, 0xE0, ts, ts, make([]byte, 300)), ts*300, i%25 == 0)
+		if i%2 == 0 {
+			_ = w.WritePES(apid, AppendPES(nil, 0xC0, ts, -1, make([]byte, 100)), -1, false)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	data := newSegment(timestampPeriod-3600*10, 50) // Wrap around
+	info, err := Inspect(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Packets != len(data)/PacketSize {
+		t.Errorf("expect %d packets, but got %d", len(data)/PacketSize, info.Packets)
+	}
+	if len(info.Streams) != 2 {
+		t.Fatalf("expect %d streams, but got %d", 2, len(info.Streams))
+	}
+	if len(info.ContinuityErrors) != 0 {
+		t.Errorf("unexpected continuity errors: %v", info.ContinuityErrors)
+	}
+
+	video := info.Streams[0]
+	if video.Frames != 50 || video.KeyFrames != 2 {
+		t.Errorf("expect %d frames and %d key frames, but got %d and %d", 50, 2, video.Frames, video.KeyFrames)
+	}
+	if pts := info.StartPTS(); pts != timestampPeriod-3600*10 {
+		t.Errorf("expect start pts %d, but got %d", int64(timestampPeriod-3600*10), pts)
+	}
+	if duration := info.Duration(); duration != 2*time.Second {
+		t.Errorf("expect duration %s, but got %s", 2*time.Second, duration)
+	}
+	if info.FirstPCR != (timestampPeriod-3600*10)*300 || info.LastPCR != 3600*39*300 {
+		t.Errorf("unexpected PCR: first=%d, last=%d", info.FirstPCR, info.LastPCR)
+	}
+
+	// Drop a video packet.
+	packets, _ := Packets(data)
+	var dropped []byte
+	for i, p := range packets {
+		if i != 10 {
+			dropped = append(dropped, p...)
+		}
+	}
+
+	info, err = InspectReader(bytes.NewReader(dropped))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(info.ContinuityErrors) != 1 {
+		t.Errorf("expect %d continuity error, but got %d", 1, len(info.ContinuityErrors))
+	} else if e := info.ContinuityErrors[0]; e.Packet != 10 || e.PID != packets[10].PID() {
+		t.Errorf("unexpected continuity error: %s", e)
+	}
+}
+
+func TestCheckMediaPlayList(t *testing.T) {
+	segments := map[string][]byte{
+		"0.ts": newSegment(0, 50),
+		"1.ts": newSegment(3600*50, 50),
+		"2.ts": newSegment(3600*200, 50), // Jump
+		"3.ts": newSegment(3600*250, 100),
+		"4.ts": newSegment(0, 50),
+	}
+
+	pl := playlist.MediaPlayList{TargetDuration: 4, Segments: []playlist.MediaSegment{
+		{URI: "0.ts", Duration: 2},
+		{URI: "1.ts", Duration: 2},
+		{URI: "2.ts", Duration: 2},
+		{URI: "3.ts", Duration: 2},
+		{URI: "4.ts", Duration: 2, Discontinuity: true},
+	}}
+
+	reports, err := CheckMediaPlayList(pl, 0.1, func(seg playlist.MediaSegment) ([]byte, error) {
+		if data, ok := segments[seg.URI]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("not found '%s'", seg.URI)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expects := []string{"", "", "jumps", "mismatches", ""}
+	for i, r := range reports {
+		if expects[i] == "" && !r.OK() {
+			t.Errorf("segment %d: unexpected problems: %v", i, r.Problems)
+		} else if expects[i] != "" && (len(r.Problems) != 1 || !strings.Contains(r.Problems[0], expects[i])) {
+			t.Errorf("segment %d: expect the problem '%s', but got %v", i, expects[i], r.Problems)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// PacketSize is the size of a transport stream packet.
+	PacketSize = 188
+
+	// SyncByte is the first byte of a transport stream packet.
+	SyncByte = 0x47
+)
+
+// Some predefined PIDs.
+const (
+	PIDPAT  = 0x0000
+	PIDNull = 0x1FFF
+)
+
+var errInvalidPacket = errors.New("invalid transport stream packet")
+
+// Packet is a transport stream packet with 188 bytes.
+type Packet []byte
+
+// Packets splits the data into the transport stream packets,
+// which shares the underlying data.
+func Packets(data []byte) ([]Packet, error) {
+	if len(data)%PacketSize != 0 {
+		return nil, fmt.Errorf("%w: the data size %d is not a multiple of %d",
+			errInvalidPacket, len(data), PacketSize)
+	}
+
+	packets := make([]Packet, len(data)/PacketSize)
+	for i := range packets {
+		packet := Packet(data[i*PacketSize : (i+1)*PacketSize])
+		if err := packet.Validate(); err != nil {
+			return nil, fmt.Errorf("packet %d: %w", i, err)
+		}
+		packets[i] = packet
+	}
+	return packets, nil
+}
+
+// Validate checks whether the packet is valid.
+func (p Packet) Validate() error {
+	switch {
+	case len(p) != PacketSize:
+		return fmt.Errorf("%w: size %d", errInvalidPacket, len(p))
+
+	case p[0] != SyncByte:
+		return fmt.Errorf("%w: sync byte 0x%02x", errInvalidPacket, p[0])
+
+	case p[3]&0x30 == 0:
+		return fmt.Errorf("%w: reserved adaptation field control", errInvalidPacket)
+
+	case p.HasAdaptationField() && 5+int(p[4]) > PacketSize:
+		return fmt.Errorf("%w: adaptation field length %d", errInvalidPacket, p[4])
+
+	default:
+		return nil
+	}
+}
+
+// PID returns the packet identifier.
+func (p Packet) PID() uint16 { return uint16(p[1]&0x1F)<<8 | uint16(p[2]) }
+
+// PayloadUnitStart reports whether the payload_unit_start_indicator is set,
+// that's, the payload starts with a PES packet or a PSI section.
+func (p Packet) PayloadUnitStart() bool { return p[1]&0x40 != 0 }
+
+// ContinuityCounter returns the continuity counter, which is in [0, 15].
+func (p Packet) ContinuityCounter() uint8 { return p[3] & 0x0F }
+
+// HasAdaptationField reports whether the packet contains the adaptation field.
+func (p Packet) HasAdaptationField() bool { return p[3]&0x20 != 0 }
+
+// HasPayload reports whether the packet contains the payload.
+func (p Packet) HasPayload() bool { return p[3]&0x10 != 0 }
+
+// AdaptationField returns the adaptation field without the length byte.
+//
+// Return nil if the packet has no adaptation field.
+func (p Packet) AdaptationField() []byte {
+	if !p.HasAdaptationField() {
+		return nil
+	}
+	return p[5 : 5+int(p[4])]
+}
+
+// DiscontinuityIndicator reports whether the discontinuity_indicator
+// in the adaptation field is set, which means that the continuity counter
+// or the PCR is discontinuous.
+func (p Packet) DiscontinuityIndicator() bool {
+	af := p.AdaptationField()
+	return len(af) > 0 && af[0]&0x80 != 0
+}
+
+// RandomAccessIndicator reports whether the random_access_indicator
+// in the adaptation field is set, such as the packet starting a key frame.
+func (p Packet) RandomAccessIndicator() bool {
+	af := p.AdaptationField()
+	return len(af) > 0 && af[0]&0x40 != 0
+}
+
+// PCR returns the program clock reference in the adaptation field,
+// the unit of which is 27MHz.
+func (p Packet) PCR() (pcr int64, ok bool) {
+	af := p.AdaptationField()
+	if len(af) < 7 || af[0]&0x10 == 0 {
+		return
+	}
+
+	base := int64(af[1])<<25 | int64(af[2])<<17 | int64(af[3])<<9 | int64(af[4])<<1 | int64(af[5]>>7)
+	ext := int64(af[5]&0x01)<<8 | int64(af[6])
+	return base*300 + ext, true
+}
+
+// Payload returns the payload of the packet.
+//
+// Return nil if the packet has no payload.
+func (p Packet) Payload() []byte {
+	if !p.HasPayload() {
+		return nil
+	} else if p.HasAdaptationField() {
+		return p[5+int(p[4]):]
+	}
+	return p[4:]
+}
+
+// buildPacket builds a new packet based on the header of the original packet,
+// which has the adaptation field af, not including the length byte,
+// and the payload.
+//
+// If hasaf is true, the adaptation field is kept even if af is empty.
+// The adaptation field is stuffed if the payload is less than the capacity.
+func buildPacket(header []byte, pusi bool, cc uint8, hasaf bool, af, payload []byte) Packet {
+	p := make(Packet, PacketSize)
+	copy(p, header[:4])
+
+	if pusi {
+		p[1] |= 0x40
+	} else {
+		p[1] &^= 0x40
+	}
+	p[3] = p[3]&0xC0 | cc&0x0F
+
+	if len(payload) > 0 {
+		p[3] |= 0x10
+	}
+
+	aflen := PacketSize - 4 - len(payload) // Including the length byte.
+	if aflen > 0 || hasaf {
+		p[3] |= 0x20
+		p[4] = byte(aflen - 1)
+		if aflen > 1 {
+			field := p[5 : 4+aflen]
+			n := copy(field, af)
+			if n == 0 {
+				field[0] = 0 // No flags
+				n = 1
+			}
+			for i := n; i < len(field); i++ {
+				field[i] = 0xFF // Stuffing
+			}
+		}
+	}
+
+	copy(p[4+aflen:], payload)
+	return p
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errInvalidPES = errors.New("invalid pes packet")
+
+// PESHeader is the header of a PES packet.
+type PESHeader struct {
+	StreamID uint8
+	Length   uint16 // PES_packet_length, which is 0 if unbounded.
+	PTS      int64  // -1 if absent
+	DTS      int64  // -1 if absent
+	Size     int    // The size of the header, which is the offset of the payload.
+}
+
+// ParsePESHeader parses the header of the PES packet.
+func ParsePESHeader(data []byte) (h PESHeader, err error) {
+	if len(data) < 6 || data[0] != 0 || data[1] != 0 || data[2] != 1 {
+		return h, fmt.Errorf("%w: missing start code prefix", errInvalidPES)
+	}
+
+	h.StreamID = data[3]
+	h.Length = uint16(data[4])<<8 | uint16(data[5])
+	h.PTS, h.DTS, h.Size = -1, -1, 6
+
+	switch h.StreamID {
+	case 0xBC, 0xBE, 0xBF, 0xF0, 0xF1, 0xF2, 0xF8, 0xFF:
+		// No optional PES header.
+		return
+	}
+
+	if len(data) < 9 || data[6]&0xC0 != 0x80 {
+		return h, fmt.Errorf("%w: invalid optional header", errInvalidPES)
+	}
+
+	h.Size = 9 + int(data[8])
+	if h.Size > len(data) {
+		return h, fmt.Errorf("%w: too short header data", errInvalidPES)
+	}
+
+	switch flags := data[7] >> 6; flags {
+	case 2:
+		if data[8] < 5 {
+			return h, fmt.Errorf("%w: too short PTS", errInvalidPES)
+		}
+		h.PTS = parseTimestamp(data[9:14])
+
+	case 3:
+		if data[8] < 10 {
+			return h, fmt.Errorf("%w: too short PTS and DTS", errInvalidPES)
+		}
+		h.PTS = parseTimestamp(data[9:14])
+		h.DTS = parseTimestamp(data[14:19])
+	}
+
+	return
+}
+
+func parseTimestamp(b []byte) int64 {
+	return int64(b[0]>>1&0x07)<<30 | int64(b[1])<<22 | int64(b[2]>>1)<<15 |
+		int64(b[3])<<7 | int64(b[4]>>1)
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import "fmt"
+
+// ProgramTracker discovers the programs and the elementary streams
+// of a transport stream by tracking the PIDs of the PMTs announced by PAT.
+//
+// The zero value is ready to use.
+type ProgramTracker struct {
+	pmts map[uint16]struct{}
+}
+
+// IsPMT reports whether pid is the PID of a PMT announced by the parsed PAT.
+func (t *ProgramTracker) IsPMT(pid uint16) bool {
+	_, ok := t.pmts[pid]
+	return ok
+}
+
+// IsPSI reports whether pid is the PID of PAT or a PMT announced by the parsed PAT.
+func (t *ProgramTracker) IsPSI(pid uint16) bool {
+	return pid == PIDPAT || t.IsPMT(pid)
+}
+
+// Parse parses the PAT or PMT section starting in the packet p.
+//
+// If p starts a PAT section, pat is returned and the PIDs of the PMTs
+// are recorded. If p starts a PMT section, pmt is returned.
+// Or, both of them are nil.
+func (t *ProgramTracker) Parse(p Packet) (pat *PAT, pmt *PMT, err error) {
+	if !p.PayloadUnitStart() {
+		return
+	}
+
+	switch pid := p.PID(); {
+	case pid == PIDPAT:
+		var v PAT
+		if v, err = parsePacketSection(p, "PAT", ParsePAT); err != nil {
+			return
+		}
+
+		if t.pmts == nil {
+			t.pmts = make(map[uint16]struct{}, 1)
+		}
+		for _, program := range v.Programs {
+			if program.Number != 0 { // Skip the network PID.
+				t.pmts[program.PID] = struct{}{}
+			}
+		}
+		pat = &v
+
+	case t.IsPMT(pid):
+		var v PMT
+		if v, err = parsePacketSection(p, "PMT", ParsePMT); err != nil {
+			return
+		}
+		pmt = &v
+	}
+
+	return
+}
+
+func parsePacketSection[T any](p Packet, name string, parse func([]byte) (T, error)) (v T, err error) {
+	section, err := Section(p.Payload())
+	if err == nil {
+		v, err = parse(section)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%s: %w", name, err)
+	}
+	return
+}
+
+// MainStream returns the first video stream, or the first audio stream
+// if no video. If neither, return the zero Stream whose PID is 0.
+func (m PMT) MainStream() (main Stream) {
+	for _, s := range m.Streams {
+		if IsVideo(s.Type) {
+			return s
+		} else if main.PID == 0 && IsAudio(s.Type) {
+			main = s
+		}
+	}
+	return
+}
+
+// UnwrapTimestamp returns the unwrapped timestamp of the 33-bit PTS or DTS ts,
+// which is the closest to the last unwrapped timestamp.
+func UnwrapTimestamp(last, ts int64) int64 {
+	delta := (ts - last) % timestampPeriod
+	switch {
+	case delta >= timestampPeriod/2:
+		delta -= timestampPeriod
+	case delta < -timestampPeriod/2:
+		delta += timestampPeriod
+	}
+	return last + delta
+}
+
+// TimestampDelta returns the delta from the 33-bit PTS or DTS from to to,
+// which is negative if to is before from, such as the PTS of a B-frame.
+func TimestampDelta(from, to int64) int64 {
+	return UnwrapTimestamp(from, to) - from
+}
@@ -0,0 +1,298 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Some table ids of the program specific information.
+const (
+	TableIDPAT = 0x00
+	TableIDPMT = 0x02
+)
+
+// Some stream types in PMT.
+const (
+	StreamTypeMPEG1Video = 0x01
+	StreamTypeMPEG2Video = 0x02
+	StreamTypeMPEG1Audio = 0x03
+	StreamTypeMPEG2Audio = 0x04
+	StreamTypeAAC        = 0x0F // ADTS AAC
+	StreamTypeMetadata   = 0x15 // Such as ID3 timed metadata.
+	StreamTypeH264       = 0x1B
+	StreamTypeH265       = 0x24
+	StreamTypeAC3        = 0x81
+	StreamTypeEAC3       = 0x87
+
+	// The stream types encrypted by SAMPLE-AES, which are defined by
+	// Apple's MPEG-2 Stream Encryption Format for HTTP Live Streaming.
+	StreamTypeH264SampleAES = 0xDB
+	StreamTypeAACSampleAES  = 0xCF
+	StreamTypeAC3SampleAES  = 0xC1
+	StreamTypeEAC3SampleAES = 0xC2
+)
+
+var errInvalidSection = errors.New("invalid psi section")
+
+// Section returns the PSI section from the payload of the packet
+// whose payload_unit_start_indicator is set, which skips the pointer field
+// and the stuffing bytes, and checks the CRC32.
+//
+// The section spanning multiple packets is not supported.
+func Section(payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("%w: empty payload", errInvalidSection)
+	}
+
+	pointer := int(payload[0])
+	if 1+pointer+3 > len(payload) {
+		return nil, fmt.Errorf("%w: pointer field %d", errInvalidSection, pointer)
+	}
+
+	data := payload[1+pointer:]
+	size := 3 + int(binary.BigEndian.Uint16(data[1:3])&0x0FFF)
+	if size > len(data) {
+		return nil, fmt.Errorf("%w: the section spans multiple packets", errInvalidSection)
+	} else if size < 3+5+4 {
+		return nil, fmt.Errorf("%w: too short section length", errInvalidSection)
+	}
+
+	data = data[:size]
+	if crc := crc32(data[:size-4]); crc != binary.BigEndian.Uint32(data[size-4:]) {
+		return nil, fmt.Errorf("%w: mismatched crc32", errInvalidSection)
+	}
+
+	return data, nil
+}
+
+// Descriptor is a descriptor in PSI.
+type Descriptor struct {
+	Tag  uint8
+	Data []byte
+}
+
+func parseDescriptors(data []byte) (descriptors []Descriptor, err error) {
+	for len(data) > 0 {
+		if len(data) < 2 || 2+int(data[1]) > len(data) {
+			return nil, fmt.Errorf("%w: invalid descriptor", errInvalidSection)
+		}
+
+		descriptors = append(descriptors, Descriptor{Tag: data[0], Data: data[2 : 2+data[1]]})
+		data = data[2+data[1]:]
+	}
+	return
+}
+
+func appendDescriptors(dst []byte, descriptors []Descriptor) []byte {
+	for _, d := range descriptors {
+		dst = append(dst, d.Tag, byte(len(d.Data)))
+		dst = append(dst, d.Data...)
+	}
+	return dst
+}
+
+func descriptorsSize(descriptors []Descriptor) (n int) {
+	for _, d := range descriptors {
+		n += 2 + len(d.Data)
+	}
+	return
+}
+
+/// ----------------------------------------------------------------------- ///
+
+// Program is a program in PAT.
+type Program struct {
+	Number uint16
+	PID    uint16 // The PID of PMT, or network PID if Number is 0.
+}
+
+// PAT is the program association table.
+type PAT struct {
+	TransportStreamID uint16
+	Programs          []Program
+}
+
+// checkSection checks the length and the table id of the section,
+// and that its section_length is consistent with the length.
+func checkSection(section []byte, name string, tableID uint8, minLen int) error {
+	switch {
+	case len(section) < minLen:
+		return fmt.Errorf("%w: too short %s", errInvalidSection, name)
+	case section[0] != tableID:
+		return fmt.Errorf("%w: unexpected table id 0x%02x for %s", errInvalidSection, section[0], name)
+	case 3+int(binary.BigEndian.Uint16(section[1:3])&0x0FFF) != len(section):
+		return fmt.Errorf("%w: inconsistent %s section length", errInvalidSection, name)
+	}
+	return nil
+}
+
+// ParsePAT parses the PAT from the section returned by Section.
+func ParsePAT(section []byte) (pat PAT, err error) {
+	if err = checkSection(section, "PAT", TableIDPAT, 12); err != nil {
+		return
+	}
+
+	pat.TransportStreamID = binary.BigEndian.Uint16(section[3:5])
+	programs := section[8 : len(section)-4]
+	if len(programs)%4 != 0 {
+		return pat, fmt.Errorf("%w: invalid PAT programs", errInvalidSection)
+	}
+
+	pat.Programs = make([]Program, 0, len(programs)/4)
+	for ; len(programs) > 0; programs = programs[4:] {
+		pat.Programs = append(pat.Programs, Program{
+			Number: binary.BigEndian.Uint16(programs[:2]),
+			PID:    binary.BigEndian.Uint16(programs[2:4]) & 0x1FFF,
+		})
+	}
+	return
+}
+
+// Section encodes the PAT to a section with the CRC32,
+// which is current and the only one.
+func (t PAT) Section() []byte {
+	size := 8 + len(t.Programs)*4 + 4
+	data := make([]byte, 8, size)
+	data[0] = TableIDPAT
+	binary.BigEndian.PutUint16(data[1:3], 0xB000|uint16(size-3))
+	binary.BigEndian.PutUint16(data[3:5], t.TransportStreamID)
+	data[5] = 0xC1 // version_number=0, current_next_indicator=1
+
+	for _, p := range t.Programs {
+		data = binary.BigEndian.AppendUint16(data, p.Number)
+		data = binary.BigEndian.AppendUint16(data, 0xE000|p.PID)
+	}
+
+	return binary.BigEndian.AppendUint32(data, crc32(data))
+}
+
+// Stream is an elementary stream in PMT.
+type Stream struct {
+	Type        uint8
+	PID         uint16
+	Descriptors []Descriptor
+}
+
+// PMT is the program map table.
+type PMT struct {
+	ProgramNumber uint16
+	Version       uint8
+	PCRPID        uint16
+	Descriptors   []Descriptor // The program descriptors.
+	Streams       []Stream
+}
+
+// ParsePMT parses the PMT from the section returned by Section.
+func ParsePMT(section []byte) (pmt PMT, err error) {
+	if err = checkSection(section, "PMT", TableIDPMT, 16); err != nil {
+		return
+	}
+
+	pmt.ProgramNumber = binary.BigEndian.Uint16(section[3:5])
+	pmt.Version = section[5] >> 1 & 0x1F
+	pmt.PCRPID = binary.BigEndian.Uint16(section[8:10]) & 0x1FFF
+
+	data := section[12 : len(section)-4]
+	infolen := int(binary.BigEndian.Uint16(section[10:12]) & 0x0FFF)
+	if infolen > len(data) {
+		return pmt, fmt.Errorf("%w: invalid program info length", errInvalidSection)
+	} else if pmt.Descriptors, err = parseDescriptors(data[:infolen]); err != nil {
+		return
+	}
+
+	for data = data[infolen:]; len(data) > 0; {
+		if len(data) < 5 {
+			return pmt, fmt.Errorf("%w: invalid PMT stream", errInvalidSection)
+		}
+
+		stream := Stream{Type: data[0], PID: binary.BigEndian.Uint16(data[1:3]) & 0x1FFF}
+		infolen = int(binary.BigEndian.Uint16(data[3:5]) & 0x0FFF)
+		if 5+infolen > len(data) {
+			return pmt, fmt.Errorf("%w: invalid ES info length", errInvalidSection)
+		} else if stream.Descriptors, err = parseDescriptors(data[5 : 5+infolen]); err != nil {
+			return
+		}
+
+		pmt.Streams = append(pmt.Streams, stream)
+		data = data[5+infolen:]
+	}
+
+	return
+}
+
+// Stream returns the elementary stream by the pid.
+func (m PMT) Stream(pid uint16) (stream Stream, ok bool) {
+	for _, s := range m.Streams {
+		if s.PID == pid {
+			return s, true
+		}
+	}
+	return
+}
+
+// Section encodes the PMT to a section with the CRC32,
+// which is current and the only one.
+func (m PMT) Section() []byte {
+	size := 12 + descriptorsSize(m.Descriptors) + 4
+	for _, s := range m.Streams {
+		size += 5 + descriptorsSize(s.Descriptors)
+	}
+
+	data := make([]byte, 12, size)
+	data[0] = TableIDPMT
+	binary.BigEndian.PutUint16(data[1:3], 0xB000|uint16(size-3))
+	binary.BigEndian.PutUint16(data[3:5], m.ProgramNumber)
+	data[5] = 0xC1 | (m.Version&0x1F)<<1 // current_next_indicator=1
+	binary.BigEndian.PutUint16(data[8:10], 0xE000|m.PCRPID)
+	binary.BigEndian.PutUint16(data[10:12], 0xF000|uint16(descriptorsSize(m.Descriptors)))
+	data = appendDescriptors(data, m.Descriptors)
+
+	for _, s := range m.Streams {
+		data = append(data, s.Type, byte(0xE0|s.PID>>8), byte(s.PID),
+			byte(0xF0|descriptorsSize(s.Descriptors)>>8), byte(descriptorsSize(s.Descriptors)))
+		data = appendDescriptors(data, s.Descriptors)
+	}
+
+	return binary.BigEndian.AppendUint32(data, crc32(data))
+}
+
+/// ----------------------------------------------------------------------- ///
+
+var crc32Table = func() (table [256]uint32) {
+	for i := range table {
+		crc := uint32(i) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return
+}()
+
+// crc32 calculates the CRC32/MPEG-2 checksum used by PSI.
+func crc32(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc = crc<<8 ^ crc32Table[byte(crc>>24)^b]
+	}
+	return crc
+}
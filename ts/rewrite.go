@@ -0,0 +1,231 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+// Rewriter is used to rewrite the PMT and the PES packets
+// of the elementary streams in a transport stream, such as decrypting them.
+type Rewriter struct {
+	// PMT is used to rewrite the program map table, which is optional.
+	PMT func(pmt *PMT) error
+
+	// PES is used to rewrite a whole PES packet of the elementary stream,
+	// which is optional and may change the size of the PES packet.
+	//
+	// stream is the original one in PMT before rewriting.
+	// If returning the original pes, the packets are kept as they are.
+	PES func(stream Stream, pes []byte) ([]byte, error)
+}
+
+// Rewrite rewrites the transport stream data and returns the new one.
+//
+// The rewritten PES packet is packetized into the original packets in turn,
+// which keeps their adaptation fields, such as PCR. If the new PES packet
+// is longer, the extra packets are inserted after the last original packet;
+// or, the extra original packets are removed, or only their adaptation
+// fields are kept if containing any field. And the PES_packet_length
+// and the continuity counters are updated accordingly.
+//
+// The PSI section spanning multiple packets is not supported.
+func (r Rewriter) Rewrite(data []byte) ([]byte, error) {
+	packets, err := Packets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var psi ProgramTracker
+	pess := make(map[uint16]*pesBuffer, 4)
+	slots := make([][]byte, len(packets))
+	for i, p := range packets {
+		slots[i] = p
+
+		pid := p.PID()
+		if psi.IsPSI(pid) {
+			var pmt *PMT
+			if _, pmt, err = psi.Parse(p); err != nil {
+				return nil, err
+			} else if pmt != nil {
+				if slots[i], err = r.rewritePMT(p, *pmt, pess); err != nil {
+					return nil, err
+				}
+			}
+		} else if b, ok := pess[pid]; ok && p.HasPayload() {
+			if p.PayloadUnitStart() {
+				if err = b.flush(r.PES, packets, slots); err != nil {
+					return nil, err
+				}
+			}
+
+			// Ignore the remaining part of the PES packet before the first one.
+			if p.PayloadUnitStart() || len(b.indexes) > 0 {
+				b.indexes = append(b.indexes, i)
+				b.data = append(b.data, p.Payload()...)
+			}
+		}
+	}
+
+	for _, b := range pess {
+		if err = b.flush(r.PES, packets, slots); err != nil {
+			return nil, err
+		}
+	}
+
+	return bytes.Join(slots, nil), nil
+}
+
+func (r Rewriter) rewritePMT(p Packet, pmt PMT, pess map[uint16]*pesBuffer) (Packet, error) {
+	if r.PES != nil {
+		for _, s := range pmt.Streams {
+			if b, ok := pess[s.PID]; ok {
+				b.stream = s
+			} else {
+				pess[s.PID] = &pesBuffer{stream: s}
+			}
+		}
+	}
+
+	if r.PMT == nil {
+		return p, nil
+	} else if err := r.PMT(&pmt); err != nil {
+		return nil, err
+	}
+
+	section := pmt.Section()
+	p = slices.Clone(p)
+	payload := p.Payload()
+	if 1+len(section) > len(payload) {
+		return nil, fmt.Errorf("PMT: the section with %d bytes is too long", len(section))
+	}
+
+	payload[0] = 0 // pointer_field
+	n := 1 + copy(payload[1:], section)
+	for ; n < len(payload); n++ {
+		payload[n] = 0xFF
+	}
+
+	return p, nil
+}
+
+type pesBuffer struct {
+	stream  Stream
+	indexes []int // The indexes of the packets carrying the PES packet.
+	data    []byte
+
+	cc     uint8 // The next continuity counter.
+	ccinit bool
+}
+
+func (b *pesBuffer) flush(rewrite func(Stream, []byte) ([]byte, error), packets []Packet, slots [][]byte) error {
+	if len(b.indexes) == 0 {
+		return nil
+	}
+
+	defer func() { b.indexes, b.data = b.indexes[:0], nil }()
+	pes, err := rewrite(b.stream, b.data)
+	if err != nil {
+		return fmt.Errorf("pid %d: %w", b.stream.PID, err)
+	}
+
+	first := packets[b.indexes[0]].ContinuityCounter()
+	if len(pes) == len(b.data) && (len(pes) == 0 || &pes[0] == &b.data[0]) &&
+		(!b.ccinit || b.cc == first) {
+		b.cc = (packets[b.indexes[len(b.indexes)-1]].ContinuityCounter() + 1) & 0x0F
+		b.ccinit = true
+		return nil
+	}
+
+	if !b.ccinit {
+		b.cc, b.ccinit = first, true
+	}
+
+	if len(pes) != len(b.data) && len(pes) >= 6 && (pes[4] != 0 || pes[5] != 0) {
+		if n := len(pes) - 6; n > 0xFFFF {
+			pes[4], pes[5] = 0, 0 // Unbounded, only for video.
+		} else {
+			pes[4], pes[5] = byte(n>>8), byte(n)
+		}
+	}
+
+	for k, index := range b.indexes {
+		p := packets[index]
+		af := trimAdaptationField(p.AdaptationField())
+		hasaf := len(af) > 0
+
+		if len(pes) == 0 {
+			if hasaf {
+				slots[index] = buildPacket(p, false, b.cc-1, true, af, nil)
+			} else {
+				slots[index] = nil
+			}
+			continue
+		}
+
+		capacity := PacketSize - 4
+		if hasaf {
+			capacity -= 1 + len(af)
+		}
+
+		n := min(capacity, len(pes))
+		slot := buildPacket(p, k == 0, b.cc, hasaf, af, pes[:n])
+		pes = pes[n:]
+		b.cc++
+
+		if k == len(b.indexes)-1 {
+			for len(pes) > 0 {
+				n = min(PacketSize-4, len(pes))
+				slot = append(slot, buildPacket(p, false, b.cc, false, nil, pes[:n])...)
+				pes = pes[n:]
+				b.cc++
+			}
+		}
+
+		slots[index] = slot
+	}
+
+	b.cc &= 0x0F
+	return nil
+}
+
+// trimAdaptationField removes the stuffing bytes from the adaptation field,
+// and returns nil if it contains no flags.
+func trimAdaptationField(af []byte) []byte {
+	if len(af) == 0 || af[0] == 0 {
+		return nil
+	}
+
+	flags, size := af[0], 1
+	if flags&0x10 != 0 { // PCR
+		size += 6
+	}
+	if flags&0x08 != 0 { // OPCR
+		size += 6
+	}
+	if flags&0x04 != 0 { // splice_countdown
+		size++
+	}
+	if flags&0x02 != 0 && size < len(af) { // transport_private_data
+		size += 1 + int(af[size])
+	}
+	if flags&0x01 != 0 && size < len(af) { // adaptation_field_extension
+		size += 1 + int(af[size])
+	}
+
+	return af[:min(size, len(af))]
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// demux collects the payloads of the PES packets of the pid.
+func demux(t *testing.T, data []byte, pid uint16) (pess [][]byte) {
+	packets, err := Packets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cc uint8
+	var started bool
+	for _, p := range packets {
+		if p.PID() != pid || !p.HasPayload() {
+			continue
+		}
+
+		if started && p.ContinuityCounter() != (cc+1)&0x0F {
+			t.Errorf("pid %d: expect continuity counter %d, but got %d", pid, (cc+1)&0x0F, p.ContinuityCounter())
+		}
+		cc, started = p.ContinuityCounter(), true
+
+		if p.PayloadUnitStart() {
+			pess = append(pess, nil)
+		}
+		pess[len(pess)-1] = append(pess[len(pess)-1], p.Payload()...)
+	}
+	return
+}
+
+func TestRewriter(t *testing.T) {
+	const pmtpid, vpid, apid = 0x1000, 0x100, 0x101
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	_ = w.WritePAT(PAT{TransportStreamID: 1, Programs: []Program{{Number: 1, PID: pmtpid}}})
+	_ = w.WritePMT(pmtpid, PMT{ProgramNumber: 1, PCRPID: vpid, Streams: []Stream{
+		{Type: StreamTypeH264SampleAES, PID: vpid, Descriptors: []Descriptor{{Tag: 0x0F, Data: []byte("zavc")}}},
+		{Type: StreamTypeAAC, PID: apid},
+	}})
+
+	var vpes, apes [][]byte
+	for i := range 3 {
+		payload := bytes.Repeat([]byte{byte(i + 1)}, 500*(i+1))
+		vpes = append(vpes, AppendPES(nil, 0xE0, int64(i)*3000+6000, int64(i)*3000+3000, payload))
+		_ = w.WritePES(vpid, vpes[i], int64(i)*3000*300, i == 0)
+
+		apes = append(apes, AppendPES(nil, 0xC0, int64(i)*3000, -1, payload[:100]))
+		_ = w.WritePES(apid, apes[i], -1, false)
+	}
+
+	if pess := demux(t, buf.Bytes(), vpid); len(pess) != 3 {
+		t.Fatalf("expect %d video PES packets, but got %d", 3, len(pess))
+	}
+
+	rewriter := Rewriter{
+		PMT: func(pmt *PMT) error {
+			for i := range pmt.Streams {
+				if pmt.Streams[i].Type == StreamTypeH264SampleAES {
+					pmt.Streams[i].Type = StreamTypeH264
+					pmt.Streams[i].Descriptors = nil
+				}
+			}
+			return nil
+		},
+
+		PES: func(stream Stream, pes []byte) ([]byte, error) {
+			if stream.Type != StreamTypeH264SampleAES {
+				return pes, nil
+			}
+
+			h, err := ParsePESHeader(pes)
+			if err != nil {
+				return nil, err
+			}
+
+			// Shrink the first, and expand the others.
+			switch payload := pes[h.Size:]; payload[0] {
+			case 1:
+				return pes[:h.Size+10], nil
+			default:
+				return append(pes, payload...), nil
+			}
+		},
+	}
+
+	data, err := rewriter.Rewrite(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets, err := Packets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section, err := Section(packets[1].Payload())
+	if err != nil {
+		t.Fatal(err)
+	} else if pmt, err := ParsePMT(section); err != nil {
+		t.Fatal(err)
+	} else if s, _ := pmt.Stream(vpid); s.Type != StreamTypeH264 || len(s.Descriptors) != 0 {
+		t.Errorf("unexpected video stream: %+v", s)
+	}
+
+	if pess := demux(t, data, apid); len(pess) != len(apes) {
+		t.Errorf("expect %d audio PES packets, but got %d", len(apes), len(pess))
+	} else {
+		for i := range pess {
+			if !bytes.Equal(pess[i], apes[i]) {
+				t.Errorf("%d: the audio PES packet is changed", i)
+			}
+		}
+	}
+
+	pess := demux(t, data, vpid)
+	if len(pess) != len(vpes) {
+		t.Fatalf("expect %d video PES packets, but got %d", len(vpes), len(pess))
+	}
+
+	for i, pes := range pess {
+		h, err := ParsePESHeader(pes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expect := 10
+		if i > 0 {
+			expect = 500 * (i + 1) * 2
+		}
+
+		if n := len(pes) - h.Size; n != expect {
+			t.Errorf("%d: expect %d bytes payload, but got %d", i, expect, n)
+		} else if int(h.Length) != len(pes)-6 {
+			t.Errorf("%d: expect PES_packet_length %d, but got %d", i, len(pes)-6, h.Length)
+		}
+
+		if pts := int64(i)*3000 + 6000; h.PTS != pts {
+			t.Errorf("%d: expect PTS %d, but got %d", i, pts, h.PTS)
+		}
+		if dts := int64(i)*3000 + 3000; h.DTS != dts {
+			t.Errorf("%d: expect DTS %d, but got %d", i, dts, h.DTS)
+		}
+	}
+}
+
+func TestParsePSIShortSection(t *testing.T) {
+	pat := PAT{TransportStreamID: 1, Programs: []Program{{Number: 1, PID: 0x1000}}}
+	section := pat.Section()
+	if parsed, err := ParsePAT(section); err != nil {
+		t.Fatal(err)
+	} else if len(parsed.Programs) != 1 || parsed.Programs[0] != pat.Programs[0] {
+		t.Errorf("unexpected PAT: %+v", parsed)
+	}
+
+	pmt := PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: []Stream{{Type: StreamTypeH264, PID: 0x100}}}
+	inputs := [][]byte{nil, {}, {TableIDPAT}, section[:11], section[:len(section)-1], pmt.Section()[:15]}
+	for i, input := range inputs {
+		if _, err := ParsePAT(input); !errors.Is(err, errInvalidSection) {
+			t.Errorf("%d: expect PAT error '%v', but got '%v'", i, errInvalidSection, err)
+		}
+		if _, err := ParsePMT(input); !errors.Is(err, errInvalidSection) {
+			t.Errorf("%d: expect PMT error '%v', but got '%v'", i, errInvalidSection, err)
+		}
+	}
+}
+
+func TestProgramTracker(t *testing.T) {
+	packets, err := Packets(newSegment(0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var programs ProgramTracker
+	var pats, pmts int
+	for _, p := range packets {
+		if !programs.IsPSI(p.PID()) {
+			continue
+		}
+
+		switch pat, pmt, err := programs.Parse(p); {
+		case err != nil:
+			t.Fatal(err)
+
+		case pat != nil:
+			pats++
+
+		case pmt != nil:
+			pmts++
+			if main := pmt.MainStream(); main.PID != 0x100 || main.Type != StreamTypeH264 {
+				t.Errorf("expect the main stream 0x100, but got %+v", main)
+			}
+		}
+	}
+
+	if pats != 1 || pmts != 1 {
+		t.Errorf("expect 1 PAT and 1 PMT, but got %d and %d", pats, pmts)
+	}
+	if !programs.IsPMT(0x1000) || programs.IsPMT(0x100) {
+		t.Errorf("expect only the PMT PID 0x1000")
+	}
+
+	audio := PMT{Streams: []Stream{{Type: 0x06, PID: 0x102}, {Type: StreamTypeAAC, PID: 0x101}}}
+	if main := audio.MainStream(); main.PID != 0x101 {
+		t.Errorf("expect the main audio stream 0x101, but got %+v", main)
+	}
+}
+
+func TestTimestampDelta(t *testing.T) {
+	tests := []struct{ from, to, delta int64 }{
+		{0, 3600, 3600},
+		{3600, 0, -3600},
+		{timestampPeriod - 1800, 1800, 3600},
+		{1800, timestampPeriod - 1800, -3600},
+	}
+
+	for _, test := range tests {
+		if delta := TimestampDelta(test.from, test.to); delta != test.delta {
+			t.Errorf("%d->%d: expect delta %d, but got %d", test.from, test.to, test.delta, delta)
+		}
+		if ts := UnwrapTimestamp(test.from, test.to); ts != test.from+test.delta {
+			t.Errorf("%d->%d: expect timestamp %d, but got %d", test.from, test.to, test.from+test.delta, ts)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ts
+
+import (
+	"fmt"
+	"io"
+)
+
+// Writer is used to write the PSI sections and the PES packets
+// into the transport stream packets, which maintains the continuity
+// counter of each PID.
+type Writer struct {
+	w   io.Writer
+	ccs map[uint16]uint8
+}
+
+// NewWriter returns a new transport stream writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, ccs: make(map[uint16]uint8, 4)}
+}
+
+// WritePAT writes the PAT into a packet with PID 0.
+func (w *Writer) WritePAT(pat PAT) error {
+	return w.writeSection(PIDPAT, pat.Section())
+}
+
+// WritePMT writes the PMT into a packet with the pid.
+func (w *Writer) WritePMT(pid uint16, pmt PMT) error {
+	return w.writeSection(pid, pmt.Section())
+}
+
+func (w *Writer) writeSection(pid uint16, section []byte) error {
+	if 1+len(section) > PacketSize-4 {
+		return fmt.Errorf("the section with %d bytes is too long", len(section))
+	}
+
+	payload := make([]byte, PacketSize-4)
+	n := 1 + copy(payload[1:], section) // pointer_field=0
+	for ; n < len(payload); n++ {
+		payload[n] = 0xFF
+	}
+
+	return w.write(pid, true, nil, payload)
+}
+
+// WritePES writes the PES packet into the packets with the pid.
+//
+// If pcr is not negative, put it into the adaptation field
+// of the first packet, the unit of which is 27MHz.
+// randomAccess is used to set the random_access_indicator.
+func (w *Writer) WritePES(pid uint16, pes []byte, pcr int64, randomAccess bool) error {
+	var af []byte
+	if pcr >= 0 || randomAccess {
+		af = append(af, 0)
+		if randomAccess {
+			af[0] |= 0x40
+		}
+		if pcr >= 0 {
+			af[0] |= 0x10
+			base, ext := pcr/300, pcr%300
+			af = append(af, byte(base>>25), byte(base>>17), byte(base>>9), byte(base>>1),
+				byte(base<<7)|0x7E|byte(ext>>8), byte(ext))
+		}
+	}
+
+	for first := true; first || len(pes) > 0; first = false {
+		capacity := PacketSize - 4
+		if len(af) > 0 {
+			capacity -= 1 + len(af)
+		}
+
+		n := min(capacity, len(pes))
+		if err := w.write(pid, first, af, pes[:n]); err != nil {
+			return err
+		}
+		pes, af = pes[n:], nil
+	}
+
+	return nil
+}
+
+func (w *Writer) write(pid uint16, pusi bool, af, payload []byte) (err error) {
+	cc := w.ccs[pid]
+	w.ccs[pid] = (cc + 1) & 0x0F
+
+	header := []byte{SyncByte, byte(pid >> 8 & 0x1F), byte(pid), 0}
+	_, err = w.w.Write(buildPacket(header, pusi, cc, len(af) > 0, af, payload))
+	return
+}
+
+// AppendPES appends a PES packet with the stream id, PTS, DTS and payload
+// into dst, and returns the extended buffer.
+//
+// If pts or dts is negative, it is absent. And dts is ignored if pts is absent.
+// If the PES packet is too long, PES_packet_length is set to 0.
+func AppendPES(dst []byte, streamID uint8, pts, dts int64, payload []byte) []byte {
+	var flags, hlen byte
+	switch {
+	case pts >= 0 && dts >= 0:
+		flags, hlen = 0xC0, 10
+	case pts >= 0:
+		flags, hlen = 0x80, 5
+	}
+
+	length := 3 + int(hlen) + len(payload)
+	if length > 0xFFFF {
+		length = 0
+	}
+
+	dst = append(dst, 0, 0, 1, streamID, byte(length>>8), byte(length), 0x80, flags, hlen)
+	switch flags {
+	case 0xC0:
+		dst = appendTimestamp(dst, 0x30, pts)
+		dst = appendTimestamp(dst, 0x10, dts)
+	case 0x80:
+		dst = appendTimestamp(dst, 0x20, pts)
+	}
+
+	return append(dst, payload...)
+}
+
+func appendTimestamp(dst []byte, prefix byte, ts int64) []byte {
+	return append(dst,
+		prefix|byte(ts>>29&0x0E)|1,
+		byte(ts>>22),
+		byte(ts>>14)|1,
+		byte(ts>>7),
+		byte(ts<<1)|1,
+	)
+}